@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/boozedog/smoothbrain/internal/config"
+	"github.com/boozedog/smoothbrain/internal/core"
+	"github.com/boozedog/smoothbrain/internal/plugin"
+)
+
+const configUsage = "usage: smoothbrain [-config path] config check"
+
+// runConfig implements the "config" subcommand. "check" validates every
+// plugin's config against its schema and every route against the plugins'
+// capabilities without opening the database or starting anything; the
+// config file itself was already loaded and validated by then.
+func runConfig(cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) != 1 || args[0] != "check" {
+		return errors.New(configUsage)
+	}
+	addImplicitPlugins(cfg)
+	registry := plugin.NewRegistry(slog.New(slog.DiscardHandler), nil)
+	if err := registry.RegisterConfigured(cfg.Plugins); err != nil {
+		return err
+	}
+	err := errors.Join(registry.ValidateConfigs(cfg.Plugins), core.ValidateRoutes(cfg, registry))
+	if err != nil {
+		problems := strings.Split(err.Error(), "\n")
+		for _, p := range problems {
+			_, _ = fmt.Fprintln(out, p)
+		}
+		return fmt.Errorf("%d config problems", len(problems))
+	}
+	_, _ = fmt.Fprintf(out, "config ok: %d plugins, %d routes\n", len(registry.All()), len(cfg.Routes))
+	return nil
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"log/slog"
 	"net/http"
@@ -14,15 +15,19 @@ import (
 	"github.com/boozedog/smoothbrain/internal/auth"
 	"github.com/boozedog/smoothbrain/internal/config"
 	"github.com/boozedog/smoothbrain/internal/core"
+	"github.com/boozedog/smoothbrain/internal/outbound"
 	"github.com/boozedog/smoothbrain/internal/plugin"
-	"github.com/boozedog/smoothbrain/internal/plugin/claudecode"
-	"github.com/boozedog/smoothbrain/internal/plugin/mattermost"
-	"github.com/boozedog/smoothbrain/internal/plugin/obsidian"
+	_ "github.com/boozedog/smoothbrain/internal/plugin/claudecode"
+	_ "github.com/boozedog/smoothbrain/internal/plugin/exec"
+	_ "github.com/boozedog/smoothbrain/internal/plugin/mattermost"
+	_ "github.com/boozedog/smoothbrain/internal/plugin/obsidian"
+	_ "github.com/boozedog/smoothbrain/internal/plugin/remind"
 	"github.com/boozedog/smoothbrain/internal/plugin/tailscale"
-	"github.com/boozedog/smoothbrain/internal/plugin/td"
-	"github.com/boozedog/smoothbrain/internal/plugin/uptimekuma"
-	"github.com/boozedog/smoothbrain/internal/plugin/webmd"
-	"github.com/boozedog/smoothbrain/internal/plugin/xai"
+	_ "github.com/boozedog/smoothbrain/internal/plugin/td"
+	_ "github.com/boozedog/smoothbrain/internal/plugin/twitter"
+	_ "github.com/boozedog/smoothbrain/internal/plugin/uptimekuma"
+	_ "github.com/boozedog/smoothbrain/internal/plugin/webmd"
+	_ "github.com/boozedog/smoothbrain/internal/plugin/xai"
 	"github.com/boozedog/smoothbrain/internal/store"
 	"github.com/lmittmann/tint"
 	"tailscale.com/tsnet"
@@ -62,6 +67,27 @@ func main() {
 	}), logBuf))
 	log.Info("config loaded", "http", cfg.HTTP.Address, "database", cfg.Database, "log_level", level.String())
 
+	// Subcommands run against the configured database and exit.
+	if flag.NArg() > 0 {
+		var err error
+		switch flag.Arg(0) {
+		case "migrate":
+			err = runMigrate(cfg, flag.Args()[1:], os.Stdout)
+		case "restore":
+			err = runRestore(cfg, flag.Args()[1:], os.Stdout)
+		case "config":
+			err = runConfig(cfg, flag.Args()[1:], os.Stdout)
+		default:
+			log.Error("unknown command", "command", flag.Arg(0))
+			os.Exit(2)
+		}
+		if err != nil {
+			log.Error("command failed", "command", flag.Arg(0), "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	db, err := store.Open(cfg.Database)
 	if err != nil {
 		log.Error("failed to open database", "error", err)
@@ -70,21 +96,24 @@ func main() {
 	defer func() { _ = db.Close() }()
 	log.Info("database ready", "path", cfg.Database)
 
-	// Plugin registry
-	registry := plugin.NewRegistry(log, db.DB())
-	registry.Register(uptimekuma.New(log))
-	registry.Register(td.New(log))
-	registry.Register(xai.New(log))
-	registry.Register(mattermost.New(log))
-	registry.Register(webmd.New(log))
-	registry.Register(claudecode.New(log))
-	registry.Register(obsidian.New(log))
-	registry.Register(tailscale.New(log))
+	// Plugin registry. Only plugins present in the config are created.
+	addImplicitPlugins(cfg)
+	registry := plugin.NewRegistry(log, db)
+	registry.SetOutbound(outbound.New(outboundOptions(cfg.Outbound), log))
+	if err := registry.RegisterConfigured(cfg.Plugins); err != nil {
+		log.Error("failed to register plugins", "error", err)
+		os.Exit(1)
+	}
 
 	if err := registry.InitAll(cfg.Plugins); err != nil {
 		log.Error("failed to init plugins", "error", err)
 		os.Exit(1)
 	}
+	// After Init, so plugins that learn their actions at Init have them.
+	if err := core.ValidateRoutes(cfg, registry); err != nil {
+		log.Error("invalid routes", "error", err)
+		os.Exit(1)
+	}
 
 	// Build command list from routes and pass to command-aware plugins.
 	cmdsBySource := make(map[string][]plugin.CommandInfo)
@@ -92,7 +121,7 @@ func main() {
 		if r.Event != "" {
 			cmdsBySource[r.Source] = append(cmdsBySource[r.Source], plugin.CommandInfo{
 				Name:        r.Event,
-				Description: r.Description,
+				Description: commandDescription(r, registry),
 			})
 		}
 	}
@@ -104,36 +133,65 @@ func main() {
 		}
 	}
 
-	// Event bus + router + websocket hub
+	// Event bus + router + websocket hub + SSE stream
 	bus := core.NewBus(db, log)
 	hub := core.NewHub(db, log)
+	stream := core.NewStream(db, log)
 	router := core.NewRouter(cfg.Routes, registry, db, log)
-	router.SetNotifyFn(hub.Notify)
-	bus.Subscribe(router.HandleEvent)
+	router.SetBus(bus)
+	router.SubscribeRuns(hub.HandleRun)
+	router.SubscribeRuns(stream.HandleRun)
+	// The hub goes before the router, so an event's row is queued before
+	// any of its runs start.
 	bus.Subscribe(hub.HandleEvent)
+	bus.Subscribe(router.HandleEvent)
+	bus.Subscribe(stream.HandleEvent)
+	heartbeats := core.NewHeartbeats(cfg.Heartbeats, bus, db, log)
+	bus.Subscribe(heartbeats.HandleEvent)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	go hub.Run(ctx)
 
-	if err := registry.StartAll(ctx, bus); err != nil {
-		log.Error("failed to start plugins", "error", err)
-		os.Exit(1)
-	}
+	// A plugin that fails to start is retried in the background and shows
+	// as an error in health until it comes up.
+	registry.StartAll(ctx, bus)
 	defer registry.StopAll()
 
+	if cfg.Backup.Dir != "" {
+		interval, _ := time.ParseDuration(cfg.Backup.Interval) // validated in config.Load
+		db.StartBackups(ctx, store.BackupOptions{
+			Dir:        cfg.Backup.Dir,
+			Interval:   interval,
+			KeepDaily:  cfg.Backup.KeepDaily,
+			KeepWeekly: cfg.Backup.KeepWeekly,
+		}, log)
+		log.Info("database backups enabled", "dir", cfg.Backup.Dir, "every", interval)
+	}
+
 	supervisor := core.NewSupervisor(cfg.Supervisor.Tasks, bus, db, log)
+	supervisor.SetRouter(router)
 	supervisor.Start(ctx)
 	defer supervisor.Stop()
 
+	heartbeats.Start(ctx)
+	defer heartbeats.Stop()
+
 	// HTTP server
 	srv := core.NewServer(db, log, hub, registry, cfg.Routes, logBuf)
+	srv.SetBus(bus)
+	srv.SetRouter(router)
+	srv.SetStream(stream)
+	srv.SetSupervisor(supervisor)
+	srv.SetHeartbeats(heartbeats)
+	srv.SetIngestSources(cfg.Ingest.Sources)
 	registry.RegisterWebhooks(srv)
+	registry.RegisterAPIs(srv)
 
 	handler := srv.Handler()
 	if cfg.Auth.RPID != "" {
-		a, err := auth.New(cfg.Auth, db.DB(), log)
+		a, err := auth.New(cfg.Auth, db, log)
 		if err != nil {
 			log.Error("failed to init auth", "error", err)
 			os.Exit(1)
@@ -142,6 +200,8 @@ func main() {
 		handler = a.Middleware(srv.Handler())
 		log.Info("auth enabled", "rp_id", cfg.Auth.RPID)
 		a.StartCleanup(ctx)
+	} else {
+		auth.RegisterDisabledRoutes(srv.Mux())
 	}
 
 	// tsnet listener (Tailscale Service)
@@ -188,9 +248,13 @@ func main() {
 		WriteTimeout:      60 * time.Second,
 		IdleTimeout:       120 * time.Second,
 	}
+	// Open SSE streams never go idle, so end them when shutdown begins.
+	httpServer.RegisterOnShutdown(stream.Close)
 
 	// Graceful shutdown
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		sigCh := make(chan os.Signal, 1)
 		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 		<-sigCh
@@ -205,6 +269,14 @@ func main() {
 				log.Error("tsnet close error", "error", err)
 			}
 		}
+		// Let running pipelines finish before the deferred supervisor,
+		// plugin and store shutdown.
+		drainTimeout, _ := time.ParseDuration(cfg.DrainTimeout) // validated in config.Load
+		drainCtx, drainCancel := context.WithTimeout(context.Background(), drainTimeout)
+		defer drainCancel()
+		if err := router.Shutdown(drainCtx); err != nil {
+			log.Warn("interrupted running pipelines", "drain_timeout", drainTimeout)
+		}
 		cancel()
 	}()
 
@@ -213,4 +285,47 @@ func main() {
 		log.Error("http server error", "error", err)
 		os.Exit(1)
 	}
+	<-shutdownDone
+}
+
+// addImplicitPlugins adds config entries for plugins that come with other
+// settings: the tailscale health check comes with tsnet.
+func addImplicitPlugins(cfg *config.Config) {
+	if !cfg.Tailscale.Enabled {
+		return
+	}
+	if cfg.Plugins == nil {
+		cfg.Plugins = make(map[string]json.RawMessage)
+	}
+	if _, ok := cfg.Plugins["tailscale"]; !ok {
+		cfg.Plugins["tailscale"] = json.RawMessage("{}")
+	}
+}
+
+// commandDescription is a route's description for chat help or, without
+// one, the descriptions of its pipeline's actions.
+func commandDescription(r config.RouteConfig, reg *plugin.Registry) string {
+	if r.Description != "" {
+		return r.Description
+	}
+	var descs []string
+	for _, step := range r.Pipeline {
+		for _, a := range reg.Actions(step.Plugin) {
+			if a.Name == step.Action && a.Description != "" {
+				descs = append(descs, a.Description)
+			}
+		}
+	}
+	return strings.Join(descs, " → ")
+}
+
+// outboundOptions converts the validated outbound config to pool options.
+func outboundOptions(c config.OutboundConfig) outbound.Options {
+	cooldown, _ := time.ParseDuration(c.BreakerCooldown) // validated in config.Load
+	return outbound.Options{
+		RateLimits:       c.RateLimits,
+		MaxRetries:       c.MaxRetries,
+		BreakerThreshold: c.BreakerThreshold,
+		BreakerCooldown:  cooldown,
+	}
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/boozedog/smoothbrain/internal/config"
+	"github.com/boozedog/smoothbrain/internal/store"
+)
+
+const migrateUsage = "usage: smoothbrain [-config path] migrate status|up"
+
+// runMigrate implements the "migrate" subcommand.
+func runMigrate(cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%s", migrateUsage)
+	}
+
+	db, err := store.OpenUnmigrated(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	switch args[0] {
+	case "status":
+		return printMigrationStatus(db, out)
+	case "up":
+		if err := db.MigrateAll(); err != nil {
+			return err
+		}
+		return printMigrationStatus(db, out)
+	default:
+		return fmt.Errorf("unknown migrate command %q; %s", args[0], migrateUsage)
+	}
+}
+
+func printMigrationStatus(db *store.Store, out io.Writer) error {
+	states, err := store.Status(db.DB())
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "COMPONENT\tVERSION\tDESCRIPTION\tAPPLIED")
+	for _, s := range states {
+		applied := "pending"
+		if s.Applied() {
+			applied = s.AppliedAt.Local().Format(time.DateTime)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", s.Component, s.Version, s.Description, applied)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/boozedog/smoothbrain/internal/config"
+	"github.com/boozedog/smoothbrain/internal/store"
+)
+
+// runRestore implements the "restore" subcommand. smoothbrain must be stopped
+// while the database file is swapped.
+func runRestore(cfg *config.Config, args []string, out io.Writer) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: smoothbrain [-config path] restore <backup-file>")
+	}
+
+	prev, err := store.Restore(args[0], cfg.Database)
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(out, "restored %s to %s\n", args[0], cfg.Database)
+	if prev != "" {
+		_, _ = fmt.Fprintf(out, "previous database saved as %s\n", prev)
+	}
+	return nil
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/boozedog/smoothbrain/internal/config"
+	"github.com/boozedog/smoothbrain/internal/store"
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
 )
@@ -25,7 +26,7 @@ type challengeEntry struct {
 // Auth provides WebAuthn-based passkey authentication.
 type Auth struct {
 	wa              *webauthn.WebAuthn
-	db              *sql.DB
+	store           *store.Store
 	log             *slog.Logger
 	sessionDuration time.Duration
 
@@ -33,9 +34,54 @@ type Auth struct {
 	challenges map[string]challengeEntry
 }
 
-// New creates an Auth instance, configures WebAuthn, and ensures the required
-// database tables exist.
-func New(cfg config.AuthConfig, db *sql.DB, log *slog.Logger) (*Auth, error) {
+func init() {
+	store.Register("auth", store.Migration{
+		Version:     1,
+		Description: "webauthn credentials and sessions",
+		SQL: `
+CREATE TABLE IF NOT EXISTS webauthn_credentials (
+    id               INTEGER PRIMARY KEY,
+    credential_id    BLOB UNIQUE,
+    public_key       BLOB,
+    attestation_type TEXT,
+    aaguid           BLOB,
+    sign_count       INTEGER,
+    backup_eligible  BOOLEAN NOT NULL DEFAULT 0,
+    backup_state     BOOLEAN NOT NULL DEFAULT 0,
+    transport        TEXT NOT NULL DEFAULT '',
+    created_at       DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    token      TEXT PRIMARY KEY,
+    expires_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`,
+	}, store.Migration{
+		Version:     3,
+		Description: "sessions expiry for older databases",
+		Func:        addSessionExpiry,
+	})
+}
+
+// addSessionExpiry adds sessions.expires_at to databases whose sessions
+// table predates it; v1's CREATE TABLE IF NOT EXISTS leaves those alone.
+func addSessionExpiry(tx *sql.Tx) error {
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('sessions') WHERE name = 'expires_at'`).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+	_, err := tx.Exec(`ALTER TABLE sessions ADD COLUMN expires_at DATETIME`)
+	return err
+}
+
+// New creates an Auth instance, configures WebAuthn, and applies the auth
+// schema migrations.
+func New(cfg config.AuthConfig, s *store.Store, log *slog.Logger) (*Auth, error) {
 	waCfg := &webauthn.Config{
 		RPDisplayName: cfg.RPDisplayName,
 		RPID:          cfg.RPID,
@@ -46,34 +92,10 @@ func New(cfg config.AuthConfig, db *sql.DB, log *slog.Logger) (*Auth, error) {
 		return nil, fmt.Errorf("auth: webauthn init: %w", err)
 	}
 
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS webauthn_credentials (
-		id               INTEGER PRIMARY KEY,
-		credential_id    BLOB UNIQUE,
-		public_key       BLOB,
-		attestation_type TEXT,
-		aaguid           BLOB,
-		sign_count       INTEGER,
-		backup_eligible  BOOLEAN NOT NULL DEFAULT 0,
-		backup_state     BOOLEAN NOT NULL DEFAULT 0,
-		transport        TEXT NOT NULL DEFAULT '',
-		created_at       DATETIME DEFAULT CURRENT_TIMESTAMP
-	)`)
-	if err != nil {
-		return nil, fmt.Errorf("auth: create credentials table: %w", err)
-	}
-
-	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS sessions (
-		token      TEXT PRIMARY KEY,
-		expires_at DATETIME,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	)`)
-	if err != nil {
-		return nil, fmt.Errorf("auth: create sessions table: %w", err)
+	if err := s.Migrate("auth"); err != nil {
+		return nil, fmt.Errorf("auth: %w", err)
 	}
 
-	// Add expires_at column for existing databases (ignore error if already exists).
-	_, _ = db.Exec(`ALTER TABLE sessions ADD COLUMN expires_at DATETIME`)
-
 	sessionDuration := cfg.SessionDuration
 	if sessionDuration == 0 {
 		sessionDuration = 24 * time.Hour
@@ -81,7 +103,7 @@ func New(cfg config.AuthConfig, db *sql.DB, log *slog.Logger) (*Auth, error) {
 
 	return &Auth{
 		wa:              wa,
-		db:              db,
+		store:           s,
 		log:             log,
 		sessionDuration: sessionDuration,
 		challenges:      make(map[string]challengeEntry),
@@ -101,7 +123,7 @@ func (u *User) WebAuthnCredentials() []webauthn.Credential { return u.credential
 // HasCredential returns true if at least one passkey credential is registered.
 func (a *Auth) HasCredential() bool {
 	var count int
-	err := a.db.QueryRow(`SELECT COUNT(*) FROM webauthn_credentials`).Scan(&count)
+	err := a.store.DB().QueryRow(`SELECT COUNT(*) FROM webauthn_credentials`).Scan(&count)
 	if err != nil {
 		a.log.Error("auth: count credentials", "error", err)
 		return false
@@ -110,7 +132,7 @@ func (a *Auth) HasCredential() bool {
 }
 
 func (a *Auth) loadCredentials() []webauthn.Credential {
-	rows, err := a.db.Query(`SELECT credential_id, public_key, attestation_type, aaguid, sign_count, backup_eligible, backup_state, transport FROM webauthn_credentials`)
+	rows, err := a.store.DB().Query(`SELECT credential_id, public_key, attestation_type, aaguid, sign_count, backup_eligible, backup_state, transport FROM webauthn_credentials`)
 	if err != nil {
 		a.log.Error("auth: load credentials", "error", err)
 		return nil
@@ -169,7 +191,7 @@ func (a *Auth) FinishRegistration(challengeID string, r *http.Request) error {
 	}
 
 	transportJSON, _ := json.Marshal(cred.Transport)
-	_, err = a.db.Exec(
+	_, err = a.store.Exec(r.Context(),
 		`INSERT INTO webauthn_credentials (credential_id, public_key, attestation_type, aaguid, sign_count, backup_eligible, backup_state, transport) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
 		cred.ID, cred.PublicKey, cred.AttestationType, cred.Authenticator.AAGUID[:], cred.Authenticator.SignCount, cred.Flags.BackupEligible, cred.Flags.BackupState, string(transportJSON),
 	)
@@ -211,7 +233,7 @@ func (a *Auth) FinishLogin(challengeID string, r *http.Request) (string, error)
 	}
 
 	// Update sign count (non-fatal).
-	if _, err := a.db.Exec(`UPDATE webauthn_credentials SET sign_count = ? WHERE credential_id = ?`, cred.Authenticator.SignCount, cred.ID); err != nil {
+	if _, err := a.store.Exec(r.Context(), `UPDATE webauthn_credentials SET sign_count = ? WHERE credential_id = ?`, cred.Authenticator.SignCount, cred.ID); err != nil {
 		a.log.Error("auth: update sign count", "error", err)
 	}
 
@@ -223,7 +245,7 @@ func (a *Auth) FinishLogin(challengeID string, r *http.Request) (string, error)
 	token := hex.EncodeToString(tokenBytes)
 
 	expiresAt := time.Now().Add(a.sessionDuration)
-	_, err = a.db.Exec(`INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, token, expiresAt)
+	_, err = a.store.Exec(r.Context(), `INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, token, expiresAt)
 	if err != nil {
 		return "", fmt.Errorf("auth: store session: %w", err)
 	}
@@ -233,7 +255,7 @@ func (a *Auth) FinishLogin(challengeID string, r *http.Request) (string, error)
 // ValidateSession returns true if the given token exists in the sessions table.
 func (a *Auth) ValidateSession(token string) bool {
 	var count int
-	err := a.db.QueryRow(`SELECT COUNT(*) FROM sessions WHERE token = ? AND expires_at > ?`, token, time.Now()).Scan(&count)
+	err := a.store.DB().QueryRow(`SELECT COUNT(*) FROM sessions WHERE token = ? AND expires_at > ?`, token, time.Now()).Scan(&count)
 	if err != nil {
 		a.log.Error("auth: validate session", "error", err)
 		return false
@@ -243,14 +265,14 @@ func (a *Auth) ValidateSession(token string) bool {
 
 // DeleteSession removes a session token from the database.
 func (a *Auth) DeleteSession(token string) {
-	_, err := a.db.Exec(`DELETE FROM sessions WHERE token = ?`, token)
+	_, err := a.store.Exec(context.Background(), `DELETE FROM sessions WHERE token = ?`, token)
 	if err != nil {
 		a.log.Error("auth: delete session", "error", err)
 	}
 }
 
 func (a *Auth) cleanupExpiredSessions() {
-	result, err := a.db.Exec(`DELETE FROM sessions WHERE expires_at <= ?`, time.Now())
+	result, err := a.store.Exec(context.Background(), `DELETE FROM sessions WHERE expires_at <= ?`, time.Now())
 	if err != nil {
 		a.log.Error("auth: cleanup expired sessions", "error", err)
 		return
@@ -1,23 +1,23 @@
 package auth
 
 import (
-	"database/sql"
+	"context"
 	"log/slog"
 	"testing"
 	"time"
 
 	"github.com/boozedog/smoothbrain/internal/config"
+	"github.com/boozedog/smoothbrain/internal/store"
 	"github.com/go-webauthn/webauthn/webauthn"
-	_ "modernc.org/sqlite"
 )
 
 func newTestAuth(t *testing.T, sessionDuration time.Duration) *Auth {
 	t.Helper()
-	db, err := sql.Open("sqlite", ":memory:")
+	st, err := store.Open(":memory:")
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Cleanup(func() { _ = db.Close() })
+	t.Cleanup(func() { _ = st.Close() })
 
 	cfg := config.AuthConfig{
 		RPDisplayName:   "Test",
@@ -25,7 +25,7 @@ func newTestAuth(t *testing.T, sessionDuration time.Duration) *Auth {
 		RPOrigins:       []string{"http://localhost:8080"},
 		SessionDuration: sessionDuration,
 	}
-	auth, err := New(cfg, db, slog.Default())
+	auth, err := New(cfg, st, slog.Default())
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -36,7 +36,7 @@ func TestSessionExpiry(t *testing.T) {
 	a := newTestAuth(t, 100*time.Millisecond)
 
 	// Insert a session that expires soon.
-	_, err := a.db.Exec(`INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "test-token", time.Now().Add(100*time.Millisecond))
+	_, err := a.store.Exec(context.Background(), `INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "test-token", time.Now().Add(100*time.Millisecond))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -55,12 +55,35 @@ func TestSessionExpiry(t *testing.T) {
 	}
 }
 
+func TestNew_AddsExpiryToLegacySessions(t *testing.T) {
+	st, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	// A sessions table from before expires_at existed.
+	if _, err := st.Exec(context.Background(), `CREATE TABLE sessions (token TEXT PRIMARY KEY, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := New(config.AuthConfig{RPDisplayName: "Test", RPID: "localhost", RPOrigins: []string{"http://localhost:8080"}}, st, slog.Default())
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if _, err := a.store.Exec(context.Background(), `INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "legacy", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("insert session: %v", err)
+	}
+	if !a.ValidateSession("legacy") {
+		t.Error("session on a migrated legacy table should be valid")
+	}
+}
+
 func TestCleanupExpiredSessions(t *testing.T) {
 	a := newTestAuth(t, 24*time.Hour)
 
 	// Insert expired and valid sessions.
-	_, _ = a.db.Exec(`INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "expired", time.Now().Add(-1*time.Hour))
-	_, _ = a.db.Exec(`INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "valid", time.Now().Add(1*time.Hour))
+	_, _ = a.store.Exec(context.Background(), `INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "expired", time.Now().Add(-1*time.Hour))
+	_, _ = a.store.Exec(context.Background(), `INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "valid", time.Now().Add(1*time.Hour))
 
 	a.cleanupExpiredSessions()
 
@@ -76,7 +99,7 @@ func TestLoadCredentialsInvalidTransport(t *testing.T) {
 	a := newTestAuth(t, 24*time.Hour)
 
 	// Insert a credential with invalid transport JSON.
-	_, err := a.db.Exec(`INSERT INTO webauthn_credentials (credential_id, public_key, attestation_type, aaguid, sign_count, backup_eligible, backup_state, transport) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+	_, err := a.store.Exec(context.Background(), `INSERT INTO webauthn_credentials (credential_id, public_key, attestation_type, aaguid, sign_count, backup_eligible, backup_state, transport) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
 		[]byte("cred1"), []byte("key1"), "none", make([]byte, 16), 0, false, false, "not-valid-json")
 	if err != nil {
 		t.Fatal(err)
@@ -180,7 +203,7 @@ func TestChallengeCleanupRemovesExpired(t *testing.T) {
 func TestDeleteSession(t *testing.T) {
 	a := newTestAuth(t, 24*time.Hour)
 
-	_, _ = a.db.Exec(`INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "to-delete", time.Now().Add(1*time.Hour))
+	_, _ = a.store.Exec(context.Background(), `INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "to-delete", time.Now().Add(1*time.Hour))
 	if !a.ValidateSession("to-delete") {
 		t.Fatal("session should exist before deletion")
 	}
@@ -2,9 +2,12 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // RegisterRoutes adds all authentication routes to the given ServeMux.
@@ -16,6 +19,23 @@ func (a *Auth) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /auth/login/begin", a.handleLoginBegin)
 	mux.HandleFunc("POST /auth/login/finish", a.handleLoginFinish)
 	mux.HandleFunc("POST /auth/logout", a.handleLogout)
+
+	// Token management sits under /api/, so it needs a session or an admin
+	// token like the rest of the API.
+	mux.HandleFunc("GET /api/tokens", a.handleTokens)
+	mux.HandleFunc("POST /api/tokens", a.handleTokenCreate)
+	mux.HandleFunc("GET /api/tokens/html", a.handleTokensHTML)
+	mux.HandleFunc("POST /api/tokens/html", a.handleTokenCreateHTML)
+	mux.HandleFunc("POST /api/tokens/{id}/revoke", a.handleTokenRevoke)
+}
+
+// RegisterDisabledRoutes serves the tokens tab when authentication is off,
+// explaining that tokens are not needed.
+func RegisterDisabledRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/tokens/html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_ = TokensDisabled().Render(r.Context(), w)
+	})
 }
 
 func (a *Auth) handleLoginPage(w http.ResponseWriter, r *http.Request) {
@@ -159,3 +179,179 @@ func (a *Auth) handleLogout(w http.ResponseWriter, r *http.Request) {
 
 	http.Redirect(w, r, "/auth/login", http.StatusFound)
 }
+
+// scopeList joins scopes for display.
+func scopeList(scopes []Scope) string {
+	names := make([]string, len(scopes))
+	for i, s := range scopes {
+		names[i] = string(s)
+	}
+	return strings.Join(names, ", ")
+}
+
+// tokenTime formats a token timestamp, or returns zero for an unset one.
+func tokenTime(t time.Time, zero string) string {
+	if t.IsZero() {
+		return zero
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func tokenRevokeURL(id int64) string {
+	return "/api/tokens/" + strconv.FormatInt(id, 10) + "/revoke"
+}
+
+// checkOrigin guards session-authenticated token changes against CSRF, as
+// handleLogout does. Requests made with an API token carry no cookie and
+// need no check.
+func (a *Auth) checkOrigin(w http.ResponseWriter, r *http.Request) bool {
+	if _, ok := TokenFromContext(r.Context()); ok {
+		return true
+	}
+	if !isValidOrigin(r, a.wa.Config.RPOrigins) {
+		http.Error(w, "forbidden: invalid origin", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (a *Auth) handleTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := a.ListTokens(r.Context())
+	if err != nil {
+		a.log.Error("auth: list tokens", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if tokens == nil {
+		tokens = []Token{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(tokens); err != nil {
+		a.log.Error("auth: encode response", "error", err)
+	}
+}
+
+// createTokenRequest is the body of POST /api/tokens. ExpiresIn is a Go
+// duration; empty means the token never expires.
+type createTokenRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn string   `json:"expires_in"`
+}
+
+func (c createTokenRequest) parse() ([]Scope, time.Duration, error) {
+	scopes, err := ParseScopes(c.Scopes)
+	if err != nil {
+		return nil, 0, err
+	}
+	var ttl time.Duration
+	if c.ExpiresIn != "" {
+		if ttl, err = time.ParseDuration(c.ExpiresIn); err != nil || ttl <= 0 {
+			return nil, 0, errors.New("expires_in must be a positive duration")
+		}
+	}
+	return scopes, ttl, nil
+}
+
+func (a *Auth) handleTokenCreate(w http.ResponseWriter, r *http.Request) {
+	if !a.checkOrigin(w, r) {
+		return
+	}
+	var req createTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	scopes, ttl, err := req.parse()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	secret, tok, err := a.CreateToken(r.Context(), req.Name, scopes, ttl)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	a.log.Info("api token created", "name", tok.Name, "scopes", tok.Scopes)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(struct {
+		Token
+		Secret string `json:"token"`
+	}{tok, secret}); err != nil {
+		a.log.Error("auth: encode response", "error", err)
+	}
+}
+
+func (a *Auth) handleTokensHTML(w http.ResponseWriter, r *http.Request) {
+	tokens, err := a.ListTokens(r.Context())
+	if err != nil {
+		a.log.Error("auth: list tokens", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	if err := TokensTab(tokens).Render(r.Context(), w); err != nil {
+		a.log.Error("auth: render tokens tab", "error", err)
+	}
+}
+
+// handleTokenCreateHTML creates a token from the tab's form and shows its
+// secret once. The HX-Trigger header refreshes the token list.
+func (a *Auth) handleTokenCreateHTML(w http.ResponseWriter, r *http.Request) {
+	if !a.checkOrigin(w, r) {
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	req := createTokenRequest{
+		Name:      r.PostForm.Get("name"),
+		Scopes:    r.PostForm["scopes"],
+		ExpiresIn: r.PostForm.Get("expires_in"),
+	}
+	w.Header().Set("Content-Type", "text/html")
+	scopes, ttl, err := req.parse()
+	var secret string
+	var tok Token
+	if err == nil {
+		secret, tok, err = a.CreateToken(r.Context(), req.Name, scopes, ttl)
+	}
+	if err != nil {
+		_ = TokenError(err.Error()).Render(r.Context(), w)
+		return
+	}
+	a.log.Info("api token created", "name", tok.Name, "scopes", tok.Scopes)
+	w.Header().Set("HX-Trigger", "tokens-changed")
+	if err := TokenCreated(tok, secret).Render(r.Context(), w); err != nil {
+		a.log.Error("auth: render created token", "error", err)
+	}
+}
+
+func (a *Auth) handleTokenRevoke(w http.ResponseWriter, r *http.Request) {
+	if !a.checkOrigin(w, r) {
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid token id", http.StatusBadRequest)
+		return
+	}
+	ok, err := a.RevokeToken(r.Context(), id)
+	if err != nil {
+		a.log.Error("auth: revoke token", "error", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "token not found", http.StatusNotFound)
+		return
+	}
+	a.log.Info("api token revoked", "id", id)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("HX-Trigger", "tokens-changed")
+	if err := json.NewEncoder(w).Encode(map[string]bool{"ok": true}); err != nil {
+		a.log.Error("auth: encode response", "error", err)
+	}
+}
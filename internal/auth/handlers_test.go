@@ -1,8 +1,12 @@
 package auth
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 )
@@ -11,7 +15,7 @@ func TestLogoutCSRFValidOrigin(t *testing.T) {
 	a := newTestAuth(t, 24*time.Hour)
 
 	// Insert a session.
-	_, _ = a.db.Exec(`INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "logout-token", time.Now().Add(1*time.Hour))
+	_, _ = a.store.Exec(context.Background(), `INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "logout-token", time.Now().Add(1*time.Hour))
 
 	req := httptest.NewRequest("POST", "/auth/logout", nil)
 	req.Header.Set("Origin", "http://localhost:8080")
@@ -56,7 +60,7 @@ func TestLogoutCSRFMissingOrigin(t *testing.T) {
 func TestLogoutCSRFRefererFallback(t *testing.T) {
 	a := newTestAuth(t, 24*time.Hour)
 
-	_, _ = a.db.Exec(`INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "ref-token", time.Now().Add(1*time.Hour))
+	_, _ = a.store.Exec(context.Background(), `INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "ref-token", time.Now().Add(1*time.Hour))
 
 	req := httptest.NewRequest("POST", "/auth/logout", nil)
 	req.Header.Set("Referer", "http://localhost:8080/dashboard")
@@ -87,7 +91,7 @@ func TestLogoutCSRFPrefixAttack(t *testing.T) {
 func TestLogoutDeletesSession(t *testing.T) {
 	a := newTestAuth(t, 24*time.Hour)
 
-	_, _ = a.db.Exec(`INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "del-token", time.Now().Add(1*time.Hour))
+	_, _ = a.store.Exec(context.Background(), `INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "del-token", time.Now().Add(1*time.Hour))
 
 	req := httptest.NewRequest("POST", "/auth/logout", nil)
 	req.Header.Set("Origin", "http://localhost:8080")
@@ -100,3 +104,83 @@ func TestLogoutDeletesSession(t *testing.T) {
 		t.Error("session should be deleted after logout")
 	}
 }
+
+func TestTokenHandlers(t *testing.T) {
+	a := newTestAuth(t, 24*time.Hour)
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	req := httptest.NewRequest("POST", "/api/tokens", strings.NewReader(`{"name":"cli","scopes":["events:read","replay"],"expires_in":"24h"}`))
+	req.Header.Set("Origin", "http://localhost:8080")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d, body %s", rec.Code, rec.Body)
+	}
+	var created struct {
+		ID        int64     `json:"id"`
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Token == "" || created.ExpiresAt.IsZero() {
+		t.Errorf("created = %+v, want secret and expiry", created)
+	}
+	if _, ok := a.ValidateToken(context.Background(), created.Token); !ok {
+		t.Error("created token does not validate")
+	}
+
+	for _, body := range []string{`{"name":"x","scopes":["root"]}`, `{"name":"","scopes":["admin"]}`, `{"name":"x","scopes":["admin"],"expires_in":"soon"}`} {
+		req := httptest.NewRequest("POST", "/api/tokens", strings.NewReader(body))
+		req.Header.Set("Origin", "http://localhost:8080")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("create %s: status %d, want 400", body, rec.Code)
+		}
+	}
+
+	// A session-authenticated change from another origin is refused.
+	req = httptest.NewRequest("POST", tokenRevokeURL(created.ID), nil)
+	req.Header.Set("Origin", "http://evil.example.com")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("cross-origin revoke: status %d, want 403", rec.Code)
+	}
+
+	req = httptest.NewRequest("POST", tokenRevokeURL(created.ID), nil)
+	req.Header.Set("Origin", "http://localhost:8080")
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("HX-Trigger") != "tokens-changed" {
+		t.Errorf("revoke: status %d, HX-Trigger %q", rec.Code, rec.Header().Get("HX-Trigger"))
+	}
+	if _, ok := a.ValidateToken(context.Background(), created.Token); ok {
+		t.Error("revoked token still validates")
+	}
+}
+
+func TestTokenCreateHTML(t *testing.T) {
+	a := newTestAuth(t, 24*time.Hour)
+	mux := http.NewServeMux()
+	a.RegisterRoutes(mux)
+
+	form := url.Values{"name": {"phone"}, "scopes": {"events:emit"}, "expires_in": {""}}
+	req := httptest.NewRequest("POST", "/api/tokens/html", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", "http://localhost:8080")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), tokenPrefix) || rec.Header().Get("HX-Trigger") != "tokens-changed" {
+		t.Errorf("create html: HX-Trigger %q, body %s", rec.Header().Get("HX-Trigger"), rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/api/tokens/html", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "phone") || !strings.Contains(body, "events:emit") {
+		t.Errorf("tokens tab missing token: %s", body)
+	}
+}
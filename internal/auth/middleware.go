@@ -1,13 +1,26 @@
 package auth
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"strings"
 )
 
+type tokenKey struct{}
+
+// TokenFromContext returns the API token that authenticated the request, if
+// it was authenticated with one rather than a session.
+func TokenFromContext(ctx context.Context) (Token, bool) {
+	t, ok := ctx.Value(tokenKey{}).(Token)
+	return t, ok
+}
+
 // Middleware returns an HTTP middleware that enforces session authentication.
 // Requests to /auth/, /hooks/, /vendor/, and /api/health are allowed through
-// without a valid session.
+// without a valid session. A request with an "Authorization: Bearer" header
+// is authenticated by API token instead, and must hold the scope the route
+// needs; it gets a 401 or 403 rather than a redirect to the login page.
 func (a *Auth) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
@@ -21,6 +34,22 @@ func (a *Auth) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
+		if secret, ok := bearerToken(r); ok {
+			tok, ok := a.ValidateToken(r.Context(), secret)
+			if !ok {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				http.Error(w, "invalid or expired token", http.StatusUnauthorized)
+				return
+			}
+			if scope := requiredScope(r); !tok.Allows(scope) {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, scope))
+				http.Error(w, "token lacks scope "+string(scope), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tokenKey{}, tok)))
+			return
+		}
+
 		// Check session cookie.
 		cookie, err := r.Cookie("session")
 		if err == nil && a.ValidateSession(cookie.Value) {
@@ -31,3 +60,29 @@ func (a *Auth) Middleware(next http.Handler) http.Handler {
 		http.Redirect(w, r, "/auth/login", http.StatusFound)
 	})
 }
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, secret, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(secret), true
+}
+
+// requiredScope returns the scope an API token needs for a request. Reading
+// events (including the live stream), emitting them and replaying them have
+// their own scopes; everything else, including the UI and token management,
+// needs admin.
+func requiredScope(r *http.Request) Scope {
+	path := r.URL.Path
+	events := path == "/api/events" || strings.HasPrefix(path, "/api/events/")
+	switch {
+	case r.Method == http.MethodGet && (events || path == "/api/stream"):
+		return ScopeEventsRead
+	case r.Method == http.MethodPost && path == "/api/events":
+		return ScopeEventsEmit
+	case r.Method == http.MethodPost && events && strings.HasSuffix(path, "/replay"):
+		return ScopeReplay
+	}
+	return ScopeAdmin
+}
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -45,7 +46,7 @@ func TestMiddlewareValidSession(t *testing.T) {
 	a := newTestAuth(t, 24*time.Hour)
 
 	// Insert a valid session.
-	_, _ = a.db.Exec(`INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "valid-token", time.Now().Add(1*time.Hour))
+	_, _ = a.store.Exec(context.Background(), `INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "valid-token", time.Now().Add(1*time.Hour))
 
 	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -65,7 +66,7 @@ func TestMiddlewareExpiredSession(t *testing.T) {
 	a := newTestAuth(t, 24*time.Hour)
 
 	// Insert an expired session.
-	_, _ = a.db.Exec(`INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "expired-token", time.Now().Add(-1*time.Hour))
+	_, _ = a.store.Exec(context.Background(), `INSERT INTO sessions (token, expires_at) VALUES (?, ?)`, "expired-token", time.Now().Add(-1*time.Hour))
 
 	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -96,3 +97,53 @@ func TestMiddlewareNoCookie(t *testing.T) {
 		t.Errorf("missing cookie should redirect, got status %d", rec.Code)
 	}
 }
+
+func TestMiddlewareBearerToken(t *testing.T) {
+	a := newTestAuth(t, 24*time.Hour)
+	reader, _, err := a.CreateToken(context.Background(), "reader", []Scope{ScopeEventsRead}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, _, err := a.CreateToken(context.Background(), "admin", []Scope{ScopeAdmin}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotToken string
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tok, _ := TokenFromContext(r.Context())
+		gotToken = tok.Name
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		method, path, token string
+		want                int
+	}{
+		{"GET", "/api/events", reader, http.StatusOK},
+		{"GET", "/api/events/abc/runs", reader, http.StatusOK},
+		{"GET", "/api/stream", reader, http.StatusOK},
+		{"POST", "/api/events/abc/replay", reader, http.StatusForbidden},
+		{"POST", "/api/events", reader, http.StatusForbidden},
+		{"GET", "/api/status/html", reader, http.StatusForbidden},
+		{"POST", "/api/events/abc/replay", admin, http.StatusOK},
+		{"GET", "/api/tokens", admin, http.StatusOK},
+		{"GET", "/api/events", "sb_bogus", http.StatusUnauthorized},
+		{"GET", "/api/events", "not-a-token", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %s: got status %d, want %d", tt.method, tt.path, rec.Code, tt.want)
+		}
+		if rec.Code != http.StatusOK && rec.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%s %s: missing WWW-Authenticate header", tt.method, tt.path)
+		}
+	}
+	if gotToken != "admin" {
+		t.Errorf("TokenFromContext name = %q, want admin", gotToken)
+	}
+}
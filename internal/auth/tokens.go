@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/boozedog/smoothbrain/internal/store"
+)
+
+func init() {
+	store.Register("auth", store.Migration{
+		Version:     2,
+		Description: "scoped api tokens",
+		SQL: `
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id           INTEGER PRIMARY KEY,
+    name         TEXT NOT NULL,
+    prefix       TEXT NOT NULL,
+    token_hash   TEXT UNIQUE NOT NULL,
+    scopes       TEXT NOT NULL,
+    expires_at   DATETIME,
+    last_used_at DATETIME,
+    created_at   DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`,
+	})
+}
+
+// Scope is a permission granted to an API token.
+type Scope string
+
+const (
+	ScopeEventsRead Scope = "events:read" // read events, runs and lineage
+	ScopeEventsEmit Scope = "events:emit" // push events into the bus
+	ScopeReplay     Scope = "replay"      // replay stored events
+	ScopeAdmin      Scope = "admin"       // everything, including token management
+)
+
+// Scopes lists every scope in the order the UI shows them.
+var Scopes = []Scope{ScopeEventsRead, ScopeEventsEmit, ScopeReplay, ScopeAdmin}
+
+// tokenPrefix marks smoothbrain API tokens so they are recognisable in
+// scripts and secret scanners.
+const tokenPrefix = "sb_"
+
+// lastUsedInterval limits how often a token's last_used_at is written.
+const lastUsedInterval = time.Minute
+
+// Token is a stored API token. The secret itself is only returned once, by
+// CreateToken; the database keeps its SHA-256 hash.
+type Token struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Prefix     string    `json:"prefix"` // first characters of the secret, for recognising it
+	Scopes     []Scope   `json:"scopes"`
+	ExpiresAt  time.Time `json:"expires_at,omitzero"` // zero means never
+	LastUsedAt time.Time `json:"last_used_at,omitzero"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Allows reports whether the token grants scope. Admin grants every scope.
+func (t Token) Allows(scope Scope) bool {
+	return slices.Contains(t.Scopes, ScopeAdmin) || slices.Contains(t.Scopes, scope)
+}
+
+// Expired reports whether the token has passed its expiry.
+func (t Token) Expired() bool {
+	return !t.ExpiresAt.IsZero() && !time.Now().Before(t.ExpiresAt)
+}
+
+// ParseScopes checks names against the known scopes, dropping duplicates.
+func ParseScopes(names []string) ([]Scope, error) {
+	var scopes []Scope
+	for _, n := range names {
+		s := Scope(strings.TrimSpace(n))
+		if !slices.Contains(Scopes, s) {
+			return nil, fmt.Errorf("unknown scope %q", n)
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	return scopes, nil
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateToken stores a new token and returns its secret, which cannot be
+// recovered later. A ttl of zero means the token never expires.
+func (a *Auth) CreateToken(ctx context.Context, name string, scopes []Scope, ttl time.Duration) (string, Token, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", Token{}, errors.New("auth: token name must not be empty")
+	}
+	if len(scopes) == 0 {
+		return "", Token{}, errors.New("auth: token needs at least one scope")
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", Token{}, fmt.Errorf("auth: generate token: %w", err)
+	}
+	secret := tokenPrefix + hex.EncodeToString(b)
+
+	tok := Token{
+		Name:      name,
+		Prefix:    secret[:len(tokenPrefix)+8],
+		Scopes:    scopes,
+		CreatedAt: time.Now().UTC(),
+	}
+	var expiresAt any
+	if ttl > 0 {
+		tok.ExpiresAt = tok.CreatedAt.Add(ttl)
+		expiresAt = tok.ExpiresAt
+	}
+	names := make([]string, len(scopes))
+	for i, s := range scopes {
+		names[i] = string(s)
+	}
+	res, err := a.store.Exec(ctx,
+		`INSERT INTO api_tokens (name, prefix, token_hash, scopes, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		tok.Name, tok.Prefix, hashToken(secret), strings.Join(names, ","), expiresAt, tok.CreatedAt,
+	)
+	if err != nil {
+		return "", Token{}, fmt.Errorf("auth: store token: %w", err)
+	}
+	if tok.ID, err = res.LastInsertId(); err != nil {
+		return "", Token{}, fmt.Errorf("auth: token id: %w", err)
+	}
+	return secret, tok, nil
+}
+
+const tokenColumns = `id, name, prefix, scopes, expires_at, last_used_at, created_at`
+
+func scanToken(row interface{ Scan(...any) error }) (Token, error) {
+	var t Token
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.Name, &t.Prefix, &scopes, &expiresAt, &lastUsedAt, &t.CreatedAt); err != nil {
+		return Token{}, err
+	}
+	for s := range strings.SplitSeq(scopes, ",") {
+		t.Scopes = append(t.Scopes, Scope(s))
+	}
+	t.ExpiresAt = expiresAt.Time
+	t.LastUsedAt = lastUsedAt.Time
+	return t, nil
+}
+
+// ListTokens returns every stored token, newest first.
+func (a *Auth) ListTokens(ctx context.Context) ([]Token, error) {
+	rows, err := a.store.DB().QueryContext(ctx, `SELECT `+tokenColumns+` FROM api_tokens ORDER BY id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("auth: list tokens: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var tokens []Token
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("auth: scan token: %w", err)
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeToken deletes a token. It reports false if there was no such token.
+func (a *Auth) RevokeToken(ctx context.Context, id int64) (bool, error) {
+	res, err := a.store.Exec(ctx, `DELETE FROM api_tokens WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("auth: revoke token: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// ValidateToken looks up an unexpired token by its secret and records that
+// it was used.
+func (a *Auth) ValidateToken(ctx context.Context, secret string) (Token, bool) {
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return Token{}, false
+	}
+	row := a.store.DB().QueryRowContext(ctx, `SELECT `+tokenColumns+` FROM api_tokens WHERE token_hash = ?`, hashToken(secret))
+	t, err := scanToken(row)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			a.log.Error("auth: validate token", "error", err)
+		}
+		return Token{}, false
+	}
+	if t.Expired() {
+		return Token{}, false
+	}
+	if now := time.Now().UTC(); now.Sub(t.LastUsedAt) >= lastUsedInterval {
+		if _, err := a.store.Exec(ctx, `UPDATE api_tokens SET last_used_at = ? WHERE id = ?`, now, t.ID); err != nil {
+			a.log.Error("auth: update token last used", "error", err)
+		}
+		t.LastUsedAt = now
+	}
+	return t, true
+}
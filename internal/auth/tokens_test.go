@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateAndValidateToken(t *testing.T) {
+	a := newTestAuth(t, 24*time.Hour)
+	ctx := context.Background()
+
+	secret, tok, err := a.CreateToken(ctx, "shortcuts", []Scope{ScopeEventsRead}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(secret, tokenPrefix) || !strings.HasPrefix(secret, tok.Prefix) {
+		t.Errorf("secret %q, prefix %q", secret, tok.Prefix)
+	}
+
+	var stored string
+	if err := a.store.DB().QueryRow(`SELECT token_hash FROM api_tokens WHERE id = ?`, tok.ID).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored == secret || stored != hashToken(secret) {
+		t.Error("token should be stored as its hash")
+	}
+
+	got, ok := a.ValidateToken(ctx, secret)
+	if !ok {
+		t.Fatal("ValidateToken() = false for a fresh token")
+	}
+	if got.Name != "shortcuts" || !got.Allows(ScopeEventsRead) || got.Allows(ScopeReplay) {
+		t.Errorf("token = %+v", got)
+	}
+	if got.LastUsedAt.IsZero() {
+		t.Error("LastUsedAt not recorded")
+	}
+	tokens, err := a.ListTokens(ctx)
+	if err != nil || len(tokens) != 1 || tokens[0].LastUsedAt.IsZero() {
+		t.Errorf("ListTokens() = %+v, %v; want one token with last used set", tokens, err)
+	}
+
+	if _, ok := a.ValidateToken(ctx, secret+"x"); ok {
+		t.Error("ValidateToken() accepted a wrong secret")
+	}
+	if ok, err := a.RevokeToken(ctx, tok.ID); !ok || err != nil {
+		t.Fatalf("RevokeToken() = %v, %v", ok, err)
+	}
+	if _, ok := a.ValidateToken(ctx, secret); ok {
+		t.Error("ValidateToken() accepted a revoked token")
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	a := newTestAuth(t, 24*time.Hour)
+	secret, _, err := a.CreateToken(context.Background(), "brief", []Scope{ScopeAdmin}, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := a.ValidateToken(context.Background(), secret); !ok {
+		t.Fatal("token should be valid before expiry")
+	}
+	time.Sleep(80 * time.Millisecond)
+	if _, ok := a.ValidateToken(context.Background(), secret); ok {
+		t.Error("token should be invalid after expiry")
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes([]string{"replay", "events:read", "replay"})
+	if err != nil || len(scopes) != 2 {
+		t.Errorf("ParseScopes() = %v, %v", scopes, err)
+	}
+	if _, err := ParseScopes([]string{"root"}); err == nil {
+		t.Error("ParseScopes() accepted an unknown scope")
+	}
+	if _, err := ParseScopes(nil); err == nil {
+		t.Error("ParseScopes() accepted no scopes")
+	}
+}
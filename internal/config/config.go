@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/boozedog/smoothbrain/internal/schedule"
 )
 
 func DefaultStateDir() (string, error) {
@@ -24,7 +28,14 @@ type Config struct {
 	Plugins    map[string]json.RawMessage `json:"plugins"`
 	Routes     []RouteConfig              `json:"routes"`
 	Supervisor SupervisorConfig           `json:"supervisor"`
+	Heartbeats []HeartbeatConfig          `json:"heartbeats"`
 	Tailscale  TailscaleConfig            `json:"tailscale"`
+	Backup     BackupConfig               `json:"backup"`
+	Outbound   OutboundConfig             `json:"outbound"`
+	Ingest     IngestConfig               `json:"ingest"`
+	// DrainTimeout is how long shutdown waits for running pipelines before
+	// interrupting them. Go duration string, default "30s".
+	DrainTimeout string `json:"drain_timeout"`
 }
 
 type AuthConfig struct {
@@ -46,6 +57,9 @@ type RouteConfig struct {
 	Timeout     string       `json:"timeout,omitempty"` // Go duration string, default "30s"
 	Pipeline    []StepConfig `json:"pipeline"`
 	Sink        SinkConfig   `json:"sink"`
+	// Emit, when set, re-emits the pipeline output as a "route" event of this
+	// type, so other routes can chain off it.
+	Emit string `json:"emit,omitempty"`
 }
 
 type StepConfig struct {
@@ -64,10 +78,116 @@ type SupervisorConfig struct {
 }
 
 type SupervisorTask struct {
-	Name     string `json:"name"`
-	Schedule string `json:"schedule"`
-	Prompt   string `json:"prompt"`
-	Plugin   string `json:"plugin"`
+	Name     string         `json:"name"`
+	Schedule string         `json:"schedule"`           // cron expression, @descriptor or Go duration
+	Timezone string         `json:"timezone,omitempty"` // IANA name, default local time
+	Jitter   string         `json:"jitter,omitempty"`   // Go duration; random delay added to each run
+	CatchUp  string         `json:"catch_up,omitempty"` // runs missed while down: "skip" (default), "once", "all"
+	Prompt   string         `json:"prompt"`             // sent as payload "message" unless the payload sets one
+	Payload  map[string]any `json:"payload,omitempty"`
+
+	// A task either emits its event on the bus for routes to match, or runs
+	// a pipeline directly: a named route (Route) or an inline one
+	// (Pipeline plus Sink, with Plugin as shorthand for sink.plugin).
+	Route    string       `json:"route,omitempty"`
+	Pipeline []StepConfig `json:"pipeline,omitempty"`
+	Sink     SinkConfig   `json:"sink,omitzero"`
+	Plugin   string       `json:"plugin,omitempty"`
+	Timeout  string       `json:"timeout,omitempty"` // inline pipeline timeout, default 30s
+}
+
+// Inline reports whether the task runs its own pipeline rather than a route.
+func (t SupervisorTask) Inline() bool {
+	return len(t.Pipeline) > 0 || t.Sink.Plugin != "" || t.Plugin != ""
+}
+
+// InlineRoute returns the task's inline pipeline as a route named
+// "supervisor:<task>".
+func (t SupervisorTask) InlineRoute() RouteConfig {
+	sink := t.Sink
+	if sink.Plugin == "" {
+		sink.Plugin = t.Plugin
+	}
+	return RouteConfig{
+		Name:     "supervisor:" + t.Name,
+		Source:   "supervisor",
+		Event:    t.Name,
+		Pipeline: t.Pipeline,
+		Sink:     sink,
+		Timeout:  t.Timeout,
+	}
+}
+
+// Catch-up policies for supervisor runs missed while smoothbrain was down.
+const (
+	CatchUpSkip = "skip"
+	CatchUpOnce = "once"
+	CatchUpAll  = "all"
+)
+
+// Location returns the task's timezone, defaulting to time.Local.
+func (t SupervisorTask) Location() (*time.Location, error) {
+	if t.Timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(t.Timezone)
+}
+
+// JitterDuration returns the parsed jitter, or 0 if unset.
+func (t SupervisorTask) JitterDuration() (time.Duration, error) {
+	if t.Jitter == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(t.Jitter)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("jitter %q must be a non-negative duration", t.Jitter)
+	}
+	return d, nil
+}
+
+// HeartbeatConfig declares an event that must keep arriving: from Source
+// (optionally of type Event, with payload fields equal to Match) at least
+// once per Every. Any heartbeat can also be pinged over HTTP at
+// /hooks/heartbeat/<name>, so Source may be left empty for cron jobs and
+// backups that only ping.
+type HeartbeatConfig struct {
+	Name   string         `json:"name"`
+	Source string         `json:"source,omitempty"`
+	Event  string         `json:"event,omitempty"`
+	Match  map[string]any `json:"match,omitempty"`
+	Every  string         `json:"every"` // Go duration string
+}
+
+// Interval returns the parsed Every duration.
+func (h HeartbeatConfig) Interval() (time.Duration, error) {
+	d, err := time.ParseDuration(h.Every)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("every %q must be a positive duration", h.Every)
+	}
+	return d, nil
+}
+
+// BackupConfig enables scheduled online backups when Dir is set.
+type BackupConfig struct {
+	Dir        string `json:"dir"`
+	Interval   string `json:"interval,omitempty"` // Go duration string, default "24h"
+	KeepDaily  int    `json:"keep_daily"`
+	KeepWeekly int    `json:"keep_weekly"`
+}
+
+// OutboundConfig tunes the HTTP client plugins use for external services.
+type OutboundConfig struct {
+	RateLimits       map[string]float64 `json:"rate_limits,omitempty"` // requests per second by host
+	MaxRetries       int                `json:"max_retries"`
+	BreakerThreshold int                `json:"breaker_threshold"`          // consecutive failures that open a host's circuit
+	BreakerCooldown  string             `json:"breaker_cooldown,omitempty"` // Go duration string, default "30s"
+}
+
+// IngestConfig lists the sources POST /api/events accepts. Routes and
+// heartbeats can match them like plugin sources; a plugin's own name cannot
+// be used, so a token cannot speak for a plugin.
+type IngestConfig struct {
+	Sources []string `json:"sources,omitempty"`
 }
 
 type TailscaleConfig struct {
@@ -101,6 +221,17 @@ func Load(path string) (*Config, error) {
 			ServiceName: "svc:smoothbrain",
 			StateDir:    tsnetStateDir,
 		},
+		Backup: BackupConfig{
+			Interval:   "24h",
+			KeepDaily:  7,
+			KeepWeekly: 4,
+		},
+		Outbound: OutboundConfig{
+			MaxRetries:       3,
+			BreakerThreshold: 5,
+			BreakerCooldown:  "30s",
+		},
+		DrainTimeout: "30s",
 	}
 	if err := json.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("parsing config: %w", err)
@@ -123,6 +254,37 @@ func (c *Config) validate() error {
 	if c.Tailscale.Enabled && c.Tailscale.ServiceName == "" {
 		return fmt.Errorf("config: tailscale.service_name must not be empty when enabled")
 	}
+	if c.Backup.Dir != "" {
+		d, err := time.ParseDuration(c.Backup.Interval)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("config: backup.interval %q must be a positive duration", c.Backup.Interval)
+		}
+		if c.Backup.KeepDaily < 0 || c.Backup.KeepWeekly < 0 {
+			return fmt.Errorf("config: backup.keep_daily and backup.keep_weekly must not be negative")
+		}
+	}
+	if d, err := time.ParseDuration(c.DrainTimeout); err != nil || d <= 0 {
+		return fmt.Errorf("config: drain_timeout %q must be a positive duration", c.DrainTimeout)
+	}
+	if c.Outbound.MaxRetries < 0 {
+		return fmt.Errorf("config: outbound.max_retries must not be negative")
+	}
+	if c.Outbound.BreakerThreshold < 1 {
+		return fmt.Errorf("config: outbound.breaker_threshold must be at least 1")
+	}
+	if d, err := time.ParseDuration(c.Outbound.BreakerCooldown); err != nil || d <= 0 {
+		return fmt.Errorf("config: outbound.breaker_cooldown %q must be a positive duration", c.Outbound.BreakerCooldown)
+	}
+	for host, rps := range c.Outbound.RateLimits {
+		if rps <= 0 {
+			return fmt.Errorf("config: outbound.rate_limits[%q] must be positive", host)
+		}
+	}
+	for i, src := range c.Ingest.Sources {
+		if src == "" {
+			return fmt.Errorf("config: ingest.sources[%d] must not be empty", i)
+		}
+	}
 	seen := make(map[string]bool)
 	for i, r := range c.Routes {
 		if r.Name == "" {
@@ -135,8 +297,126 @@ func (c *Config) validate() error {
 		if r.Source == "" {
 			return fmt.Errorf("config: route %q: source must not be empty", r.Name)
 		}
-		if r.Sink.Plugin == "" {
-			return fmt.Errorf("config: route %q: sink.plugin must not be empty", r.Name)
+		if r.Sink.Plugin == "" && r.Emit == "" {
+			return fmt.Errorf("config: route %q: sink.plugin must not be empty unless emit is set", r.Name)
+		}
+	}
+	if err := checkEmitCycles(c.Routes); err != nil {
+		return err
+	}
+	tasks := make(map[string]bool)
+	for i, t := range c.Supervisor.Tasks {
+		if t.Name == "" {
+			return fmt.Errorf("config: supervisor.tasks[%d].name must not be empty", i)
+		}
+		if tasks[t.Name] {
+			return fmt.Errorf("config: duplicate supervisor task %q", t.Name)
+		}
+		tasks[t.Name] = true
+		loc, err := t.Location()
+		if err != nil {
+			return fmt.Errorf("config: supervisor task %q: timezone: %w", t.Name, err)
+		}
+		if _, err := schedule.Parse(t.Schedule, loc); err != nil {
+			return fmt.Errorf("config: supervisor task %q: %w", t.Name, err)
+		}
+		if _, err := t.JitterDuration(); err != nil {
+			return fmt.Errorf("config: supervisor task %q: %w", t.Name, err)
+		}
+		switch t.CatchUp {
+		case "", CatchUpSkip, CatchUpOnce, CatchUpAll:
+		default:
+			return fmt.Errorf("config: supervisor task %q: catch_up must be skip, once or all", t.Name)
+		}
+		if t.Route != "" {
+			if t.Inline() {
+				return fmt.Errorf("config: supervisor task %q: route and an inline pipeline or sink are mutually exclusive", t.Name)
+			}
+			if !seen[t.Route] {
+				return fmt.Errorf("config: supervisor task %q: unknown route %q", t.Name, t.Route)
+			}
+		}
+		if t.Sink.Plugin != "" && t.Plugin != "" && t.Sink.Plugin != t.Plugin {
+			return fmt.Errorf("config: supervisor task %q: plugin %q conflicts with sink.plugin %q", t.Name, t.Plugin, t.Sink.Plugin)
+		}
+		for j, step := range t.Pipeline {
+			if step.Plugin == "" || step.Action == "" {
+				return fmt.Errorf("config: supervisor task %q: pipeline[%d] needs plugin and action", t.Name, j)
+			}
+		}
+		if t.Timeout != "" {
+			if d, err := time.ParseDuration(t.Timeout); err != nil || d <= 0 {
+				return fmt.Errorf("config: supervisor task %q: timeout %q must be a positive duration", t.Name, t.Timeout)
+			}
+		}
+	}
+	beats := make(map[string]bool)
+	for i, h := range c.Heartbeats {
+		if h.Name == "" {
+			return fmt.Errorf("config: heartbeats[%d].name must not be empty", i)
+		}
+		if beats[h.Name] {
+			return fmt.Errorf("config: duplicate heartbeat %q", h.Name)
+		}
+		beats[h.Name] = true
+		if _, err := h.Interval(); err != nil {
+			return fmt.Errorf("config: heartbeat %q: %w", h.Name, err)
+		}
+		if h.Source == "" && (h.Event != "" || len(h.Match) > 0) {
+			return fmt.Errorf("config: heartbeat %q: event and match require a source", h.Name)
+		}
+	}
+	return nil
+}
+
+// checkEmitCycles rejects routes whose emitted events would, directly or
+// through other routes, trigger them again.
+func checkEmitCycles(routes []RouteConfig) error {
+	// next lists the routes each route's emitted event matches.
+	next := make(map[int][]int)
+	for i, r := range routes {
+		if r.Emit == "" {
+			continue
+		}
+		for j, t := range routes {
+			if t.Source == "route" && (t.Event == "" || t.Event == r.Emit) {
+				next[i] = append(next[i], j)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(routes))
+	var path []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		state[i] = visiting
+		path = append(path, routes[i].Name)
+		for _, j := range next[i] {
+			switch state[j] {
+			case visiting:
+				start := slices.Index(path, routes[j].Name)
+				cycle := append(slices.Clone(path[start:]), routes[j].Name)
+				return fmt.Errorf("config: route %q: emit %q forms a cycle: %s", routes[i].Name, routes[i].Emit, strings.Join(cycle, " -> "))
+			case unvisited:
+				if err := visit(j); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = done
+		return nil
+	}
+	for i := range routes {
+		if state[i] == unvisited {
+			if err := visit(i); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 func writeConfig(t *testing.T, content string) string {
@@ -142,3 +143,192 @@ func TestLoad_RouteValidation_EmptySource(t *testing.T) {
 		t.Errorf("error = %q, want it to mention source", err)
 	}
 }
+
+func TestLoad_RouteValidation_SinkOptionalWithEmit(t *testing.T) {
+	path := writeConfig(t, `{"routes":[{"name":"r1","source":"a","emit":"done"}]}`)
+	if _, err := Load(path); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	path = writeConfig(t, `{"routes":[{"name":"r1","source":"a"}]}`)
+	if _, err := Load(path); err == nil {
+		t.Fatal("Load() expected error for route without sink or emit")
+	}
+}
+
+func TestLoad_RouteValidation_EmitCycle(t *testing.T) {
+	for name, routes := range map[string]string{
+		"self, no event filter": `[{"name":"a","source":"route","emit":"x","sink":{"plugin":"s"}}]`,
+		"self, same event":      `[{"name":"a","source":"route","event":"x","emit":"x"}]`,
+		"two routes": `[
+			{"name":"a","source":"route","event":"x","emit":"y"},
+			{"name":"b","source":"route","event":"y","emit":"x"}
+		]`,
+	} {
+		_, err := Load(writeConfig(t, `{"routes":`+routes+`}`))
+		if err == nil || !strings.Contains(err.Error(), "cycle") {
+			t.Errorf("%s: error = %v, want a cycle error", name, err)
+		}
+	}
+
+	// A chain that ends is fine.
+	path := writeConfig(t, `{"routes":[
+		{"name":"a","source":"src","emit":"x"},
+		{"name":"b","source":"route","event":"x","emit":"y"},
+		{"name":"c","source":"route","event":"y","sink":{"plugin":"s"}}
+	]}`)
+	if _, err := Load(path); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+}
+
+func TestLoad_BackupDefaults(t *testing.T) {
+	path := writeConfig(t, `{"backup":{"dir":"/tmp/backups"}}`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Backup.Interval != "24h" || cfg.Backup.KeepDaily != 7 || cfg.Backup.KeepWeekly != 4 {
+		t.Errorf("Backup = %+v, want 24h/7/4 defaults", cfg.Backup)
+	}
+}
+
+func TestLoad_DrainTimeout(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `{}`))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.DrainTimeout != "30s" {
+		t.Errorf("DrainTimeout = %q, want 30s default", cfg.DrainTimeout)
+	}
+	if _, err := Load(writeConfig(t, `{"drain_timeout": "0s"}`)); err == nil || !strings.Contains(err.Error(), "drain_timeout") {
+		t.Errorf("Load() error = %v, want drain_timeout error", err)
+	}
+}
+
+func TestLoad_Outbound(t *testing.T) {
+	cfg, err := Load(writeConfig(t, `{"outbound":{"rate_limits":{"api.x.ai":2}}}`))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Outbound.MaxRetries != 3 || cfg.Outbound.BreakerThreshold != 5 || cfg.Outbound.BreakerCooldown != "30s" {
+		t.Errorf("Outbound = %+v, want defaults kept", cfg.Outbound)
+	}
+	if cfg.Outbound.RateLimits["api.x.ai"] != 2 {
+		t.Errorf("RateLimits = %v", cfg.Outbound.RateLimits)
+	}
+	for _, bad := range []string{
+		`{"outbound":{"max_retries":-1}}`,
+		`{"outbound":{"breaker_threshold":0}}`,
+		`{"outbound":{"breaker_cooldown":"soon"}}`,
+		`{"outbound":{"rate_limits":{"api.x.ai":0}}}`,
+	} {
+		if _, err := Load(writeConfig(t, bad)); err == nil || !strings.Contains(err.Error(), "outbound.") {
+			t.Errorf("Load(%s) error = %v, want outbound error", bad, err)
+		}
+	}
+}
+
+func TestLoad_BackupValidation_BadInterval(t *testing.T) {
+	path := writeConfig(t, `{"backup":{"dir":"/tmp/backups","interval":"daily"}}`)
+	_, err := Load(path)
+	if err == nil {
+		t.Fatal("Load() expected validation error for bad backup interval, got nil")
+	}
+	if !strings.Contains(err.Error(), "backup.interval") {
+		t.Errorf("error = %q, want it to mention backup.interval", err)
+	}
+}
+
+func TestLoad_SupervisorValidation(t *testing.T) {
+	for _, tc := range []struct {
+		name, tasks, want string
+	}{
+		{"bad schedule", `[{"name":"a","schedule":"61 * * * *"}]`, "minute"},
+		{"bad timezone", `[{"name":"a","schedule":"@daily","timezone":"Mars/Olympus"}]`, "timezone"},
+		{"bad jitter", `[{"name":"a","schedule":"@daily","jitter":"soon"}]`, "jitter"},
+		{"bad catch_up", `[{"name":"a","schedule":"@daily","catch_up":"some"}]`, "catch_up"},
+		{"duplicate", `[{"name":"a","schedule":"1h"},{"name":"a","schedule":"2h"}]`, "duplicate"},
+		{"unknown route", `[{"name":"a","schedule":"1h","route":"missing"}]`, "unknown route"},
+		{"route and inline", `[{"name":"a","schedule":"1h","route":"missing","plugin":"mm"}]`, "mutually exclusive"},
+		{"plugin conflict", `[{"name":"a","schedule":"1h","plugin":"mm","sink":{"plugin":"obsidian"}}]`, "conflicts"},
+		{"step without action", `[{"name":"a","schedule":"1h","pipeline":[{"plugin":"db"}]}]`, "pipeline[0]"},
+		{"bad timeout", `[{"name":"a","schedule":"1h","plugin":"mm","timeout":"-1s"}]`, "timeout"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfig(t, `{"supervisor":{"tasks":`+tc.tasks+`}}`)
+			_, err := Load(path)
+			if err == nil {
+				t.Fatal("Load() expected validation error")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("error = %q, want it to mention %s", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestLoad_SupervisorTaskRoute(t *testing.T) {
+	path := writeConfig(t, `{
+		"routes": [{"name": "notify", "source": "webhook", "sink": {"plugin": "mm"}}],
+		"supervisor": {"tasks": [
+			{"name": "a", "schedule": "1h", "route": "notify", "payload": {"days": 30}},
+			{"name": "b", "schedule": "1h", "plugin": "mm", "timeout": "2m"}
+		]}
+	}`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	a, b := cfg.Supervisor.Tasks[0], cfg.Supervisor.Tasks[1]
+	if a.Inline() || a.Payload["days"] != 30.0 {
+		t.Errorf("task a = %+v, want route task with payload", a)
+	}
+	r := b.InlineRoute()
+	if !b.Inline() || r.Name != "supervisor:b" || r.Sink.Plugin != "mm" || r.Timeout != "2m" {
+		t.Errorf("task b inline route = %+v", r)
+	}
+}
+
+func TestLoad_Heartbeats(t *testing.T) {
+	path := writeConfig(t, `{"heartbeats": [
+		{"name": "backup", "every": "26h"},
+		{"name": "feed", "source": "miniflux", "event": "entry", "match": {"feed": "news"}, "every": "1h"}
+	]}`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(cfg.Heartbeats) != 2 {
+		t.Fatalf("len(Heartbeats) = %d, want 2", len(cfg.Heartbeats))
+	}
+	if d, _ := cfg.Heartbeats[1].Interval(); d != time.Hour {
+		t.Errorf("Interval() = %v, want 1h", d)
+	}
+	if cfg.Heartbeats[1].Match["feed"] != "news" {
+		t.Errorf("Match = %v", cfg.Heartbeats[1].Match)
+	}
+}
+
+func TestLoad_HeartbeatValidation(t *testing.T) {
+	for _, tc := range []struct {
+		name, beats, want string
+	}{
+		{"no name", `[{"every":"1h"}]`, "name"},
+		{"duplicate", `[{"name":"a","every":"1h"},{"name":"a","every":"2h"}]`, "duplicate"},
+		{"bad every", `[{"name":"a","every":"often"}]`, "every"},
+		{"missing every", `[{"name":"a"}]`, "every"},
+		{"event without source", `[{"name":"a","event":"done","every":"1h"}]`, "source"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := writeConfig(t, `{"heartbeats":`+tc.beats+`}`)
+			_, err := Load(path)
+			if err == nil {
+				t.Fatal("Load() expected validation error")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("error = %q, want it to mention %s", err, tc.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"encoding/json"
 	"log/slog"
 	"sync"
@@ -33,8 +34,8 @@ func (b *Bus) Emit(event plugin.Event) {
 	subs := b.subscribers
 	b.mu.RUnlock()
 
-	b.log.Debug("event emitted", "source", event.Source, "type", event.Type, "id", event.ID)
-	b.logEvent(event)
+	event = b.Record(event)
+	b.log.Debug("event emitted", "source", event.Source, "type", event.Type, "id", event.ID, "parent_id", event.ParentID)
 
 	for _, fn := range subs {
 		func() {
@@ -48,17 +49,52 @@ func (b *Bus) Emit(event plugin.Event) {
 	}
 }
 
+// Record resolves the event's lineage and persists it without dispatching it
+// to subscribers. It is used when the caller runs the event's pipeline itself.
+func (b *Bus) Record(event plugin.Event) plugin.Event {
+	event = b.resolveLineage(event)
+	b.logEvent(event)
+	return event
+}
+
+// resolveLineage fills in CorrelationID: a root event correlates with itself,
+// and a child inherits its parent's correlation ID.
+func (b *Bus) resolveLineage(event plugin.Event) plugin.Event {
+	if event.CorrelationID != "" {
+		return event
+	}
+	if event.ParentID == "" {
+		event.CorrelationID = event.ID
+		return event
+	}
+	err := b.store.DB().QueryRow(
+		`SELECT COALESCE(correlation_id, id) FROM events WHERE id = ?`, event.ParentID,
+	).Scan(&event.CorrelationID)
+	if err != nil {
+		event.CorrelationID = event.ParentID
+	}
+	return event
+}
+
 func (b *Bus) logEvent(event plugin.Event) {
 	payload, err := json.Marshal(event.Payload)
 	if err != nil {
 		b.log.Error("failed to marshal event payload", "error", err)
 		return
 	}
-	_, err = b.store.DB().Exec(
-		`INSERT OR IGNORE INTO events (id, source, type, payload, timestamp) VALUES (?, ?, ?, ?, ?)`,
-		event.ID, event.Source, event.Type, string(payload), event.Timestamp,
+	_, err = b.store.Exec(context.Background(),
+		`INSERT OR IGNORE INTO events (id, source, type, payload, timestamp, parent_id, correlation_id) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		event.ID, event.Source, event.Type, string(payload), event.Timestamp, nullStr(event.ParentID), event.CorrelationID,
 	)
 	if err != nil {
 		b.log.Error("failed to log event", "error", err)
 	}
 }
+
+// nullStr maps "" to NULL.
+func nullStr(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -96,6 +96,37 @@ func TestBus_DBLogging(t *testing.T) {
 	}
 }
 
+func TestBus_Lineage(t *testing.T) {
+	st, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	bus := NewBus(st, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	root := testEvent("root")
+	bus.Emit(root)
+
+	// A child that only names its parent inherits the root's correlation ID.
+	child := testEvent("child")
+	child.ParentID = "root"
+	bus.Emit(child)
+
+	grandchild := testEvent("grandchild")
+	grandchild.ParentID = "child"
+	bus.Emit(grandchild)
+
+	for _, id := range []string{"root", "child", "grandchild"} {
+		var corr string
+		if err := st.DB().QueryRow(`SELECT correlation_id FROM events WHERE id = ?`, id).Scan(&corr); err != nil {
+			t.Fatal(err)
+		}
+		if corr != "root" {
+			t.Errorf("%s correlation_id = %q, want root", id, corr)
+		}
+	}
+}
+
 func TestBus_ConcurrentEmit(t *testing.T) {
 	bus := newTestBus(t)
 	var received atomic.Int32
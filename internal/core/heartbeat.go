@@ -0,0 +1,299 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/boozedog/smoothbrain/internal/config"
+	"github.com/boozedog/smoothbrain/internal/plugin"
+	"github.com/boozedog/smoothbrain/internal/store"
+	"github.com/google/uuid"
+)
+
+// heartbeatCheckInterval is how often overdue heartbeats are looked for. It
+// is shortened for heartbeats with a smaller interval.
+const heartbeatCheckInterval = 30 * time.Second
+
+// ErrUnknownHeartbeat is returned for a heartbeat name not in the
+// configuration.
+var ErrUnknownHeartbeat = errors.New("unknown heartbeat")
+
+// Heartbeats is a dead man's switch. Each configured heartbeat must be seen,
+// as a matching bus event or an HTTP ping, at least once per interval; when
+// one goes quiet a "heartbeat.missed" event is emitted, and when it is seen
+// again a "heartbeat.recovered" event follows.
+type Heartbeats struct {
+	beats []config.HeartbeatConfig
+	bus   *Bus
+	store *store.Store
+	log   *slog.Logger
+	now   func() time.Time
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu    sync.Mutex
+	state map[string]*beatState
+}
+
+type beatState struct {
+	every    time.Duration
+	lastSeen time.Time // zero if never seen
+	since    time.Time // start of the first interval for a never-seen heartbeat
+	missedAt time.Time // zero unless currently missed
+}
+
+func NewHeartbeats(beats []config.HeartbeatConfig, bus *Bus, store *store.Store, log *slog.Logger) *Heartbeats {
+	h := &Heartbeats{
+		beats: beats,
+		bus:   bus,
+		store: store,
+		log:   log,
+		now:   time.Now,
+		state: make(map[string]*beatState),
+	}
+	h.load()
+	return h
+}
+
+// load restores each heartbeat's last seen and missed times. A heartbeat
+// never seen before gets one full interval from now.
+func (h *Heartbeats) load() {
+	now := h.now()
+	for _, b := range h.beats {
+		every, err := b.Interval()
+		if err != nil {
+			h.log.Error("invalid heartbeat interval", "heartbeat", b.Name, "error", err)
+			continue
+		}
+		st := &beatState{every: every, since: now}
+		var lastSeen, missedAt sql.NullTime
+		err = h.store.DB().QueryRow(
+			`SELECT last_seen, missed_at FROM heartbeat_state WHERE name = ?`, b.Name,
+		).Scan(&lastSeen, &missedAt)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			h.log.Error("failed to load heartbeat state", "heartbeat", b.Name, "error", err)
+		}
+		st.lastSeen = lastSeen.Time
+		st.missedAt = missedAt.Time
+		h.state[b.Name] = st
+	}
+}
+
+func (h *Heartbeats) Start(ctx context.Context) {
+	if h.cancel != nil || len(h.beats) == 0 {
+		return
+	}
+	ctx, h.cancel = context.WithCancel(ctx)
+	tick := heartbeatCheckInterval
+	h.mu.Lock()
+	for _, st := range h.state {
+		for st.every < 2*tick && tick > time.Second {
+			tick /= 2
+		}
+	}
+	h.mu.Unlock()
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		h.check()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.check()
+			}
+		}
+	}()
+	h.log.Info("heartbeat monitor started", "heartbeats", len(h.beats), "check_interval", tick)
+}
+
+func (h *Heartbeats) Stop() {
+	if h.cancel != nil {
+		h.cancel()
+	}
+	h.wg.Wait()
+}
+
+// HandleEvent is a bus subscriber that counts matching events as beats.
+// Heartbeat events themselves are ignored.
+func (h *Heartbeats) HandleEvent(event plugin.Event) {
+	if event.Source == "heartbeat" {
+		return
+	}
+	for _, b := range h.beats {
+		if b.Source == event.Source && (b.Event == "" || b.Event == event.Type) && payloadMatches(b.Match, event.Payload) {
+			h.beat(b.Name, event.ID)
+		}
+	}
+}
+
+// Ping records a beat for the named heartbeat, as from an HTTP ping.
+func (h *Heartbeats) Ping(name string) error {
+	return h.beat(name, "")
+}
+
+// payloadMatches reports whether every field in match equals the payload's.
+// Values are compared by their formatted form so JSON numbers match ints.
+func payloadMatches(match, payload map[string]any) bool {
+	for k, want := range match {
+		got, ok := payload[k]
+		if !ok || fmt.Sprint(got) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
+
+// beat marks the heartbeat seen and, if it was missed, emits its recovery
+// as a child of the event that revived it.
+func (h *Heartbeats) beat(name, eventID string) error {
+	now := h.now()
+	h.mu.Lock()
+	st, ok := h.state[name]
+	if !ok {
+		h.mu.Unlock()
+		return ErrUnknownHeartbeat
+	}
+	missedAt := st.missedAt
+	st.lastSeen, st.missedAt = now, time.Time{}
+	h.mu.Unlock()
+
+	h.save(name, now, time.Time{})
+	if missedAt.IsZero() {
+		return nil
+	}
+
+	down := now.Sub(missedAt).Round(time.Second)
+	h.log.Info("heartbeat recovered", "heartbeat", name, "down", down)
+	h.emit("heartbeat.recovered", eventID, map[string]any{
+		"name":      name,
+		"last_seen": now.UTC().Format(time.RFC3339),
+		"missed_at": missedAt.UTC().Format(time.RFC3339),
+		"summary":   fmt.Sprintf("Heartbeat %q recovered after %s.", name, down),
+	})
+	return nil
+}
+
+// check emits "heartbeat.missed" for each heartbeat that has gone a full
+// interval without a beat. A heartbeat is reported once until it recovers.
+func (h *Heartbeats) check() {
+	now := h.now()
+	type missed struct {
+		name     string
+		every    time.Duration
+		lastSeen time.Time
+	}
+	var overdue []missed
+	h.mu.Lock()
+	for name, st := range h.state {
+		if !st.missedAt.IsZero() {
+			continue
+		}
+		base := st.lastSeen
+		if base.IsZero() {
+			base = st.since
+		}
+		if now.Sub(base) > st.every {
+			st.missedAt = now
+			overdue = append(overdue, missed{name, st.every, st.lastSeen})
+		}
+	}
+	h.mu.Unlock()
+
+	for _, m := range overdue {
+		h.save(m.name, m.lastSeen, now)
+		seen, summary := "never", fmt.Sprintf("Heartbeat %q missed: never seen in %s.", m.name, m.every)
+		if !m.lastSeen.IsZero() {
+			seen = m.lastSeen.UTC().Format(time.RFC3339)
+			summary = fmt.Sprintf("Heartbeat %q missed: last seen %s ago (expected every %s).",
+				m.name, now.Sub(m.lastSeen).Round(time.Second), m.every)
+		}
+		h.log.Warn("heartbeat missed", "heartbeat", m.name, "last_seen", seen)
+		h.emit("heartbeat.missed", "", map[string]any{
+			"name":      m.name,
+			"every":     m.every.String(),
+			"last_seen": seen,
+			"summary":   summary,
+		})
+	}
+}
+
+func (h *Heartbeats) emit(typ, parentID string, payload map[string]any) {
+	h.bus.Emit(plugin.Event{
+		ID:        uuid.New().String(),
+		Source:    "heartbeat",
+		Type:      typ,
+		Payload:   payload,
+		Timestamp: h.now(),
+		ParentID:  parentID,
+	})
+}
+
+func (h *Heartbeats) save(name string, lastSeen, missedAt time.Time) {
+	_, err := h.store.Exec(context.Background(),
+		`INSERT INTO heartbeat_state (name, last_seen, missed_at) VALUES (?, ?, ?)
+		 ON CONFLICT(name) DO UPDATE SET last_seen = excluded.last_seen, missed_at = excluded.missed_at`,
+		name, nullTime(lastSeen), nullTime(missedAt),
+	)
+	if err != nil {
+		h.log.Error("failed to save heartbeat state", "heartbeat", name, "error", err)
+	}
+}
+
+// nullTime maps the zero time to NULL.
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC()
+}
+
+// HeartbeatStatus describes a heartbeat's state for the UI.
+type HeartbeatStatus struct {
+	Name     string    `json:"name"`
+	Source   string    `json:"source,omitempty"`
+	Event    string    `json:"event,omitempty"`
+	Every    string    `json:"every"`
+	LastSeen time.Time `json:"last_seen"` // zero if never seen
+	Due      time.Time `json:"due"`
+	Missed   bool      `json:"missed"`
+	MissedAt time.Time `json:"missed_at"` // zero unless missed
+}
+
+// Statuses returns each heartbeat's state in configuration order.
+func (h *Heartbeats) Statuses() []HeartbeatStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make([]HeartbeatStatus, 0, len(h.beats))
+	for _, b := range h.beats {
+		st, ok := h.state[b.Name]
+		if !ok {
+			continue
+		}
+		base := st.lastSeen
+		if base.IsZero() {
+			base = st.since
+		}
+		out = append(out, HeartbeatStatus{
+			Name:     b.Name,
+			Source:   b.Source,
+			Event:    b.Event,
+			Every:    b.Every,
+			LastSeen: st.lastSeen,
+			Due:      base.Add(st.every),
+			Missed:   !st.missedAt.IsZero(),
+			MissedAt: st.missedAt,
+		})
+	}
+	return out
+}
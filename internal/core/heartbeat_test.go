@@ -0,0 +1,112 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/boozedog/smoothbrain/internal/config"
+	"github.com/boozedog/smoothbrain/internal/plugin"
+	"github.com/boozedog/smoothbrain/internal/store"
+)
+
+func newTestHeartbeats(t *testing.T, st *store.Store, beats []config.HeartbeatConfig, now *time.Time) (*Heartbeats, *[]plugin.Event) {
+	t.Helper()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	bus := NewBus(st, log)
+	var emitted []plugin.Event
+	bus.Subscribe(func(e plugin.Event) {
+		if e.Source == "heartbeat" {
+			emitted = append(emitted, e)
+		}
+	})
+	h := &Heartbeats{beats: beats, bus: bus, store: st, log: log, now: func() time.Time { return *now }, state: make(map[string]*beatState)}
+	h.load()
+	bus.Subscribe(h.HandleEvent)
+	return h, &emitted
+}
+
+func openTestStore(t *testing.T) *store.Store {
+	t.Helper()
+	st, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	return st
+}
+
+func TestHeartbeats_MissedAndRecovered(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	beats := []config.HeartbeatConfig{{Name: "feed", Source: "miniflux", Event: "entry", Match: map[string]any{"feed": 7}, Every: "1h"}}
+	h, emitted := newTestHeartbeats(t, openTestStore(t), beats, &now)
+
+	now = now.Add(30 * time.Minute)
+	h.check()
+	if len(*emitted) != 0 {
+		t.Fatalf("emitted %d events within the interval, want 0", len(*emitted))
+	}
+
+	now = now.Add(time.Hour)
+	h.check()
+	h.check() // reported once until recovered
+	if len(*emitted) != 1 || (*emitted)[0].Type != "heartbeat.missed" {
+		t.Fatalf("emitted = %+v, want one heartbeat.missed", *emitted)
+	}
+	if got := (*emitted)[0].Payload["last_seen"]; got != "never" {
+		t.Errorf("last_seen = %v, want never", got)
+	}
+
+	// Non-matching events do not count as beats.
+	h.bus.Emit(plugin.Event{ID: "e1", Source: "miniflux", Type: "entry", Payload: map[string]any{"feed": 8}, Timestamp: now})
+	h.bus.Emit(plugin.Event{ID: "e2", Source: "miniflux", Type: "other", Payload: map[string]any{"feed": 7}, Timestamp: now})
+	if len(*emitted) != 1 {
+		t.Fatalf("non-matching events recovered the heartbeat: %+v", *emitted)
+	}
+
+	now = now.Add(time.Minute)
+	h.bus.Emit(plugin.Event{ID: "e3", Source: "miniflux", Type: "entry", Payload: map[string]any{"feed": 7.0}, Timestamp: now})
+	if len(*emitted) != 2 {
+		t.Fatalf("emitted %d events, want 2", len(*emitted))
+	}
+	rec := (*emitted)[1]
+	if rec.Type != "heartbeat.recovered" || rec.ParentID != "e3" {
+		t.Errorf("recovery = %s parent %q, want heartbeat.recovered parent e3", rec.Type, rec.ParentID)
+	}
+
+	s := h.Statuses()[0]
+	if s.Missed || !s.LastSeen.Equal(now) || !s.Due.Equal(now.Add(time.Hour)) {
+		t.Errorf("status = %+v", s)
+	}
+}
+
+func TestHeartbeats_PingAndPersist(t *testing.T) {
+	st := openTestStore(t)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	beats := []config.HeartbeatConfig{{Name: "backup", Every: "24h"}}
+	h, _ := newTestHeartbeats(t, st, beats, &now)
+
+	if err := h.Ping("nope"); !errors.Is(err, ErrUnknownHeartbeat) {
+		t.Errorf("Ping(unknown) = %v, want ErrUnknownHeartbeat", err)
+	}
+	if err := h.Ping("backup"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restart resumes from the persisted last beat, not from startup.
+	now = now.Add(25 * time.Hour)
+	h2, emitted := newTestHeartbeats(t, st, beats, &now)
+	h2.check()
+	if len(*emitted) != 1 || (*emitted)[0].Type != "heartbeat.missed" {
+		t.Fatalf("emitted = %+v, want one heartbeat.missed", *emitted)
+	}
+
+	// The missed state persists too, so it is not reported again.
+	h3, emitted := newTestHeartbeats(t, st, beats, &now)
+	h3.check()
+	if len(*emitted) != 0 || !h3.Statuses()[0].Missed {
+		t.Errorf("after restart emitted = %+v, missed = %v", *emitted, h3.Statuses()[0].Missed)
+	}
+}
@@ -3,47 +3,102 @@ package core
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"sync"
 	"time"
 
+	"github.com/a-h/templ"
 	"github.com/boozedog/smoothbrain/internal/plugin"
 	"github.com/boozedog/smoothbrain/internal/store"
 	"github.com/coder/websocket"
 )
 
+const (
+	// hubClientBuffer is how many messages a WebSocket client may fall
+	// behind by before it is disconnected. The page reconnects and gets a
+	// fresh table.
+	hubClientBuffer = 64
+	// hubQueue is how many events and run updates may wait to be rendered.
+	// Past that they are dropped and every client gets a fresh table instead.
+	hubQueue = 1024
+	// hubWriteTimeout bounds a single WebSocket write.
+	hubWriteTimeout = 5 * time.Second
+)
+
 type client struct {
 	conn   *websocket.Conn
+	send   chan []byte
+	ctx    context.Context
 	cancel context.CancelFunc
+
+	// What the client's last full table showed, so updates queued before it
+	// was rendered are not applied twice. Only Run touches these.
+	events map[string]bool
+	runs   map[int64]string // run ID to status
+}
+
+// hubUpdate is one bus event or run update waiting to be rendered.
+type hubUpdate struct {
+	event *plugin.Event
+	run   *RunUpdate
 }
 
+// Hub pushes the event log to WebSocket clients as htmx out-of-band
+// fragments: the full table when a client connects, then a row per new event
+// and a redraw of a run each time it starts, finishes a step or ends.
 type Hub struct {
 	mu      sync.Mutex
 	clients map[*client]struct{}
+	updates chan hubUpdate
+	joins   chan *client
 	notify  chan struct{}
 	store   *store.Store
 	log     *slog.Logger
+
+	// running holds the progress of runs still going, which is only stored
+	// once they end. Only Run touches it.
+	running map[int64]*pipelineRun
+	steps   map[int64][]stepResult
 }
 
 func NewHub(s *store.Store, log *slog.Logger) *Hub {
 	return &Hub{
 		clients: make(map[*client]struct{}),
+		updates: make(chan hubUpdate, hubQueue),
+		joins:   make(chan *client),
 		notify:  make(chan struct{}, 1),
 		store:   s,
 		log:     log,
+		running: make(map[int64]*pipelineRun),
+		steps:   make(map[int64][]stepResult),
 	}
 }
 
-// HandleEvent is a bus subscriber. Non-blocking send coalesces bursts.
+// HandleEvent is a bus subscriber. It must be subscribed before the router,
+// so an event's row reaches clients before its runs do.
 func (h *Hub) HandleEvent(e plugin.Event) {
+	h.enqueue(hubUpdate{event: &e})
+}
+
+// HandleRun is a router run subscriber.
+func (h *Hub) HandleRun(u RunUpdate) {
+	h.enqueue(hubUpdate{run: &u})
+}
+
+func (h *Hub) enqueue(u hubUpdate) {
 	select {
-	case h.notify <- struct{}{}:
+	case h.updates <- u:
 	default:
+		h.log.Warn("ws update queue full, resyncing clients")
+		h.Notify()
 	}
 }
 
-// Notify triggers a broadcast to all connected WebSocket clients.
+// Notify makes the hub send every client a fresh copy of the whole table.
+// Bursts coalesce into one redraw.
 func (h *Hub) Notify() {
 	select {
 	case h.notify <- struct{}{}:
@@ -51,49 +106,166 @@ func (h *Hub) Notify() {
 	}
 }
 
-// Run processes notifications and broadcasts to all clients.
+// Run renders queued updates and hands them to clients. It is the only
+// goroutine that renders, so clients see updates in the order they happened.
 func (h *Hub) Run(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case u := <-h.updates:
+			switch {
+			case u.event != nil:
+				h.sendEvent(*u.event)
+			case u.run != nil:
+				h.sendRun(*u.run)
+			}
+		case c := <-h.joins:
+			msg, events, runs, err := h.snapshot()
+			if err != nil {
+				h.log.Error("render events wrapper", "error", err)
+				c.cancel()
+				continue
+			}
+			c.events, c.runs = events, runs
+			c.send <- msg // the buffer is empty, so this cannot block
+			h.mu.Lock()
+			if c.ctx.Err() == nil {
+				h.clients[c] = struct{}{}
+			}
+			n := len(h.clients)
+			h.mu.Unlock()
+			h.log.Info("ws client connected", "clients", n)
 		case <-h.notify:
-			h.broadcast()
+			msg, events, runs, err := h.snapshot()
+			if err != nil {
+				h.log.Error("render events wrapper", "error", err)
+				continue
+			}
+			h.broadcast(msg, func(c *client) bool {
+				c.events, c.runs = events, runs
+				return true
+			})
 		}
 	}
 }
 
-func (h *Hub) broadcast() {
-	events := queryEvents(h.store, h.log)
-	views := toEventViews(events, h.store, h.log)
+// snapshot renders the whole table, with the live progress of running runs,
+// and reports which events and runs it showed.
+func (h *Hub) snapshot() ([]byte, map[string]bool, map[int64]string, error) {
+	views := toEventViews(queryEvents(h.store, h.log), h.store, h.log)
+	events := make(map[string]bool, len(views))
+	runs := make(map[int64]string)
+	for i, v := range views {
+		events[v.ID] = true
+		for j, r := range v.Runs {
+			if p, ok := h.running[r.ID]; ok {
+				if r.Status == "running" {
+					views[i].Runs[j] = *p
+				} else {
+					// The run's last update was dropped.
+					delete(h.running, r.ID)
+					delete(h.steps, r.ID)
+				}
+			}
+			runs[r.ID] = r.Status
+		}
+	}
+	msg, err := render(EventsWrapper(views))
+	return msg, events, runs, err
+}
 
-	var buf bytes.Buffer
-	if err := EventsWrapper(views).Render(context.Background(), &buf); err != nil {
-		h.log.Error("render events wrapper", "error", err)
+func (h *Hub) sendEvent(e plugin.Event) {
+	stored, ok := queryEvent(h.store, h.log, e.ID)
+	if !ok {
 		return
 	}
-	msg := buf.Bytes()
+	msg, err := render(eventRowsOOB(toEventView(stored, h.store, h.log)))
+	if err != nil {
+		h.log.Error("render event row", "error", err)
+		return
+	}
+	h.broadcast(msg, func(c *client) bool { return !c.events[e.ID] })
+}
+
+func (h *Hub) sendRun(u RunUpdate) {
+	run, ok := h.running[u.RunID]
+	if !ok {
+		run = &pipelineRun{ID: u.RunID, EventID: u.EventID, Route: u.Route}
+		h.running[u.RunID] = run
+	}
+	run.Status = u.Status
+	run.Error = u.Error
+	if u.Step != nil {
+		h.steps[u.RunID] = append(h.steps[u.RunID], *u.Step)
+		if b, err := json.Marshal(h.steps[u.RunID]); err == nil {
+			run.Steps = string(b)
+		}
+	}
+	run.Progress = fmt.Sprintf("%d/%d steps", len(h.steps[u.RunID]), u.Steps)
 
+	var frag templ.Component
+	switch {
+	case u.Status != "running":
+		// The stored run has the duration and the sink step.
+		delete(h.running, u.RunID)
+		delete(h.steps, u.RunID)
+		final := *run
+		final.Progress = ""
+		for _, r := range queryPipelineRuns(h.store, h.log, u.EventID) {
+			if r.ID == u.RunID {
+				final = r
+			}
+		}
+		frag = runUpdateOOB(final)
+	case u.Step == nil:
+		frag = runStartOOB(*run)
+	default:
+		frag = runUpdateOOB(*run)
+	}
+	msg, err := render(frag)
+	if err != nil {
+		h.log.Error("render pipeline run", "error", err)
+		return
+	}
+	h.broadcast(msg, func(c *client) bool {
+		status, shown := c.runs[u.RunID]
+		if u.Status == "running" && u.Step == nil {
+			return !shown // the client's table already has this run
+		}
+		return !shown || status == "running"
+	})
+}
+
+// broadcast queues msg for every client that want accepts, without
+// blocking. A client whose buffer is full is disconnected.
+func (h *Hub) broadcast(msg []byte, want func(*client) bool) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-
-	var dead []*client
 	for c := range h.clients {
-		writeCtx, writeCancel := context.WithTimeout(context.Background(), 5*time.Second)
-		err := c.conn.Write(writeCtx, websocket.MessageText, msg)
-		writeCancel()
-		if err != nil {
-			h.log.Debug("removing dead ws client", "error", err)
+		if !want(c) {
+			continue
+		}
+		select {
+		case c.send <- msg:
+		default:
+			h.log.Warn("ws client too slow, disconnecting")
+			delete(h.clients, c)
 			c.cancel()
-			dead = append(dead, c)
 		}
 	}
-	for _, c := range dead {
-		delete(h.clients, c)
+}
+
+func render(c templ.Component) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.Render(context.Background(), &buf); err != nil {
+		return nil, err
 	}
+	return buf.Bytes(), nil
 }
 
-// ServeHTTP upgrades the connection to WebSocket.
+// ServeHTTP upgrades the connection to WebSocket and writes the client's
+// messages until it disconnects or falls too far behind.
 func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	conn, err := websocket.Accept(w, r, nil)
 	if err != nil {
@@ -101,47 +273,37 @@ func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx, cancel := context.WithCancel(r.Context())
-	c := &client{conn: conn, cancel: cancel}
-
-	h.mu.Lock()
-	h.clients[c] = struct{}{}
-	h.mu.Unlock()
-
-	h.log.Info("ws client connected", "clients", len(h.clients))
-
-	// Send initial state.
-	events := queryEvents(h.store, h.log)
-	views := toEventViews(events, h.store, h.log)
-	var buf bytes.Buffer
-	if err := EventsWrapper(views).Render(ctx, &buf); err != nil {
-		h.log.Error("render events wrapper", "error", err)
-		h.mu.Lock()
-		delete(h.clients, c)
-		h.mu.Unlock()
+	// CloseRead handles control frames and cancels ctx when the peer goes.
+	ctx, cancel := context.WithCancel(conn.CloseRead(r.Context()))
+	c := &client{conn: conn, send: make(chan []byte, hubClientBuffer), ctx: ctx, cancel: cancel}
+	defer func() {
+		// Cancel first: Run will not register a client that is done.
 		cancel()
-		return
-	}
-	if err := conn.Write(ctx, websocket.MessageText, buf.Bytes()); err != nil {
-		h.log.Debug("ws initial push failed", "error", err)
 		h.mu.Lock()
 		delete(h.clients, c)
 		h.mu.Unlock()
-		cancel()
+		_ = conn.CloseNow()
+		h.log.Info("ws client disconnected")
+	}()
+
+	select {
+	case h.joins <- c:
+	case <-ctx.Done():
 		return
 	}
 
-	// Read loop keeps connection alive; exits on disconnect.
 	for {
-		_, _, err := conn.Read(ctx)
-		if err != nil {
-			break
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-c.send:
+			writeCtx, writeCancel := context.WithTimeout(ctx, hubWriteTimeout)
+			err := conn.Write(writeCtx, websocket.MessageText, msg)
+			writeCancel()
+			if err != nil {
+				h.log.Debug("ws write failed", "error", err)
+				return
+			}
 		}
 	}
-
-	h.mu.Lock()
-	delete(h.clients, c)
-	h.mu.Unlock()
-	cancel()
-	h.log.Info("ws client disconnected")
 }
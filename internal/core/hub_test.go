@@ -4,11 +4,14 @@ import (
 	"context"
 	"io"
 	"log/slog"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/boozedog/smoothbrain/internal/plugin"
 	"github.com/boozedog/smoothbrain/internal/store"
+	"github.com/coder/websocket"
 )
 
 func newTestHub(t *testing.T) *Hub {
@@ -34,6 +37,9 @@ func TestNewHub(t *testing.T) {
 	if cap(hub.notify) != 1 {
 		t.Errorf("notify channel cap = %d, want 1", cap(hub.notify))
 	}
+	if cap(hub.updates) != hubQueue {
+		t.Errorf("updates channel cap = %d, want %d", cap(hub.updates), hubQueue)
+	}
 }
 
 func TestHub_HandleEvent_NonBlocking(t *testing.T) {
@@ -95,30 +101,102 @@ func TestHub_Run_ContextCancel(t *testing.T) {
 	}
 }
 
-func TestHub_HandleEvent_Coalesces(t *testing.T) {
+func TestHub_QueueFullResyncs(t *testing.T) {
 	hub := newTestHub(t)
 
-	// Fill the notify channel
-	hub.notify <- struct{}{}
+	for range hubQueue {
+		hub.HandleEvent(plugin.Event{ID: "fill"})
+	}
 
-	// HandleEvent should not block even though channel is full (select default path)
+	// The queue is full: the update is dropped and a full redraw requested
+	// instead of blocking the bus.
 	done := make(chan struct{})
 	go func() {
-		hub.HandleEvent(plugin.Event{ID: "coalesce-test"})
+		hub.HandleRun(RunUpdate{RunID: 1, Status: "running"})
 		close(done)
 	}()
 
 	select {
 	case <-done:
-		// success — the select{default} path worked
 	case <-time.After(2 * time.Second):
-		t.Fatal("HandleEvent blocked when notify channel was full")
+		t.Fatal("HandleRun blocked when the update queue was full")
 	}
 
-	// Drain and verify exactly one notification was coalesced
 	select {
 	case <-hub.notify:
 	default:
-		t.Error("expected one notification in channel")
+		t.Error("expected a resync notification")
 	}
 }
+
+func TestHub_DropsSlowClient(t *testing.T) {
+	hub := newTestHub(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	slow := &client{send: make(chan []byte, 1), ctx: ctx, cancel: cancel}
+	fast := &client{send: make(chan []byte, 2), ctx: context.Background(), cancel: func() {}}
+	hub.clients[slow] = struct{}{}
+	hub.clients[fast] = struct{}{}
+
+	all := func(*client) bool { return true }
+	hub.broadcast([]byte("one"), all)
+	hub.broadcast([]byte("two"), all)
+
+	if _, ok := hub.clients[slow]; ok {
+		t.Error("slow client was not removed")
+	}
+	if ctx.Err() == nil {
+		t.Error("slow client was not cancelled")
+	}
+	if _, ok := hub.clients[fast]; !ok || len(fast.send) != 2 {
+		t.Errorf("fast client got %d messages, want 2", len(fast.send))
+	}
+}
+
+func TestHub_LiveUpdates(t *testing.T) {
+	hub := newTestHub(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go hub.Run(ctx)
+
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer func() { _ = conn.CloseNow() }()
+
+	read := func(want ...string) {
+		t.Helper()
+		readCtx, readCancel := context.WithTimeout(ctx, 2*time.Second)
+		defer readCancel()
+		_, msg, err := conn.Read(readCtx)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		for _, w := range want {
+			if !strings.Contains(string(msg), w) {
+				t.Errorf("message missing %q:\n%s", w, msg)
+			}
+		}
+	}
+
+	read(`id="events-table"`, "No events yet.")
+
+	bus := NewBus(hub.store, hub.log)
+	bus.Subscribe(hub.HandleEvent)
+	bus.Emit(plugin.Event{ID: "evt-live-1", Source: "src", Type: "ping", Payload: map[string]any{}, Timestamp: time.Now()})
+	read(`hx-swap-oob="afterbegin:#events-tbody"`, "evt-live")
+
+	hub.HandleRun(RunUpdate{RunID: 7, EventID: "evt-live-1", Route: "r", Status: "running", Steps: 2})
+	read(`hx-swap-oob="afterbegin:#runs-evt-live-1"`, `id="run-7"`, "0/2 steps")
+
+	step := stepResult{Plugin: "xf", Action: "upper", Status: "ok"}
+	hub.HandleRun(RunUpdate{RunID: 7, EventID: "evt-live-1", Route: "r", Status: "running", Step: &step, StepIndex: 1, Steps: 2})
+	read(`hx-swap-oob="innerHTML:#run-7"`, "1/2 steps", "xf.upper")
+
+	hub.HandleRun(RunUpdate{RunID: 7, EventID: "evt-live-1", Route: "r", Status: "failed", Error: "boom", Steps: 2})
+	read(`hx-swap-oob="innerHTML:#run-7"`, "failed", "boom")
+}
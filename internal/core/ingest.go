@@ -0,0 +1,221 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/boozedog/smoothbrain/internal/plugin"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxIngestBody caps a POST /api/events body.
+	maxIngestBody = 4 << 20
+	// maxIngestBatch caps the events in one NDJSON batch.
+	maxIngestBatch = 1000
+	// maxIngestWait caps ?wait=, so a client cannot hold a request open
+	// indefinitely.
+	maxIngestWait = 5 * time.Minute
+	// idempotencyTTL is how long an idempotency key maps to its event.
+	idempotencyTTL = 24 * time.Hour
+)
+
+// ingestEvent is one event pushed to POST /api/events.
+type ingestEvent struct {
+	Source         string         `json:"source"`
+	Type           string         `json:"type"`
+	Payload        map[string]any `json:"payload"`
+	IdempotencyKey string         `json:"idempotency_key,omitempty"`
+}
+
+// ingestResult reports what happened to one pushed event. Runs are only
+// filled in when the client asked to wait.
+type ingestResult struct {
+	EventID   string        `json:"event_id"`
+	Duplicate bool          `json:"duplicate,omitempty"` // the idempotency key was already used
+	Runs      []pipelineRun `json:"runs,omitempty"`
+	TimedOut  bool          `json:"timed_out,omitempty"` // runs were still going when the wait ran out
+	Error     string        `json:"error,omitempty"`     // the event was not emitted
+}
+
+// handleIngest pushes events from outside into the bus. The body is one
+// JSON event, or an NDJSON batch when sent as application/x-ndjson. An
+// Idempotency-Key header (or an event's idempotency_key) makes a retried
+// request return the original event instead of emitting it again. With
+// ?wait=<duration>, the response waits for the routes each event matched and
+// includes their runs and final payloads.
+func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
+	if s.bus == nil {
+		http.Error(w, "event ingestion unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	var wait time.Duration
+	if v := r.URL.Query().Get("wait"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "wait must be a positive duration", http.StatusBadRequest)
+			return
+		}
+		wait = min(d, maxIngestWait)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	batch := mediaType == "application/x-ndjson"
+	events, err := decodeIngest(http.MaxBytesReader(w, r.Body, maxIngestBody), batch, s.ingestSources)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		if batch {
+			http.Error(w, "use idempotency_key on each event in a batch, not the Idempotency-Key header", http.StatusBadRequest)
+			return
+		}
+		events[0].IdempotencyKey = key
+	}
+
+	// Every event is validated before any is emitted, so only a store error
+	// can fail one part way through a batch. The rest still go out, and each
+	// failed line reports its error so the client can retry just those.
+	results := make([]ingestResult, len(events))
+	failed := 0
+	for i, in := range events {
+		res, err := s.ingest(r.Context(), in)
+		if err != nil {
+			s.log.Error("ingest event", "error", err)
+			if !batch {
+				http.Error(w, "ingest failed", http.StatusInternalServerError)
+				return
+			}
+			res.Error = "ingest failed"
+			failed++
+		}
+		results[i] = res
+	}
+	if wait > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), wait)
+		defer cancel()
+		for i := range results {
+			if results[i].Error != "" {
+				continue
+			}
+			if s.router != nil && s.router.Wait(ctx, results[i].EventID) != nil {
+				results[i].TimedOut = true
+			}
+			results[i].Runs = queryPipelineRuns(s.store, s.log, results[i].EventID)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	var body any = results[0]
+	if batch {
+		body = map[string]any{"events": results}
+		if failed > 0 {
+			w.WriteHeader(http.StatusMultiStatus)
+		}
+	}
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		s.log.Error("failed to encode ingest response", "error", err)
+	}
+}
+
+// decodeIngest reads and validates every event before any is emitted, so a
+// bad line rejects the whole batch.
+func decodeIngest(body io.Reader, batch bool, sources []string) ([]ingestEvent, error) {
+	if !batch {
+		var in ingestEvent
+		if err := json.NewDecoder(body).Decode(&in); err != nil {
+			return nil, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		if err := in.validate(sources); err != nil {
+			return nil, err
+		}
+		return []ingestEvent{in}, nil
+	}
+
+	var events []ingestEvent
+	sc := bufio.NewScanner(body)
+	sc.Buffer(nil, maxIngestBody)
+	for line := 1; sc.Scan(); line++ {
+		b := bytes.TrimSpace(sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		if len(events) == maxIngestBatch {
+			return nil, fmt.Errorf("batch exceeds %d events", maxIngestBatch)
+		}
+		var in ingestEvent
+		if err := json.Unmarshal(b, &in); err != nil {
+			return nil, fmt.Errorf("line %d: invalid JSON: %w", line, err)
+		}
+		if err := in.validate(sources); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		events = append(events, in)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("reading batch: %w", err)
+	}
+	if len(events) == 0 {
+		return nil, errors.New("empty batch")
+	}
+	return events, nil
+}
+
+func (in *ingestEvent) validate(sources []string) error {
+	if in.Source == "" || in.Type == "" {
+		return errors.New("source and type are required")
+	}
+	if !slices.Contains(sources, in.Source) {
+		return fmt.Errorf("source %q is not in ingest.sources", in.Source)
+	}
+	if in.Payload == nil {
+		in.Payload = map[string]any{}
+	}
+	return nil
+}
+
+// ingest emits one event unless its idempotency key already maps to an
+// event from the last idempotencyTTL.
+func (s *Server) ingest(ctx context.Context, in ingestEvent) (ingestResult, error) {
+	event := plugin.Event{
+		ID:        uuid.NewString(),
+		Source:    in.Source,
+		Type:      in.Type,
+		Payload:   in.Payload,
+		Timestamp: time.Now(),
+	}
+	if in.IdempotencyKey != "" {
+		now := time.Now().UTC()
+		// Claim the key, or take over one that has expired.
+		res, err := s.store.Exec(ctx,
+			`INSERT INTO event_idempotency (key, event_id, created_at) VALUES (?, ?, ?)
+			 ON CONFLICT(key) DO UPDATE SET event_id = excluded.event_id, created_at = excluded.created_at
+			 WHERE event_idempotency.created_at < ?`,
+			in.IdempotencyKey, event.ID, now, now.Add(-idempotencyTTL),
+		)
+		if err != nil {
+			return ingestResult{}, fmt.Errorf("claim idempotency key: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			var id string
+			if err := s.store.DB().QueryRowContext(ctx, `SELECT event_id FROM event_idempotency WHERE key = ?`, in.IdempotencyKey).Scan(&id); err != nil {
+				return ingestResult{}, fmt.Errorf("look up idempotency key: %w", err)
+			}
+			return ingestResult{EventID: id, Duplicate: true}, nil
+		}
+	}
+	s.bus.Emit(event)
+	s.log.Info("event ingested", "event_id", event.ID, "source", event.Source, "type", event.Type)
+	return ingestResult{EventID: event.ID}, nil
+}
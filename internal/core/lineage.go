@@ -0,0 +1,140 @@
+package core
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/boozedog/smoothbrain/internal/plugin"
+	"github.com/boozedog/smoothbrain/internal/store"
+)
+
+// eventNode is one event in a lineage tree, with the runs it triggered and
+// the events it caused.
+type eventNode struct {
+	ID        string        `json:"id"`
+	Source    string        `json:"source"`
+	Type      string        `json:"type"`
+	Timestamp string        `json:"timestamp"`
+	Route     string        `json:"route,omitempty"`
+	ParentID  string        `json:"parent_id,omitempty"`
+	Runs      []pipelineRun `json:"runs"`
+	Children  []*eventNode  `json:"children,omitempty"`
+}
+
+// queryLineage returns the root of the tree containing eventID: every event
+// sharing its correlation ID, nested by parent. It returns nil if the event
+// does not exist.
+func queryLineage(s *store.Store, log *slog.Logger, eventID string) *eventNode {
+	var corr string
+	err := s.DB().QueryRow(`SELECT COALESCE(correlation_id, id) FROM events WHERE id = ?`, eventID).Scan(&corr)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error("query event lineage failed", "error", err)
+		}
+		return nil
+	}
+
+	rows, err := s.DB().Query(
+		`SELECT id, source, type, timestamp, COALESCE(route, ''), COALESCE(parent_id, '')
+		 FROM events WHERE correlation_id = ? OR id = ? ORDER BY created_at, rowid`,
+		corr, corr,
+	)
+	if err != nil {
+		log.Error("query event lineage failed", "error", err)
+		return nil
+	}
+	defer func() { _ = rows.Close() }()
+
+	var nodes []*eventNode
+	byID := make(map[string]*eventNode)
+	for rows.Next() {
+		n := &eventNode{}
+		if err := rows.Scan(&n.ID, &n.Source, &n.Type, &n.Timestamp, &n.Route, &n.ParentID); err != nil {
+			continue
+		}
+		nodes = append(nodes, n)
+		byID[n.ID] = n
+	}
+	if err := rows.Err(); err != nil {
+		log.Error("rows iteration error", "error", err)
+	}
+
+	var root *eventNode
+	for _, n := range nodes {
+		n.Runs = queryPipelineRuns(s, log, n.ID)
+		if parent, ok := byID[n.ParentID]; ok && n.ParentID != n.ID {
+			parent.Children = append(parent.Children, n)
+			continue
+		}
+		// Orphans (parent not recorded) hang off the root.
+		if root == nil {
+			root = n
+		} else {
+			root.Children = append(root.Children, n)
+		}
+	}
+	return root
+}
+
+func (s *Server) handleEventTree(w http.ResponseWriter, r *http.Request) {
+	root := queryLineage(s.store, s.log, r.PathValue("id"))
+	if root == nil {
+		http.Error(w, "event not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(root); err != nil {
+		s.log.Error("failed to encode event tree response", "error", err)
+	}
+}
+
+func (s *Server) handleEventTreeHTML(w http.ResponseWriter, r *http.Request) {
+	root := queryLineage(s.store, s.log, r.PathValue("id"))
+	if root == nil {
+		http.Error(w, "event not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	if err := EventTree(root, r.PathValue("id")).Render(r.Context(), w); err != nil {
+		s.log.Error("render event tree", "error", err)
+	}
+}
+
+// handleReplay re-emits a stored event as a child of the original.
+func (s *Server) handleReplay(w http.ResponseWriter, r *http.Request) {
+	if s.bus == nil {
+		http.Error(w, "replay unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	orig := plugin.Event{ID: r.PathValue("id")}
+	var payload string
+	err := s.store.DB().QueryRow(
+		`SELECT source, type, payload, COALESCE(correlation_id, '') FROM events WHERE id = ?`, orig.ID,
+	).Scan(&orig.Source, &orig.Type, &payload, &orig.CorrelationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "event not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		s.log.Error("replay: load event", "error", err)
+		http.Error(w, "replay failed", http.StatusInternalServerError)
+		return
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal([]byte(payload), &data); err != nil || data == nil {
+		data = map[string]any{}
+	}
+	replay := orig.Derive(orig.Source, orig.Type, data)
+	s.bus.Emit(replay)
+	s.log.Info("event replayed", "event_id", replay.ID, "parent_id", orig.ID)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": "accepted", "event_id": replay.ID}); err != nil {
+		s.log.Error("failed to encode replay response", "error", err)
+	}
+}
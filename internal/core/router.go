@@ -3,9 +3,11 @@ package core
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"maps"
+	"sync"
 	"time"
 
 	"github.com/boozedog/smoothbrain/internal/config"
@@ -18,21 +20,105 @@ type Router struct {
 	registry *plugin.Registry
 	store    *store.Store
 	log      *slog.Logger
-	notifyFn func()
+	bus      plugin.EventBus
+	runSubs  []func(RunUpdate)
+
+	// ctx is the parent of every run's context; cancel interrupts runs
+	// still going when Shutdown's drain timeout expires.
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup // running pipelines
+
+	mu       sync.Mutex
+	closed   bool                     // set by Shutdown; no new runs start
+	inflight map[string]*inflightRuns // event ID -> pipelines still running
+}
+
+type inflightRuns struct {
+	n    int
+	done chan struct{}
 }
 
 func NewRouter(routes []config.RouteConfig, registry *plugin.Registry, s *store.Store, log *slog.Logger) *Router {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &Router{
 		routes:   routes,
 		registry: registry,
 		store:    s,
 		log:      log,
+		ctx:      ctx,
+		cancel:   cancel,
+		inflight: make(map[string]*inflightRuns),
+	}
+}
+
+// SetBus sets the bus used to emit chained route events.
+func (r *Router) SetBus(bus plugin.EventBus) {
+	r.bus = bus
+}
+
+// SubscribeRuns registers fn to be called as runs start, finish each step
+// and complete. It must be called before events are routed.
+func (r *Router) SubscribeRuns(fn func(RunUpdate)) {
+	r.runSubs = append(r.runSubs, fn)
+}
+
+// RunUpdate reports progress of a pipeline run: once when it starts, after
+// each step, and when it finishes with its outcome.
+type RunUpdate struct {
+	RunID     int64          `json:"run_id"`
+	EventID   string         `json:"event_id"`
+	Source    string         `json:"source"` // the triggering event's source and type
+	Type      string         `json:"type"`
+	Route     string         `json:"route"`
+	Status    string         `json:"status"` // running, completed, failed or interrupted
+	Step      *stepResult    `json:"step,omitempty"`
+	StepIndex int            `json:"step_index,omitempty"` // 1-based; 0 when Step is nil
+	Steps     int            `json:"steps"`                // pipeline steps, plus one for a sink
+	Error     string         `json:"error,omitempty"`
+	Output    map[string]any `json:"output,omitempty"`
+}
+
+// activeRun identifies a run being executed, for recording and reporting it.
+type activeRun struct {
+	id        int64
+	event     plugin.Event
+	route     config.RouteConfig
+	startedAt time.Time
+	steps     []stepResult
+}
+
+func (r *Router) publish(run *activeRun, status string, step *stepResult, errMsg string, output map[string]any) {
+	if len(r.runSubs) == 0 {
+		return
+	}
+	u := RunUpdate{
+		RunID:   run.id,
+		EventID: run.event.ID,
+		Source:  run.event.Source,
+		Type:    run.event.Type,
+		Route:   run.route.Name,
+		Status:  status,
+		Step:    step,
+		Steps:   len(run.route.Pipeline),
+		Error:   errMsg,
+		Output:  output,
+	}
+	if run.route.Sink.Plugin != "" {
+		u.Steps++
+	}
+	if step != nil {
+		u.StepIndex = len(run.steps)
+	}
+	for _, fn := range r.runSubs {
+		fn(u)
 	}
 }
 
-// SetNotifyFn sets the callback invoked after each pipeline run completes.
-func (r *Router) SetNotifyFn(fn func()) {
-	r.notifyFn = fn
+// addStep records a finished step and reports it.
+func (r *Router) addStep(run *activeRun, step stepResult) {
+	run.steps = append(run.steps, step)
+	r.publish(run, "running", &step, "", nil)
 }
 
 type stepResult struct {
@@ -44,6 +130,12 @@ type stepResult struct {
 }
 
 func (r *Router) HandleEvent(event plugin.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		r.log.Warn("router shutting down, event not routed", "event_id", event.ID, "source", event.Source)
+		return
+	}
 	for _, route := range r.routes {
 		if route.Source != event.Source {
 			continue
@@ -51,8 +143,102 @@ func (r *Router) HandleEvent(event plugin.Event) {
 		if route.Event != "" && route.Event != event.Type {
 			continue
 		}
-		go r.executeRoute(route, event)
+		r.begin(event.ID)
+		go func() {
+			defer r.end(event.ID)
+			r.executeRoute(route, event)
+		}()
+	}
+}
+
+// begin records a run starting for eventID; r.mu must be held.
+func (r *Router) begin(eventID string) {
+	r.wg.Add(1)
+	f := r.inflight[eventID]
+	if f == nil {
+		f = &inflightRuns{done: make(chan struct{})}
+		r.inflight[eventID] = f
+	}
+	f.n++
+}
+
+func (r *Router) end(eventID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f := r.inflight[eventID]
+	f.n--
+	if f.n == 0 {
+		close(f.done)
+		delete(r.inflight, eventID)
+	}
+	r.wg.Done()
+}
+
+// Shutdown stops routing new events and waits for running pipelines to
+// finish. If ctx is done first, the remaining runs are cancelled and
+// recorded as interrupted, and Shutdown returns ctx's error once they have
+// stopped.
+func (r *Router) Shutdown(ctx context.Context) error {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+	r.log.Warn("drain timeout reached, interrupting pipelines")
+	r.cancel()
+	<-done
+	return ctx.Err()
+}
+
+// Wait blocks until every pipeline started by HandleEvent for eventID has
+// finished, or ctx is done. It returns immediately if none are running.
+func (r *Router) Wait(ctx context.Context, eventID string) error {
+	r.mu.Lock()
+	f := r.inflight[eventID]
+	r.mu.Unlock()
+	if f == nil {
+		return nil
+	}
+	select {
+	case <-f.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Route returns the configured route with the given name.
+func (r *Router) Route(name string) (config.RouteConfig, bool) {
+	for _, route := range r.routes {
+		if route.Name == name {
+			return route, true
+		}
+	}
+	return config.RouteConfig{}, false
+}
+
+// Run executes route for event synchronously, whether or not the route's
+// source and event type match it. It does nothing once Shutdown has begun.
+func (r *Router) Run(route config.RouteConfig, event plugin.Event) {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		r.log.Warn("router shutting down, route not run", "route", route.Name, "event_id", event.ID)
+		return
 	}
+	r.begin(event.ID)
+	r.mu.Unlock()
+	defer r.end(event.ID)
+	r.executeRoute(route, event)
 }
 
 func (r *Router) executeRoute(route config.RouteConfig, event plugin.Event) {
@@ -62,15 +248,16 @@ func (r *Router) executeRoute(route config.RouteConfig, event plugin.Event) {
 			timeout = d
 		}
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(r.ctx, timeout)
 	defer cancel()
+	ctx = plugin.WithCause(ctx, event)
 
 	r.log.Info("route matched", "route", route.Name, "event_id", event.ID)
 
 	startedAt := time.Now().UTC()
 
 	// Insert a running pipeline_runs row.
-	res, err := r.store.DB().Exec(
+	res, err := r.store.Exec(context.Background(),
 		`INSERT INTO pipeline_runs (event_id, route, status, started_at) VALUES (?, ?, 'running', ?)`,
 		event.ID, route.Name, startedAt,
 	)
@@ -83,21 +270,21 @@ func (r *Router) executeRoute(route config.RouteConfig, event plugin.Event) {
 		r.log.Error("failed to get pipeline run ID", "error", err)
 		return
 	}
+	run := &activeRun{id: runID, event: event, route: route, startedAt: startedAt}
+	r.publish(run, "running", nil, "", nil)
 
 	// Deep-copy payload to avoid data races when multiple routes match the same event.
 	current := event
 	current.Payload = make(map[string]any, len(event.Payload))
 	maps.Copy(current.Payload, event.Payload)
 
-	var steps []stepResult
-
 	for _, step := range route.Pipeline {
 		stepStart := time.Now()
 		t, ok := r.registry.GetTransform(step.Plugin)
 		if !ok {
 			errMsg := "transform plugin not found"
 			r.log.Error(errMsg, "plugin", step.Plugin, "route", route.Name)
-			steps = append(steps, stepResult{
+			r.addStep(run, stepResult{
 				Plugin:     step.Plugin,
 				Action:     step.Action,
 				Status:     "failed",
@@ -105,16 +292,21 @@ func (r *Router) executeRoute(route config.RouteConfig, event plugin.Event) {
 				Error:      errMsg,
 			})
 			r.deliverError(ctx, route, current, errMsg)
-			r.finishRun(runID, startedAt, "failed", errMsg, steps)
+			r.finishRun(run, "failed", errMsg, nil)
 			return
 		}
 
-		current, err = t.Transform(ctx, current, step.Action, step.Params)
+		params, err := r.registry.StepParams(step.Plugin, step.Action, step.Params)
+		if err != nil {
+			err = fmt.Errorf("invalid params: %w", err)
+		} else {
+			current, err = t.Transform(ctx, current, step.Action, params)
+		}
 		elapsed := time.Since(stepStart).Milliseconds()
 
 		if err != nil {
 			r.log.Error("transform failed", "plugin", step.Plugin, "route", route.Name, "error", err)
-			steps = append(steps, stepResult{
+			r.addStep(run, stepResult{
 				Plugin:     step.Plugin,
 				Action:     step.Action,
 				Status:     "failed",
@@ -122,11 +314,11 @@ func (r *Router) executeRoute(route config.RouteConfig, event plugin.Event) {
 				Error:      err.Error(),
 			})
 			r.deliverError(ctx, route, current, err.Error())
-			r.finishRun(runID, startedAt, "failed", err.Error(), steps)
+			r.finishRun(run, "failed", err.Error(), nil)
 			return
 		}
 
-		steps = append(steps, stepResult{
+		r.addStep(run, stepResult{
 			Plugin:     step.Plugin,
 			Action:     step.Action,
 			Status:     "completed",
@@ -134,61 +326,80 @@ func (r *Router) executeRoute(route config.RouteConfig, event plugin.Event) {
 		})
 	}
 
-	// Sink delivery step.
-	sinkStart := time.Now()
-	sink, ok := r.registry.GetSink(route.Sink.Plugin)
-	if !ok {
-		errMsg := "sink plugin not found"
-		r.log.Error(errMsg, "plugin", route.Sink.Plugin, "route", route.Name)
-		steps = append(steps, stepResult{
-			Plugin:     route.Sink.Plugin,
-			Action:     "sink",
-			Status:     "failed",
-			DurationMs: time.Since(sinkStart).Milliseconds(),
-			Error:      errMsg,
-		})
-		r.finishRun(runID, startedAt, "failed", errMsg, steps)
-		return
-	}
+	// Snapshot the pipeline output before sink params are merged in.
+	chained := maps.Clone(current.Payload)
 
-	for k, v := range route.Sink.Params {
-		if _, exists := current.Payload[k]; exists {
-			r.log.Debug("sink param overwrites payload key", "key", k, "route", route.Name)
+	if route.Sink.Plugin != "" {
+		step, err := r.deliver(ctx, route, current)
+		r.addStep(run, step)
+		if err != nil {
+			r.finishRun(run, "failed", err.Error(), nil)
+			return
 		}
-		current.Payload[k] = v
 	}
 
-	if err := sink.HandleEvent(ctx, current); err != nil {
-		r.log.Error("sink delivery failed", "plugin", route.Sink.Plugin, "route", route.Name, "error", err)
-		steps = append(steps, stepResult{
-			Plugin:     route.Sink.Plugin,
-			Action:     "sink",
-			Status:     "failed",
-			DurationMs: time.Since(sinkStart).Milliseconds(),
-			Error:      err.Error(),
+	if route.Emit != "" && r.bus != nil {
+		if depth := r.chainDepth(event); depth >= maxChainDepth {
+			errMsg := fmt.Sprintf("chain depth limit reached (%d route hops)", depth)
+			r.log.Error("route not emitting chained event", "route", route.Name, "event_id", event.ID, "error", errMsg)
+			r.addStep(run, stepResult{Plugin: "route", Action: "emit " + route.Emit, Status: "failed", Error: errMsg})
+			r.finishRun(run, "failed", errMsg, nil)
+			return
+		}
+		chained["route"] = route.Name
+		next := event.Derive("route", route.Emit, chained)
+		r.bus.Emit(next)
+		run.steps = append(run.steps, stepResult{
+			Plugin: "route",
+			Action: "emit " + route.Emit,
+			Status: "completed",
 		})
-		r.finishRun(runID, startedAt, "failed", err.Error(), steps)
-		return
+		r.log.Info("route emitted chained event", "route", route.Name, "event_id", next.ID, "parent_id", event.ID)
 	}
 
-	steps = append(steps, stepResult{
-		Plugin:     route.Sink.Plugin,
-		Action:     "sink",
-		Status:     "completed",
-		DurationMs: time.Since(sinkStart).Milliseconds(),
-	})
-
 	// Update the event row with the route name (bus already inserted it).
-	if _, err := r.store.DB().Exec(`UPDATE events SET route = ? WHERE id = ?`, route.Name, event.ID); err != nil {
+	if _, err := r.store.Exec(context.Background(), `UPDATE events SET route = ? WHERE id = ?`, route.Name, event.ID); err != nil {
 		r.log.Error("failed to update event route", "error", err)
 	}
 
-	r.finishRun(runID, startedAt, "completed", "", steps)
+	r.finishRun(run, "completed", "", chained)
 	r.log.Info("route completed", "route", route.Name, "event_id", event.ID)
 }
 
-// deliverError attempts to send an error message through the route's sink.
+// maxChainDepth caps how many chained route events may follow one another,
+// in case routes re-trigger each other despite config validation.
+const maxChainDepth = 10
+
+// chainDepth counts the route-emitted events in event's ancestry, starting
+// with event itself and stopping at the first that was not emitted by a route.
+func (r *Router) chainDepth(event plugin.Event) int {
+	if event.Source != "route" {
+		return 0
+	}
+	var depth int
+	err := r.store.DB().QueryRow(
+		`WITH RECURSIVE chain(parent_id, depth) AS (
+			SELECT parent_id, 1 FROM events WHERE id = ?
+			UNION ALL
+			SELECT e.parent_id, chain.depth + 1 FROM events e JOIN chain ON e.id = chain.parent_id
+			WHERE e.source = 'route' AND chain.depth < ?
+		)
+		SELECT COALESCE(MAX(depth), 1) FROM chain`,
+		event.ID, maxChainDepth,
+	).Scan(&depth)
+	if err != nil {
+		r.log.Error("failed to count chain depth", "event_id", event.ID, "error", err)
+		return 1
+	}
+	return depth
+}
+
+// deliverError attempts to send an error message through the route's sink,
+// unless the run was interrupted by shutdown.
 func (r *Router) deliverError(ctx context.Context, route config.RouteConfig, event plugin.Event, errMsg string) {
+	if r.ctx.Err() != nil {
+		return
+	}
 	sink, ok := r.registry.GetSink(route.Sink.Plugin)
 	if !ok {
 		return
@@ -200,21 +411,66 @@ func (r *Router) deliverError(ctx context.Context, route config.RouteConfig, eve
 	}
 }
 
-func (r *Router) finishRun(runID int64, startedAt time.Time, status, errMsg string, steps []stepResult) {
+// finishRun records the run's outcome and, for a completed run, its
+// pipeline output. A run that failed after Shutdown cancelled it is recorded
+// as interrupted.
+func (r *Router) finishRun(run *activeRun, status, errMsg string, output map[string]any) {
+	if status == "failed" && r.ctx.Err() != nil {
+		status = "interrupted"
+	}
 	finishedAt := time.Now().UTC()
-	durationMs := time.Since(startedAt).Milliseconds()
+	durationMs := time.Since(run.startedAt).Milliseconds()
 
-	stepsJSON, _ := json.Marshal(steps)
+	stepsJSON, _ := json.Marshal(run.steps)
+	var outputJSON any
+	if output != nil {
+		if b, err := json.Marshal(output); err == nil {
+			outputJSON = string(b)
+		} else {
+			r.log.Error("failed to marshal run output", "error", err)
+		}
+	}
 
-	_, err := r.store.DB().Exec(
-		`UPDATE pipeline_runs SET status = ?, finished_at = ?, duration_ms = ?, error = ?, steps = ? WHERE id = ?`,
-		status, finishedAt, durationMs, errMsg, string(stepsJSON), runID,
+	_, err := r.store.Exec(context.Background(),
+		`UPDATE pipeline_runs SET status = ?, finished_at = ?, duration_ms = ?, error = ?, steps = ?, output = ? WHERE id = ?`,
+		status, finishedAt, durationMs, errMsg, string(stepsJSON), outputJSON, run.id,
 	)
 	if err != nil {
 		r.log.Error("failed to update pipeline run", "error", err)
 	}
+	r.publish(run, status, nil, errMsg, output)
+}
+
+// deliver runs the route's sink on the pipeline output.
+func (r *Router) deliver(ctx context.Context, route config.RouteConfig, current plugin.Event) (stepResult, error) {
+	sinkStart := time.Now()
+	step := stepResult{Plugin: route.Sink.Plugin, Action: "sink"}
+
+	sink, ok := r.registry.GetSink(route.Sink.Plugin)
+	if !ok {
+		errMsg := "sink plugin not found"
+		r.log.Error(errMsg, "plugin", route.Sink.Plugin, "route", route.Name)
+		step.Status = "failed"
+		step.DurationMs = time.Since(sinkStart).Milliseconds()
+		step.Error = errMsg
+		return step, errors.New(errMsg)
+	}
+
+	for k, v := range route.Sink.Params {
+		if _, exists := current.Payload[k]; exists {
+			r.log.Debug("sink param overwrites payload key", "key", k, "route", route.Name)
+		}
+		current.Payload[k] = v
+	}
 
-	if r.notifyFn != nil {
-		r.notifyFn()
+	err := sink.HandleEvent(ctx, current)
+	step.DurationMs = time.Since(sinkStart).Milliseconds()
+	if err != nil {
+		r.log.Error("sink delivery failed", "plugin", route.Sink.Plugin, "route", route.Name, "error", err)
+		step.Status = "failed"
+		step.Error = err.Error()
+		return step, err
 	}
+	step.Status = "completed"
+	return step, nil
 }
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"reflect"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -21,17 +23,33 @@ type stubTransform struct {
 	name   string
 	called int
 	err    error
-	mu     sync.Mutex
+	panic  string // panics with this message when set
+	params map[string]any
+	// release, when set, blocks Transform until it is closed or the run's
+	// context is done.
+	release chan struct{}
+	mu      sync.Mutex
 }
 
 func (s *stubTransform) Name() string                                 { return s.name }
 func (s *stubTransform) Init(json.RawMessage) error                   { return nil }
 func (s *stubTransform) Start(context.Context, plugin.EventBus) error { return nil }
 func (s *stubTransform) Stop() error                                  { return nil }
-func (s *stubTransform) Transform(_ context.Context, e plugin.Event, _ string, _ map[string]any) (plugin.Event, error) {
+func (s *stubTransform) Transform(ctx context.Context, e plugin.Event, _ string, params map[string]any) (plugin.Event, error) {
+	if s.release != nil {
+		select {
+		case <-s.release:
+		case <-ctx.Done():
+			return e, ctx.Err()
+		}
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.called++
+	s.params = params
+	if s.panic != "" {
+		panic(s.panic)
+	}
 	if s.err != nil {
 		return e, s.err
 	}
@@ -65,7 +83,7 @@ func newTestRouter(t *testing.T, routes []config.RouteConfig, transforms map[str
 		t.Fatal(err)
 	}
 	log := slog.New(slog.NewTextHandler(io.Discard, nil))
-	reg := plugin.NewRegistry(log, st.DB())
+	reg := plugin.NewRegistry(log, st)
 	for _, tr := range transforms {
 		reg.Register(tr)
 	}
@@ -79,10 +97,15 @@ func newTestRouter(t *testing.T, routes []config.RouteConfig, transforms map[str
 	return r, func() { _ = st.Close() }
 }
 
-// waitRoute sets up a notify channel and returns a wait function.
+// waitRoute subscribes to r's runs and returns a function that waits for
+// one to finish.
 func waitRoute(r *Router) func() {
 	done := make(chan struct{}, 1)
-	r.SetNotifyFn(func() { done <- struct{}{} })
+	r.SubscribeRuns(func(u RunUpdate) {
+		if u.Status != "running" {
+			done <- struct{}{}
+		}
+	})
 	return func() {
 		select {
 		case <-done:
@@ -267,6 +290,34 @@ func TestRouter_TransformError(t *testing.T) {
 	}
 }
 
+func TestRouter_TransformPanicFailsStep(t *testing.T) {
+	tr := &stubTransform{name: "bad", panic: "nil map"}
+	sink := &stubSink{name: "out"}
+	routes := []config.RouteConfig{{
+		Name:     "panic-route",
+		Source:   "src",
+		Pipeline: []config.StepConfig{{Plugin: "bad", Action: "do"}},
+		Sink:     config.SinkConfig{Plugin: "out"},
+	}}
+	r, cleanup := newTestRouter(t, routes,
+		map[string]*stubTransform{"bad": tr},
+		map[string]*stubSink{"out": sink},
+	)
+	defer cleanup()
+
+	wait := waitRoute(r)
+	r.HandleEvent(makeEvent("src", "any"))
+	wait()
+
+	var status, steps string
+	if err := r.store.DB().QueryRow(`SELECT status, steps FROM pipeline_runs WHERE route = 'panic-route'`).Scan(&status, &steps); err != nil {
+		t.Fatal(err)
+	}
+	if status != "failed" || !strings.Contains(steps, `"status":"failed"`) || !strings.Contains(steps, "panicked in Transform: nil map") {
+		t.Errorf("run = %s %s, want a failed step with the panic", status, steps)
+	}
+}
+
 func TestRouter_SinkNotFound(t *testing.T) {
 	routes := []config.RouteConfig{{
 		Name:   "no-sink",
@@ -306,29 +357,6 @@ func TestRouter_TransformNotFound(t *testing.T) {
 	}
 }
 
-func TestRouter_NotifyFnCalled(t *testing.T) {
-	sink := &stubSink{name: "out"}
-	routes := []config.RouteConfig{{
-		Name:   "notify",
-		Source: "src",
-		Sink:   config.SinkConfig{Plugin: "out"},
-	}}
-	r, cleanup := newTestRouter(t, routes, nil, map[string]*stubSink{"out": sink})
-	defer cleanup()
-
-	called := make(chan struct{}, 1)
-	r.SetNotifyFn(func() { called <- struct{}{} })
-
-	r.HandleEvent(makeEvent("src", "any"))
-
-	select {
-	case <-called:
-		// success
-	case <-time.After(5 * time.Second):
-		t.Fatal("notifyFn was not called")
-	}
-}
-
 func TestRouter_SinkParams(t *testing.T) {
 	sink := &stubSink{name: "out"}
 	routes := []config.RouteConfig{{
@@ -359,3 +387,280 @@ func TestRouter_SinkParams(t *testing.T) {
 		t.Errorf("expected mention=true, got %v", payload["mention"])
 	}
 }
+
+func TestRouter_PassesCauseToSink(t *testing.T) {
+	sink := &causeSink{stubSink: stubSink{name: "out"}}
+	routes := []config.RouteConfig{{Name: "r1", Source: "src", Sink: config.SinkConfig{Plugin: "out"}}}
+	st, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	reg := plugin.NewRegistry(log, st)
+	reg.Register(sink)
+	r := NewRouter(routes, reg, st, log)
+	wait := waitRoute(r)
+
+	r.HandleEvent(makeEvent("src", "any"))
+	wait()
+
+	if sink.cause.ID != "evt-001" {
+		t.Errorf("cause in sink context = %q, want evt-001", sink.cause.ID)
+	}
+}
+
+type causeSink struct {
+	stubSink
+	cause plugin.Event
+}
+
+func (s *causeSink) HandleEvent(ctx context.Context, e plugin.Event) error {
+	s.cause, _ = plugin.CauseFromContext(ctx)
+	return s.stubSink.HandleEvent(ctx, e)
+}
+
+func TestRouter_EmitChainsRoutes(t *testing.T) {
+	sink := &stubSink{name: "out"}
+	routes := []config.RouteConfig{
+		{
+			Name:     "first",
+			Source:   "src",
+			Pipeline: []config.StepConfig{{Plugin: "t1", Action: "do"}},
+			Emit:     "stage.done",
+		},
+		{
+			Name:   "second",
+			Source: "route",
+			Event:  "stage.done",
+			Sink:   config.SinkConfig{Plugin: "out"},
+		},
+	}
+	st, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	reg := plugin.NewRegistry(log, st)
+	reg.Register(&stubTransform{name: "t1"})
+	reg.Register(sink)
+	r := NewRouter(routes, reg, st, log)
+	bus := NewBus(st, log)
+	bus.Subscribe(r.HandleEvent)
+	r.SetBus(bus)
+
+	wait := waitRoute(r)
+	bus.Emit(makeEvent("src", "any"))
+	wait()
+	wait()
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.events) != 1 {
+		t.Fatalf("sink received %d events, want 1", len(sink.events))
+	}
+	got := sink.events[0]
+	if got.Source != "route" || got.Type != "stage.done" {
+		t.Errorf("chained event = %s/%s, want route/stage.done", got.Source, got.Type)
+	}
+	if got.ParentID != "evt-001" || got.CorrelationID != "evt-001" {
+		t.Errorf("chained lineage = (%q, %q), want (evt-001, evt-001)", got.ParentID, got.CorrelationID)
+	}
+	if got.Payload["transformed_by_t1"] != true || got.Payload["route"] != "first" {
+		t.Errorf("chained payload = %v, want pipeline output tagged with route", got.Payload)
+	}
+}
+
+func TestRouter_EmitStopsAtChainDepth(t *testing.T) {
+	// A self-triggering route that config validation would reject.
+	routes := []config.RouteConfig{
+		{Name: "start", Source: "src", Emit: "loop"},
+		{Name: "loop", Source: "route", Event: "loop", Emit: "loop"},
+	}
+	st, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	r := NewRouter(routes, plugin.NewRegistry(log, st), st, log)
+	bus := NewBus(st, log)
+	bus.Subscribe(r.HandleEvent)
+	r.SetBus(bus)
+
+	failed := make(chan RunUpdate, 1)
+	r.SubscribeRuns(func(u RunUpdate) {
+		if u.Status == "failed" {
+			failed <- u
+		}
+	})
+	bus.Emit(makeEvent("src", "any"))
+
+	select {
+	case u := <-failed:
+		if !strings.Contains(u.Error, "chain depth") {
+			t.Errorf("failed run error = %q, want chain depth limit", u.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("chained loop was not stopped")
+	}
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := st.DB().QueryRow(`SELECT COUNT(*) FROM events WHERE source = 'route'`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != maxChainDepth {
+		t.Errorf("chained events = %d, want %d", n, maxChainDepth)
+	}
+}
+
+func TestRouter_CoercesParams(t *testing.T) {
+	st, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = st.Close() }()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	reg := plugin.NewRegistry(log, st)
+	tr := &actionTransform{stubTransform{name: "llm"}}
+	reg.Register(tr)
+	routes := []config.RouteConfig{
+		{Name: "good", Source: "src", Event: "good", Pipeline: []config.StepConfig{{Plugin: "llm", Action: "summarize", Params: map[string]any{"max_words": 50.0}}}},
+		{Name: "bad", Source: "src", Event: "bad", Pipeline: []config.StepConfig{{Plugin: "llm", Action: "summarize", Params: map[string]any{"max_words": "lots"}}}},
+	}
+	r := NewRouter(routes, reg, st, log)
+
+	wait := waitRoute(r)
+	r.HandleEvent(makeEvent("src", "good"))
+	wait()
+	tr.mu.Lock()
+	if got, want := tr.params, map[string]any{"max_words": 50}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Transform params = %#v, want %#v", got, want)
+	}
+	tr.called = 0
+	tr.mu.Unlock()
+
+	r.HandleEvent(makeEvent("src", "bad"))
+	wait()
+	tr.mu.Lock()
+	defer tr.mu.Unlock()
+	if tr.called != 0 {
+		t.Error("Transform called with invalid params")
+	}
+	var status string
+	if err := st.DB().QueryRow(`SELECT status FROM pipeline_runs WHERE route = 'bad'`).Scan(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status != "failed" {
+		t.Errorf("status = %q, want failed", status)
+	}
+}
+
+func TestRouter_ShutdownDrainsRuns(t *testing.T) {
+	tr := &stubTransform{name: "slow", release: make(chan struct{})}
+	sink := &stubSink{name: "out"}
+	routes := []config.RouteConfig{{
+		Name:     "slow-route",
+		Source:   "src",
+		Pipeline: []config.StepConfig{{Plugin: "slow", Action: "do"}},
+		Sink:     config.SinkConfig{Plugin: "out"},
+	}}
+	r, cleanup := newTestRouter(t, routes, map[string]*stubTransform{"slow": tr}, map[string]*stubSink{"out": sink})
+	defer cleanup()
+
+	r.HandleEvent(makeEvent("src", "any"))
+	drained := make(chan error, 1)
+	go func() { drained <- r.Shutdown(context.Background()) }()
+
+	select {
+	case err := <-drained:
+		t.Fatalf("Shutdown returned %v before the run finished", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Events arriving while draining are not routed.
+	late := makeEvent("src", "any")
+	late.ID = "evt-late"
+	r.HandleEvent(late)
+	close(tr.release)
+	if err := <-drained; err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	var runs int
+	var status string
+	if err := r.store.DB().QueryRow(`SELECT COUNT(*), MAX(status) FROM pipeline_runs`).Scan(&runs, &status); err != nil {
+		t.Fatal(err)
+	}
+	if runs != 1 || status != "completed" {
+		t.Errorf("runs = %d, status = %q; want one completed run", runs, status)
+	}
+}
+
+func TestRouter_ShutdownInterruptsAfterTimeout(t *testing.T) {
+	tr := &stubTransform{name: "stuck", release: make(chan struct{})}
+	sink := &stubSink{name: "out"}
+	routes := []config.RouteConfig{{
+		Name:     "stuck-route",
+		Source:   "src",
+		Pipeline: []config.StepConfig{{Plugin: "stuck", Action: "do"}},
+		Sink:     config.SinkConfig{Plugin: "out"},
+	}}
+	r, cleanup := newTestRouter(t, routes, map[string]*stubTransform{"stuck": tr}, map[string]*stubSink{"out": sink})
+	defer cleanup()
+
+	r.HandleEvent(makeEvent("src", "any"))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := r.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown() error = %v, want deadline exceeded", err)
+	}
+
+	var status string
+	if err := r.store.DB().QueryRow(`SELECT status FROM pipeline_runs WHERE route = 'stuck-route'`).Scan(&status); err != nil {
+		t.Fatal(err)
+	}
+	if status != "interrupted" {
+		t.Errorf("status = %q, want interrupted", status)
+	}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.events) != 0 {
+		t.Errorf("sink got %d events, want no error delivered for an interrupted run", len(sink.events))
+	}
+}
+
+func TestRouter_SubscribeRuns(t *testing.T) {
+	sink := &stubSink{name: "out"}
+	routes := []config.RouteConfig{{
+		Name:     "progress",
+		Source:   "webhook",
+		Pipeline: []config.StepConfig{{Plugin: "a", Action: "x"}, {Plugin: "b", Action: "y"}},
+		Sink:     config.SinkConfig{Plugin: "out"},
+	}}
+	r, cleanup := newTestRouter(t, routes, map[string]*stubTransform{"a": {name: "a"}, "b": {name: "b"}}, map[string]*stubSink{"out": sink})
+	defer cleanup()
+
+	var updates []RunUpdate
+	r.SubscribeRuns(func(u RunUpdate) { updates = append(updates, u) })
+	r.Run(routes[0], makeEvent("webhook", "push"))
+
+	if len(updates) != 5 {
+		t.Fatalf("got %d updates, want start, 3 steps and finish: %+v", len(updates), updates)
+	}
+	if u := updates[0]; u.Status != "running" || u.Step != nil || u.Steps != 3 || u.Source != "webhook" {
+		t.Errorf("start update = %+v", u)
+	}
+	for i, want := range []string{"a", "b", "out"} {
+		u := updates[i+1]
+		if u.Step == nil || u.Step.Plugin != want || u.StepIndex != i+1 || u.Status != "running" {
+			t.Errorf("step update %d = %+v, want step %s", i+1, u, want)
+		}
+	}
+	if u := updates[4]; u.Status != "completed" || u.Output["transformed_by_b"] != true || u.RunID != updates[0].RunID {
+		t.Errorf("final update = %+v", u)
+	}
+}
@@ -1,11 +1,19 @@
 package core
 
 import (
+	"database/sql"
 	"embed"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/fs"
 	"log/slog"
 	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/boozedog/smoothbrain/internal/config"
@@ -25,6 +33,12 @@ type Server struct {
 	registry *plugin.Registry
 	routes   []config.RouteConfig
 	logBuf   *LogBuffer
+	bus      plugin.EventBus
+	router   *Router
+	sup      *Supervisor
+	beats    *Heartbeats
+	// ingestSources are the sources POST /api/events accepts.
+	ingestSources []string
 }
 
 func NewServer(s *store.Store, log *slog.Logger, hub *Hub, registry *plugin.Registry, routes []config.RouteConfig, logBuf *LogBuffer) *Server {
@@ -39,10 +53,28 @@ func NewServer(s *store.Store, log *slog.Logger, hub *Hub, registry *plugin.Regi
 	srv.mux.HandleFunc("GET /api/health", srv.handleHealth)
 	srv.mux.HandleFunc("GET /api/health/html", srv.handleHealthHTML)
 	srv.mux.HandleFunc("GET /api/events", srv.handleEvents)
+	srv.mux.HandleFunc("POST /api/events", srv.handleIngest)
 	srv.mux.HandleFunc("GET /api/events/html", srv.handleEventsHTML)
 	srv.mux.HandleFunc("GET /api/events/{id}/runs", srv.handleEventRuns)
+	srv.mux.HandleFunc("GET /api/events/{id}/tree", srv.handleEventTree)
+	srv.mux.HandleFunc("GET /api/events/{id}/tree/html", srv.handleEventTreeHTML)
+	srv.mux.HandleFunc("POST /api/events/{id}/replay", srv.handleReplay)
 	srv.mux.HandleFunc("GET /api/status/html", srv.handleStatusHTML)
+	srv.mux.HandleFunc("POST /api/plugins/{name}/restart", srv.handlePluginRestart)
+	srv.mux.HandleFunc("GET /api/plugins/{name}/panels/{panel}", srv.handlePluginPanel)
+	srv.mux.HandleFunc("GET /api/panels/html", srv.handlePanelsHTML)
 	srv.mux.HandleFunc("GET /api/log/html", srv.handleLogHTML)
+	srv.mux.HandleFunc("GET /api/supervisor", srv.handleSupervisor)
+	srv.mux.HandleFunc("GET /api/supervisor/html", srv.handleSupervisorHTML)
+	srv.mux.HandleFunc("GET /api/supervisor/{task}/runs", srv.handleTaskRuns)
+	srv.mux.HandleFunc("POST /api/supervisor/{task}/run", srv.handleTaskRun)
+	srv.mux.HandleFunc("POST /api/supervisor/{task}/pause", srv.handleTaskPause)
+	srv.mux.HandleFunc("POST /api/supervisor/{task}/resume", srv.handleTaskResume)
+	srv.mux.HandleFunc("GET /api/heartbeats", srv.handleHeartbeats)
+	srv.mux.HandleFunc("GET /api/heartbeats/html", srv.handleHeartbeatsHTML)
+	srv.mux.HandleFunc("GET /hooks/heartbeat/{name}", srv.handleHeartbeatPing)
+	srv.mux.HandleFunc("POST /hooks/heartbeat/{name}", srv.handleHeartbeatPing)
+	srv.mux.HandleFunc("GET /api/backup", srv.handleBackup)
 	srv.mux.Handle("GET /ws", hub)
 
 	// Serve embedded static files at root.
@@ -55,6 +87,37 @@ func NewServer(s *store.Store, log *slog.Logger, hub *Hub, registry *plugin.Regi
 	return srv
 }
 
+// SetBus sets the bus used to replay events.
+func (s *Server) SetBus(bus plugin.EventBus) {
+	s.bus = bus
+}
+
+// SetRouter sets the router whose runs POST /api/events?wait= waits for.
+func (s *Server) SetRouter(r *Router) {
+	s.router = r
+}
+
+// SetStream serves stream at GET /api/stream.
+func (s *Server) SetStream(st *Stream) {
+	s.mux.Handle("GET /api/stream", st)
+}
+
+// SetSupervisor sets the supervisor managed from the supervisor tab.
+func (s *Server) SetSupervisor(sup *Supervisor) {
+	s.sup = sup
+}
+
+// SetHeartbeats sets the heartbeat monitor pinged at /hooks/heartbeat/{name}.
+func (s *Server) SetHeartbeats(h *Heartbeats) {
+	s.beats = h
+}
+
+// SetIngestSources sets the sources POST /api/events accepts. Without any,
+// every pushed event is rejected.
+func (s *Server) SetIngestSources(sources []string) {
+	s.ingestSources = sources
+}
+
 // Handler returns the http.Handler for use with http.Server.
 func (s *Server) Handler() http.Handler {
 	return s.mux
@@ -122,12 +185,95 @@ func (s *Server) handleHealthHTML(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleStatusHTML(w http.ResponseWriter, r *http.Request) {
 	info := buildStatusInfo(r.Context(), s.registry, s.routes)
+	if s.sup != nil {
+		info.Tasks = toTaskStatuses(s.sup.Schedules(), time.Now())
+	}
 	w.Header().Set("Content-Type", "text/html")
 	if err := StatusTab(info).Render(r.Context(), w); err != nil {
 		s.log.Error("render status tab", "error", err)
 	}
 }
 
+// handlePluginRestart stops, re-initializes and starts one plugin. A plugin
+// that fails to start again keeps retrying; the error is returned so the
+// caller knows.
+func (s *Server) handlePluginRestart(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	err := s.registry.Restart(name)
+	w.Header().Set("HX-Trigger", "plugins-changed")
+	switch {
+	case errors.Is(err, plugin.ErrUnknownPlugin):
+		http.Error(w, "plugin not found", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": "restarted", "plugin": name}); err != nil {
+		s.log.Error("failed to encode plugin restart response", "error", err)
+	}
+}
+
+// reservedPluginPaths are served by the server under /api/plugins/{name}/.
+var reservedPluginPaths = []string{"restart", "panels"}
+
+// MountAPI serves a plugin's handler at /api/plugins/{name}/{path}.
+func (s *Server) MountAPI(name, method, path string, handler http.HandlerFunc) {
+	path = strings.Trim(path, "/")
+	first, _, _ := strings.Cut(path, "/")
+	switch {
+	case method != http.MethodGet && method != http.MethodPost:
+		s.log.Error("plugin API method must be GET or POST", "plugin", name, "method", method, "path", path)
+		return
+	case slices.Contains(reservedPluginPaths, first):
+		s.log.Error("plugin API path is reserved", "plugin", name, "path", path)
+		return
+	}
+	route := pluginURL(name, path)
+	if err := handleFunc(s.mux, method+" "+route, handler); err != nil {
+		s.log.Error("plugin API not registered", "plugin", name, "path", path, "error", err)
+		return
+	}
+	s.log.Info("plugin API registered", "method", method, "path", route)
+}
+
+// handleFunc registers handler for pattern, returning ServeMux's panic on an
+// invalid or conflicting pattern as an error.
+func handleFunc(mux *http.ServeMux, pattern string, handler http.HandlerFunc) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("%v", v)
+		}
+	}()
+	mux.HandleFunc(pattern, handler)
+	return nil
+}
+
+// handlePanelsHTML renders the Plugins tab: a card per contributed panel,
+// each loading its own content.
+func (s *Server) handlePanelsHTML(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html")
+	if err := PluginPanels(buildPanelViews(s.registry)).Render(r.Context(), w); err != nil {
+		s.log.Error("render plugin panels", "error", err)
+	}
+}
+
+func (s *Server) handlePluginPanel(w http.ResponseWriter, r *http.Request) {
+	name, panel := r.PathValue("name"), r.PathValue("panel")
+	for _, p := range s.registry.Panels(name) {
+		if p.Name != panel {
+			continue
+		}
+		w.Header().Set("Content-Type", "text/html")
+		if err := p.Render(r).Render(r.Context(), w); err != nil {
+			s.log.Error("render plugin panel", "plugin", name, "panel", panel, "error", err)
+		}
+		return
+	}
+	http.Error(w, "panel not found", http.StatusNotFound)
+}
+
 func (s *Server) handleLogHTML(w http.ResponseWriter, r *http.Request) {
 	entries := s.logBuf.Entries()
 	w.Header().Set("Content-Type", "text/html")
@@ -136,10 +282,186 @@ func (s *Server) handleLogHTML(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// taskHistoryLimit is how many recent runs the supervisor tab shows per task.
+const taskHistoryLimit = 10
+
+func (s *Server) handleSupervisor(w http.ResponseWriter, r *http.Request) {
+	schedules := []TaskSchedule{}
+	if s.sup != nil {
+		schedules = s.sup.Schedules()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(schedules); err != nil {
+		s.log.Error("failed to encode supervisor response", "error", err)
+	}
+}
+
+func (s *Server) handleSupervisorHTML(w http.ResponseWriter, r *http.Request) {
+	var tasks []taskStatus
+	if s.sup != nil {
+		tasks = toTaskStatuses(s.sup.Schedules(), time.Now())
+		for i := range tasks {
+			runs, err := s.sup.History(tasks[i].Name, taskHistoryLimit)
+			if err != nil {
+				s.log.Error("query supervisor history failed", "task", tasks[i].Name, "error", err)
+				continue
+			}
+			tasks[i].addTaskRuns(runs)
+		}
+	}
+	w.Header().Set("Content-Type", "text/html")
+	if err := SupervisorTab(tasks).Render(r.Context(), w); err != nil {
+		s.log.Error("render supervisor tab", "error", err)
+	}
+}
+
+func (s *Server) handleTaskRuns(w http.ResponseWriter, r *http.Request) {
+	if s.sup == nil {
+		http.Error(w, "supervisor unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	name := r.PathValue("task")
+	if _, ok := s.sup.task(name); !ok {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+	limit := taskHistoryLimit
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 {
+		limit = n
+	}
+	runs, err := s.sup.History(name, limit)
+	if err != nil {
+		s.log.Error("query supervisor history failed", "task", name, "error", err)
+		http.Error(w, "history unavailable", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(runs); err != nil {
+		s.log.Error("failed to encode task runs response", "error", err)
+	}
+}
+
+// handleTaskRun starts a supervisor task immediately.
+func (s *Server) handleTaskRun(w http.ResponseWriter, r *http.Request) {
+	s.taskAction(w, r, "started", (*Supervisor).RunNow)
+}
+
+func (s *Server) handleTaskPause(w http.ResponseWriter, r *http.Request) {
+	s.taskAction(w, r, "paused", (*Supervisor).Pause)
+}
+
+func (s *Server) handleTaskResume(w http.ResponseWriter, r *http.Request) {
+	s.taskAction(w, r, "resumed", (*Supervisor).Resume)
+}
+
+// taskAction applies fn to the task named in the path and reports status on
+// success. The HX-Trigger header lets the supervisor tab refresh itself.
+func (s *Server) taskAction(w http.ResponseWriter, r *http.Request, status string, fn func(*Supervisor, string) error) {
+	if s.sup == nil {
+		http.Error(w, "supervisor unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	name := r.PathValue("task")
+	switch err := fn(s.sup, name); {
+	case errors.Is(err, ErrUnknownTask):
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrTaskRunning):
+		http.Error(w, "task already running", http.StatusConflict)
+		return
+	case err != nil:
+		s.log.Error("supervisor task action failed", "task", name, "action", status, "error", err)
+		http.Error(w, "task action failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("HX-Trigger", "supervisor-changed")
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": status, "task": name}); err != nil {
+		s.log.Error("failed to encode task action response", "error", err)
+	}
+}
+
+func (s *Server) handleHeartbeats(w http.ResponseWriter, r *http.Request) {
+	beats := []HeartbeatStatus{}
+	if s.beats != nil {
+		beats = s.beats.Statuses()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(beats); err != nil {
+		s.log.Error("failed to encode heartbeats response", "error", err)
+	}
+}
+
+func (s *Server) handleHeartbeatsHTML(w http.ResponseWriter, r *http.Request) {
+	var beats []heartbeatStatus
+	if s.beats != nil {
+		beats = toHeartbeatStatuses(s.beats.Statuses(), time.Now())
+	}
+	w.Header().Set("Content-Type", "text/html")
+	if err := HeartbeatsPanel(beats).Render(r.Context(), w); err != nil {
+		s.log.Error("render heartbeats panel", "error", err)
+	}
+}
+
+// handleHeartbeatPing records a beat for a heartbeat. It is public, like
+// other /hooks/ endpoints, so cron jobs can ping it with a bare curl.
+func (s *Server) handleHeartbeatPing(w http.ResponseWriter, r *http.Request) {
+	if s.beats == nil {
+		http.Error(w, "heartbeat not found", http.StatusNotFound)
+		return
+	}
+	name := r.PathValue("name")
+	switch err := s.beats.Ping(name); {
+	case errors.Is(err, ErrUnknownHeartbeat):
+		http.Error(w, "heartbeat not found", http.StatusNotFound)
+		return
+	case err != nil:
+		s.log.Error("heartbeat ping failed", "heartbeat", name, "error", err)
+		http.Error(w, "ping failed", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"status": "ok", "heartbeat": name}); err != nil {
+		s.log.Error("failed to encode heartbeat ping response", "error", err)
+	}
+}
+
+// handleBackup streams a consistent snapshot of the database.
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	dir, err := os.MkdirTemp("", "smoothbrain-backup-")
+	if err != nil {
+		s.log.Error("backup: create temp dir", "error", err)
+		http.Error(w, "backup failed", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "snapshot.db")
+	if err := s.store.Snapshot(path); err != nil {
+		s.log.Error("backup: snapshot", "error", err)
+		http.Error(w, "backup failed", http.StatusInternalServerError)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		s.log.Error("backup: open snapshot", "error", err)
+		http.Error(w, "backup failed", http.StatusInternalServerError)
+		return
+	}
+	defer func() { _ = f.Close() }()
+
+	name := "smoothbrain-" + time.Now().UTC().Format("20060102T150405Z") + ".db"
+	w.Header().Set("Content-Type", "application/vnd.sqlite3")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	http.ServeContent(w, r, name, time.Now(), f)
+	s.log.Info("backup downloaded", "remote", r.RemoteAddr)
+}
+
+const eventColumns = `id, source, type, payload, timestamp, COALESCE(route, ''), COALESCE(parent_id, ''), COALESCE(correlation_id, id)`
+
 func queryEvents(s *store.Store, log *slog.Logger) []map[string]any {
-	rows, err := s.DB().Query(
-		`SELECT id, source, type, payload, timestamp, COALESCE(route, '') FROM events ORDER BY created_at DESC LIMIT 50`,
-	)
+	rows, err := s.DB().Query(`SELECT ` + eventColumns + ` FROM events ORDER BY created_at DESC LIMIT 50`)
 	if err != nil {
 		log.Error("query events failed", "error", err)
 		return []map[string]any{}
@@ -148,18 +470,11 @@ func queryEvents(s *store.Store, log *slog.Logger) []map[string]any {
 
 	var events []map[string]any
 	for rows.Next() {
-		var id, source, typ, payload, ts, route string
-		if err := rows.Scan(&id, &source, &typ, &payload, &ts, &route); err != nil {
+		e, err := scanEvent(rows)
+		if err != nil {
 			continue
 		}
-		events = append(events, map[string]any{
-			"id":        id,
-			"source":    source,
-			"type":      typ,
-			"payload":   json.RawMessage(payload),
-			"timestamp": ts,
-			"route":     route,
-		})
+		events = append(events, e)
 	}
 	if err := rows.Err(); err != nil {
 		log.Error("rows iteration error", "error", err)
@@ -170,6 +485,35 @@ func queryEvents(s *store.Store, log *slog.Logger) []map[string]any {
 	return events
 }
 
+// queryEvent returns one stored event in the shape queryEvents uses.
+func queryEvent(s *store.Store, log *slog.Logger, id string) (map[string]any, bool) {
+	e, err := scanEvent(s.DB().QueryRow(`SELECT `+eventColumns+` FROM events WHERE id = ?`, id))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Error("query event failed", "event_id", id, "error", err)
+		}
+		return nil, false
+	}
+	return e, true
+}
+
+func scanEvent(row interface{ Scan(...any) error }) (map[string]any, error) {
+	var id, source, typ, payload, ts, route, parentID, corrID string
+	if err := row.Scan(&id, &source, &typ, &payload, &ts, &route, &parentID, &corrID); err != nil {
+		return nil, err
+	}
+	return map[string]any{
+		"id":             id,
+		"source":         source,
+		"type":           typ,
+		"payload":        json.RawMessage(payload),
+		"timestamp":      ts,
+		"route":          route,
+		"parent_id":      parentID,
+		"correlation_id": corrID,
+	}, nil
+}
+
 type pipelineRun struct {
 	ID         int64  `json:"id"`
 	EventID    string `json:"event_id"`
@@ -180,11 +524,15 @@ type pipelineRun struct {
 	DurationMs *int64 `json:"duration_ms,omitempty"`
 	Error      string `json:"error,omitempty"`
 	Steps      string `json:"steps,omitempty"`
+	// Output is the pipeline's final payload, recorded for completed runs.
+	Output json.RawMessage `json:"output,omitempty"`
+	// Progress is the live "n/m steps" shown while the run is going.
+	Progress string `json:"-"`
 }
 
 func queryPipelineRuns(s *store.Store, log *slog.Logger, eventID string) []pipelineRun {
 	rows, err := s.DB().Query(
-		`SELECT id, event_id, route, status, started_at, COALESCE(finished_at, ''), COALESCE(duration_ms, 0), COALESCE(error, ''), COALESCE(steps, '[]')
+		`SELECT id, event_id, route, status, started_at, COALESCE(finished_at, ''), COALESCE(duration_ms, 0), COALESCE(error, ''), COALESCE(steps, '[]'), COALESCE(output, '')
 		 FROM pipeline_runs WHERE event_id = ? ORDER BY id DESC`,
 		eventID,
 	)
@@ -198,9 +546,13 @@ func queryPipelineRuns(s *store.Store, log *slog.Logger, eventID string) []pipel
 	for rows.Next() {
 		var r pipelineRun
 		var dur int64
-		if err := rows.Scan(&r.ID, &r.EventID, &r.Route, &r.Status, &r.StartedAt, &r.FinishedAt, &dur, &r.Error, &r.Steps); err != nil {
+		var output string
+		if err := rows.Scan(&r.ID, &r.EventID, &r.Route, &r.Status, &r.StartedAt, &r.FinishedAt, &dur, &r.Error, &r.Steps, &output); err != nil {
 			continue
 		}
+		if output != "" {
+			r.Output = json.RawMessage(output)
+		}
 		if dur > 0 {
 			r.DurationMs = &dur
 		}
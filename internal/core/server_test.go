@@ -7,8 +7,12 @@ import (
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/a-h/templ"
+	"github.com/boozedog/smoothbrain/internal/config"
 	"github.com/boozedog/smoothbrain/internal/plugin"
 	"github.com/boozedog/smoothbrain/internal/store"
 )
@@ -26,6 +30,30 @@ func (s *stubHealthPlugin) Start(context.Context, plugin.EventBus) error    { re
 func (s *stubHealthPlugin) Stop() error                                     { return nil }
 func (s *stubHealthPlugin) HealthCheck(context.Context) plugin.HealthStatus { return s.status }
 
+// panelPlugin serves an API route and a dashboard panel.
+type panelPlugin struct {
+	stubHealthPlugin
+}
+
+func (p *panelPlugin) RegisterAPI(reg plugin.APIRegistrar) {
+	reg.RegisterAPI(http.MethodGet, "items", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, `["a"]`)
+	})
+	reg.RegisterAPI(http.MethodPost, "restart", func(http.ResponseWriter, *http.Request) {})
+	reg.RegisterAPI(http.MethodDelete, "items", func(http.ResponseWriter, *http.Request) {})
+	// Conflicts with the built-in POST /api/plugins/{name}/restart.
+	reg.RegisterAPI(http.MethodPost, "{action}", func(http.ResponseWriter, *http.Request) {})
+}
+
+func (p *panelPlugin) Panels() []plugin.Panel {
+	return []plugin.Panel{{
+		Name:    "items",
+		Title:   "Items",
+		Refresh: 30 * time.Second,
+		Render:  func(*http.Request) templ.Component { return templ.Raw("<p>2 items</p>") },
+	}}
+}
+
 // --- helpers ---
 
 func newTestServer(t *testing.T) (*Server, *store.Store) {
@@ -36,7 +64,7 @@ func newTestServer(t *testing.T) (*Server, *store.Store) {
 	}
 	t.Cleanup(func() { _ = st.Close() })
 	log := slog.New(slog.NewTextHandler(io.Discard, nil))
-	reg := plugin.NewRegistry(log, st.DB())
+	reg := plugin.NewRegistry(log, st)
 	hub := NewHub(st, log)
 	srv := NewServer(st, log, hub, reg, nil, NewLogBuffer(10))
 	return srv, st
@@ -57,7 +85,7 @@ func TestQueryEvents_Empty(t *testing.T) {
 
 func TestQueryEvents_Populated(t *testing.T) {
 	srv, st := newTestServer(t)
-	_, err := st.DB().Exec(
+	_, err := st.Exec(context.Background(),
 		`INSERT INTO events (id, source, type, payload, timestamp, route) VALUES (?, ?, ?, ?, ?, ?)`,
 		"evt-100", "webhook", "push", `{"ref":"main"}`, "2025-01-01T00:00:00Z", "my-route",
 	)
@@ -112,7 +140,7 @@ func TestQueryPipelineRuns_Populated(t *testing.T) {
 	srv, st := newTestServer(t)
 
 	// Insert a parent event first.
-	_, err := st.DB().Exec(
+	_, err := st.Exec(context.Background(),
 		`INSERT INTO events (id, source, type, payload, timestamp) VALUES (?, ?, ?, ?, ?)`,
 		"evt-200", "src", "test", "{}", "2025-01-01T00:00:00Z",
 	)
@@ -121,7 +149,7 @@ func TestQueryPipelineRuns_Populated(t *testing.T) {
 	}
 
 	// Insert a pipeline run with a duration.
-	_, err = st.DB().Exec(
+	_, err = st.Exec(context.Background(),
 		`INSERT INTO pipeline_runs (event_id, route, status, started_at, finished_at, duration_ms, steps)
 		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
 		"evt-200", "test-route", "completed", "2025-01-01T00:00:00Z", "2025-01-01T00:00:01Z", 150, `[]`,
@@ -131,7 +159,7 @@ func TestQueryPipelineRuns_Populated(t *testing.T) {
 	}
 
 	// Insert a pipeline run without a duration (0 maps to nil).
-	_, err = st.DB().Exec(
+	_, err = st.Exec(context.Background(),
 		`INSERT INTO pipeline_runs (event_id, route, status, started_at)
 		 VALUES (?, ?, ?, ?)`,
 		"evt-200", "test-route-2", "running", "2025-01-01T00:00:02Z",
@@ -200,7 +228,7 @@ func TestHandleHealth_Error(t *testing.T) {
 	}
 	t.Cleanup(func() { _ = st.Close() })
 	log := slog.New(slog.NewTextHandler(io.Discard, nil))
-	reg := plugin.NewRegistry(log, st.DB())
+	reg := plugin.NewRegistry(log, st)
 	reg.Register(&stubHealthPlugin{
 		name:   "bad-plugin",
 		status: plugin.HealthStatus{Status: plugin.StatusError, Message: "down"},
@@ -230,7 +258,7 @@ func TestHandleHealth_Error(t *testing.T) {
 
 func TestHandleEvents_JSON(t *testing.T) {
 	srv, st := newTestServer(t)
-	_, err := st.DB().Exec(
+	_, err := st.Exec(context.Background(),
 		`INSERT INTO events (id, source, type, payload, timestamp) VALUES (?, ?, ?, ?, ?)`,
 		"evt-300", "src", "test", `{"k":"v"}`, "2025-01-01T00:00:00Z",
 	)
@@ -263,14 +291,14 @@ func TestHandleEvents_JSON(t *testing.T) {
 
 func TestHandleEventRuns_JSON(t *testing.T) {
 	srv, st := newTestServer(t)
-	_, err := st.DB().Exec(
+	_, err := st.Exec(context.Background(),
 		`INSERT INTO events (id, source, type, payload, timestamp) VALUES (?, ?, ?, ?, ?)`,
 		"evt-400", "src", "test", "{}", "2025-01-01T00:00:00Z",
 	)
 	if err != nil {
 		t.Fatal(err)
 	}
-	_, err = st.DB().Exec(
+	_, err = st.Exec(context.Background(),
 		`INSERT INTO pipeline_runs (event_id, route, status, started_at, duration_ms, steps)
 		 VALUES (?, ?, ?, ?, ?, ?)`,
 		"evt-400", "r1", "completed", "2025-01-01T00:00:00Z", 42, `[]`,
@@ -328,3 +356,407 @@ func TestNewServer_HandlerNotNil(t *testing.T) {
 		t.Error("Handler() returned nil")
 	}
 }
+
+// --- backup tests ---
+
+func TestHandleBackup_StreamsSQLite(t *testing.T) {
+	srv, _ := newTestServer(t)
+	req := httptest.NewRequest("GET", "/api/backup", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if !strings.HasPrefix(w.Body.String(), "SQLite format 3\x00") {
+		t.Error("response body is not a SQLite database")
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.Contains(cd, "attachment") {
+		t.Errorf("Content-Disposition = %q, want attachment", cd)
+	}
+}
+
+func TestHandleReplay_EmitsChild(t *testing.T) {
+	srv, st := newTestServer(t)
+	bus := NewBus(st, srv.log)
+	srv.SetBus(bus)
+
+	var got plugin.Event
+	bus.Subscribe(func(e plugin.Event) { got = e })
+	bus.Emit(plugin.Event{ID: "orig", Source: "td", Type: "create", Payload: map[string]any{"k": "v"}})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/events/orig/replay", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if got.ID == "orig" || got.ParentID != "orig" || got.CorrelationID != "orig" {
+		t.Errorf("replayed event = %+v, want child of orig", got)
+	}
+	if got.Source != "td" || got.Type != "create" || got.Payload["k"] != "v" {
+		t.Errorf("replayed event = %+v, want copy of orig", got)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/events/missing/replay", nil)
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("missing event status = %d, want 404", rec.Code)
+	}
+}
+
+// newIngestServer wires a server, bus and router with a "tag" transform on
+// webhook events.
+func newIngestServer(t *testing.T) (*Server, *stubSink) {
+	t.Helper()
+	out := &stubSink{name: "out"}
+	router, cleanup := newTestRouter(t, []config.RouteConfig{{
+		Name:     "tagged",
+		Source:   "webhook",
+		Pipeline: []config.StepConfig{{Plugin: "tag", Action: "run"}},
+		Sink:     config.SinkConfig{Plugin: "out", Params: map[string]any{"channel": "x"}},
+	}}, map[string]*stubTransform{"tag": {name: "tag"}}, map[string]*stubSink{"out": out})
+	t.Cleanup(cleanup)
+	bus := NewBus(router.store, router.log)
+	bus.Subscribe(router.HandleEvent)
+	srv := NewServer(router.store, router.log, NewHub(router.store, router.log), router.registry, nil, NewLogBuffer(10))
+	srv.SetBus(bus)
+	srv.SetRouter(router)
+	srv.SetIngestSources([]string{"webhook", "other", "a"})
+	return srv, out
+}
+
+func TestHandleIngest_WaitReturnsOutput(t *testing.T) {
+	srv, out := newIngestServer(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/events?wait=5s", strings.NewReader(`{"source":"webhook","type":"push","payload":{"n":1}}`))
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body)
+	}
+
+	var res ingestResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.EventID == "" || res.TimedOut || len(res.Runs) != 1 {
+		t.Fatalf("result = %+v, want one finished run", res)
+	}
+	run := res.Runs[0]
+	if run.Route != "tagged" || run.Status != "completed" {
+		t.Errorf("run = %+v", run)
+	}
+	var output map[string]any
+	if err := json.Unmarshal(run.Output, &output); err != nil {
+		t.Fatalf("output %s: %v", run.Output, err)
+	}
+	if output["n"] != 1.0 || output["transformed_by_tag"] != true || output["channel"] != nil {
+		t.Errorf("output = %v, want pipeline output without sink params", output)
+	}
+	if len(out.events) != 1 {
+		t.Errorf("sink events = %d, want 1", len(out.events))
+	}
+}
+
+func TestHandleIngest_Idempotency(t *testing.T) {
+	srv, out := newIngestServer(t)
+
+	post := func() ingestResult {
+		req := httptest.NewRequest(http.MethodPost, "/api/events?wait=5s", strings.NewReader(`{"source":"webhook","type":"push"}`))
+		req.Header.Set("Idempotency-Key", "order-42")
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		var res ingestResult
+		if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+		return res
+	}
+	first, second := post(), post()
+	if first.Duplicate || !second.Duplicate || first.EventID != second.EventID {
+		t.Errorf("first = %+v, second = %+v; want the retry to return the same event", first, second)
+	}
+	if len(second.Runs) != 1 {
+		t.Errorf("duplicate runs = %+v, want the original's run", second.Runs)
+	}
+	if len(out.events) != 1 {
+		t.Errorf("sink events = %d, want 1", len(out.events))
+	}
+}
+
+func TestHandleIngest_Batch(t *testing.T) {
+	srv, out := newIngestServer(t)
+
+	body := `{"source":"webhook","type":"a","idempotency_key":"k1"}
+
+{"source":"other","type":"b"}
+`
+	req := httptest.NewRequest(http.MethodPost, "/api/events", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	var res struct{ Events []ingestResult }
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || len(res.Events) != 2 {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body)
+	}
+	if err := srv.router.Wait(context.Background(), res.Events[0].EventID); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.events) != 1 {
+		t.Errorf("sink events = %d, want 1 from the webhook event", len(out.events))
+	}
+
+	for _, tc := range []struct{ name, contentType, body string }{
+		{"missing type", "application/json", `{"source":"webhook"}`},
+		{"bad line", "application/x-ndjson", "{\"source\":\"a\",\"type\":\"b\"}\n{nope}\n"},
+		{"empty batch", "application/x-ndjson", "\n"},
+		{"payload not an object", "application/json", `{"source":"a","type":"b","payload":[1]}`},
+		{"plugin source", "application/json", `{"source":"tag","type":"b"}`},
+		{"unlisted source in batch", "application/x-ndjson", "{\"source\":\"a\",\"type\":\"b\"}\n{\"source\":\"mattermost\",\"type\":\"b\"}\n"},
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/events", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", tc.name, rec.Code)
+		}
+	}
+	var n int
+	if err := srv.store.DB().QueryRow(`SELECT COUNT(*) FROM events`).Scan(&n); err != nil || n != 2 {
+		t.Errorf("stored events = %d, %v; want only the 2 valid ones", n, err)
+	}
+}
+
+func TestHandleIngest_BatchReportsFailedEvents(t *testing.T) {
+	srv, _ := newIngestServer(t)
+	// Without the table, claiming an idempotency key fails.
+	if _, err := srv.store.Exec(context.Background(), `DROP TABLE event_idempotency`); err != nil {
+		t.Fatal(err)
+	}
+
+	body := "{\"source\":\"webhook\",\"type\":\"a\"}\n{\"source\":\"webhook\",\"type\":\"b\",\"idempotency_key\":\"k1\"}\n"
+	req := httptest.NewRequest(http.MethodPost, "/api/events", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("status = %d, want 207: %s", rec.Code, rec.Body)
+	}
+	var res struct{ Events []ingestResult }
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil || len(res.Events) != 2 {
+		t.Fatalf("body = %s", rec.Body)
+	}
+	if res.Events[0].EventID == "" || res.Events[0].Error != "" {
+		t.Errorf("first event = %+v, want emitted", res.Events[0])
+	}
+	if res.Events[1].EventID != "" || res.Events[1].Error == "" {
+		t.Errorf("second event = %+v, want an error", res.Events[1])
+	}
+}
+
+func TestHandleEventTree_JSON(t *testing.T) {
+	srv, st := newTestServer(t)
+	bus := NewBus(st, srv.log)
+
+	root := plugin.Event{ID: "root", Source: "mattermost", Type: "summarize", Payload: map[string]any{}}
+	bus.Emit(root)
+	child := root.Derive("route", "summary.ready", map[string]any{})
+	bus.Emit(child)
+	bus.Emit(child.Derive("mattermost", "notify", map[string]any{}))
+
+	// Any event in the tree returns the whole tree from the root.
+	req := httptest.NewRequest(http.MethodGet, "/api/events/"+child.ID+"/tree", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+
+	var tree eventNode
+	if err := json.NewDecoder(rec.Body).Decode(&tree); err != nil {
+		t.Fatal(err)
+	}
+	if tree.ID != "root" || len(tree.Children) != 1 {
+		t.Fatalf("root = %s with %d children, want root with 1", tree.ID, len(tree.Children))
+	}
+	if c := tree.Children[0]; c.ID != child.ID || len(c.Children) != 1 || c.Children[0].Type != "notify" {
+		t.Errorf("unexpected subtree: %+v", c)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/events/"+child.ID+"/tree/html", nil)
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), "lineage-current") {
+		t.Error("tree HTML does not highlight the requested event")
+	}
+}
+
+func TestHandleStatusHTML_ListsNextTaskRuns(t *testing.T) {
+	srv, st := newTestServer(t)
+	sup := NewSupervisor([]config.SupervisorTask{{Name: "nightly job", Schedule: "@daily"}}, NewBus(st, srv.log), st, srv.log)
+	srv.SetSupervisor(sup)
+	t.Cleanup(sup.Stop)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/status/html", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	body := rec.Body.String()
+	if !strings.Contains(body, "Scheduled Tasks") || !strings.Contains(body, "nightly job") {
+		t.Errorf("status tab does not list scheduled tasks:\n%s", body)
+	}
+}
+
+func TestHandleSupervisorTasks(t *testing.T) {
+	srv, st := newTestServer(t)
+	bus := NewBus(st, srv.log)
+	sup := NewSupervisor([]config.SupervisorTask{{Name: "nightly job", Schedule: "@daily"}}, bus, st, srv.log)
+	srv.SetSupervisor(sup)
+	t.Cleanup(sup.Stop)
+
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := post("/api/supervisor/missing/run"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown task status = %d, want 404", rec.Code)
+	}
+	rec := post("/api/supervisor/nightly%20job/pause")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("pause status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("HX-Trigger") != "supervisor-changed" {
+		t.Errorf("HX-Trigger = %q, want supervisor-changed", rec.Header().Get("HX-Trigger"))
+	}
+	if rec := post("/api/supervisor/nightly%20job/run"); rec.Code != http.StatusOK {
+		t.Fatalf("run status = %d, want 200: %s", rec.Code, rec.Body)
+	}
+	sup.Stop()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/supervisor/nightly%20job/runs", nil)
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	var runs []TaskRun
+	if err := json.NewDecoder(rec.Body).Decode(&runs); err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 1 || runs[0].Result != "emitted" {
+		t.Errorf("runs = %+v, want one emitted run", runs)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/supervisor/html", nil)
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	body := rec.Body.String()
+	for _, want := range []string{"nightly job", "paused", "/api/supervisor/nightly%20job/resume", "emitted"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("supervisor tab missing %q", want)
+		}
+	}
+}
+
+func TestHandleSupervisor_Unavailable(t *testing.T) {
+	srv, _ := newTestServer(t)
+	req := httptest.NewRequest(http.MethodPost, "/api/supervisor/t/run", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", rec.Code)
+	}
+}
+
+func TestHandleHeartbeatPing(t *testing.T) {
+	srv, st := newTestServer(t)
+	beats := NewHeartbeats([]config.HeartbeatConfig{{Name: "backup", Every: "24h"}}, NewBus(st, srv.log), st, srv.log)
+	srv.SetHeartbeats(beats)
+
+	for _, tc := range []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/hooks/heartbeat/backup", http.StatusOK},
+		{http.MethodPost, "/hooks/heartbeat/backup", http.StatusOK},
+		{http.MethodGet, "/hooks/heartbeat/missing", http.StatusNotFound},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s %s status = %d, want %d", tc.method, tc.path, rec.Code, tc.want)
+		}
+	}
+	if beats.Statuses()[0].LastSeen.IsZero() {
+		t.Error("ping did not record a beat")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/heartbeats/html", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if body := rec.Body.String(); !strings.Contains(body, "backup") || !strings.Contains(body, "ping") {
+		t.Errorf("heartbeats panel = %q", body)
+	}
+}
+
+func TestHandlePluginRestart(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.registry.Register(&stubHealthPlugin{name: "mm"})
+	if err := srv.registry.InitAll(nil); err != nil {
+		t.Fatal(err)
+	}
+	srv.registry.StartAll(context.Background(), nil)
+	t.Cleanup(srv.registry.StopAll)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/plugins/mm/restart", http.StatusOK},
+		{"/api/plugins/nope/restart", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("POST %s = %d, want %d", tt.path, rec.Code, tt.want)
+		}
+		if rec.Header().Get("HX-Trigger") != "plugins-changed" {
+			t.Errorf("POST %s HX-Trigger = %q", tt.path, rec.Header().Get("HX-Trigger"))
+		}
+	}
+}
+
+func TestPluginAPIAndPanels(t *testing.T) {
+	srv, _ := newTestServer(t)
+	srv.registry.Register(&panelPlugin{stubHealthPlugin{name: "kb"}})
+	srv.registry.RegisterAPIs(srv)
+
+	tests := []struct {
+		method, path string
+		want         int
+		body         string
+	}{
+		{http.MethodGet, "/api/plugins/kb/items", http.StatusOK, `["a"]`},
+		{http.MethodDelete, "/api/plugins/kb/items", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/api/plugins/kb/panels/items", http.StatusOK, "<p>2 items</p>"},
+		{http.MethodGet, "/api/plugins/kb/panels/nope", http.StatusNotFound, ""},
+		{http.MethodGet, "/api/panels/html", http.StatusOK, `hx-get="/api/plugins/kb/panels/items" hx-trigger="load, every 30s"`},
+		// The plugin's reserved "restart" route was refused; the built-in one answers.
+		{http.MethodPost, "/api/plugins/kb/restart", http.StatusInternalServerError, "plugins not started"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rec.Code, tt.want)
+		}
+		if !strings.Contains(rec.Body.String(), tt.body) {
+			t.Errorf("%s %s body = %q, want it to contain %q", tt.method, tt.path, rec.Body.String(), tt.body)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package core
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boozedog/smoothbrain/internal/plugin"
+	"github.com/boozedog/smoothbrain/internal/store"
+)
+
+const (
+	// streamBuffer is how many messages a stream client may fall behind by
+	// before it is disconnected; it can then resume with Last-Event-ID.
+	streamBuffer = 256
+	// streamBacklog caps the events replayed to a resuming client.
+	streamBacklog = 1000
+	// streamPing is how often an idle stream sends a comment, keeping
+	// proxies from closing it.
+	streamPing = 25 * time.Second
+)
+
+// Stream serves GET /api/stream: Server-Sent Events carrying bus events
+// ("event") and pipeline run progress ("run") as JSON.
+type Stream struct {
+	store *store.Store
+	log   *slog.Logger
+
+	mu      sync.Mutex
+	clients map[*streamClient]struct{}
+	closed  bool
+}
+
+func NewStream(s *store.Store, log *slog.Logger) *Stream {
+	return &Stream{store: s, log: log, clients: make(map[*streamClient]struct{})}
+}
+
+type streamMsg struct {
+	id    string // SSE id; set for bus events only
+	kind  string // SSE event name: "event" or "run"
+	data  []byte
+	match streamFields
+}
+
+// streamFields are the values a client's filters are checked against.
+// Events have no route.
+type streamFields struct {
+	source, typ, route string
+}
+
+// streamFilter holds the query's filters; an empty list matches anything.
+// Routes filter run messages only, since events carry none.
+type streamFilter struct {
+	sources, types, routes []string
+}
+
+func (f streamFilter) matches(m streamFields) bool {
+	in := func(list []string, v string) bool { return len(list) == 0 || slices.Contains(list, v) }
+	return in(f.sources, m.source) && in(f.types, m.typ) && (m.route == "" || in(f.routes, m.route))
+}
+
+// where returns the SQL condition, and its arguments, that matches stored
+// events against the source and type filters.
+func (f streamFilter) where() (string, []any) {
+	var cond string
+	var args []any
+	for _, c := range []struct {
+		col    string
+		values []string
+	}{{"source", f.sources}, {"type", f.types}} {
+		if len(c.values) == 0 {
+			continue
+		}
+		cond += " AND " + c.col + " IN (?" + strings.Repeat(", ?", len(c.values)-1) + ")"
+		for _, v := range c.values {
+			args = append(args, v)
+		}
+	}
+	return cond, args
+}
+
+type streamClient struct {
+	filter streamFilter
+	ch     chan streamMsg
+	done   chan struct{} // closed when the client is dropped
+}
+
+// HandleEvent is a bus subscriber.
+func (s *Stream) HandleEvent(e plugin.Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		s.log.Error("stream: marshal event", "error", err)
+		return
+	}
+	s.broadcast(streamMsg{id: e.ID, kind: "event", data: data, match: streamFields{e.Source, e.Type, ""}})
+}
+
+// HandleRun is a router run subscriber.
+func (s *Stream) HandleRun(u RunUpdate) {
+	data, err := json.Marshal(u)
+	if err != nil {
+		s.log.Error("stream: marshal run update", "error", err)
+		return
+	}
+	s.broadcast(streamMsg{kind: "run", data: data, match: streamFields{u.Source, u.Type, u.Route}})
+}
+
+// broadcast queues msg for every matching client without blocking. A
+// client whose buffer is full is dropped.
+func (s *Stream) broadcast(msg streamMsg) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		if !c.filter.matches(msg.match) {
+			continue
+		}
+		select {
+		case c.ch <- msg:
+		default:
+			s.log.Warn("stream client too slow, disconnecting")
+			s.drop(c)
+		}
+	}
+}
+
+// drop removes a client; s.mu must be held.
+func (s *Stream) drop(c *streamClient) {
+	if _, ok := s.clients[c]; ok {
+		delete(s.clients, c)
+		close(c.done)
+	}
+}
+
+// Close disconnects every client, so HTTP shutdown need not wait on open
+// streams.
+func (s *Stream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for c := range s.clients {
+		s.drop(c)
+	}
+}
+
+func (s *Stream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := streamFilter{sources: q["source"], types: q["type"], routes: q["route"]}
+
+	c := &streamClient{filter: filter, ch: make(chan streamMsg, streamBuffer), done: make(chan struct{})}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	s.clients[c] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.drop(c)
+		s.mu.Unlock()
+	}()
+
+	// Register before reading the backlog, so no event falls between them;
+	// live copies of backlog events are skipped below.
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = q.Get("last_event_id")
+	}
+	var backlog []streamMsg
+	if lastID != "" {
+		var err error
+		if backlog, err = s.backlog(r.Context(), lastID, filter); err != nil {
+			s.log.Error("stream: load backlog", "error", err)
+			http.Error(w, "stream failed", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	// The server's write timeout would end the stream.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	sent := make(map[string]bool, len(backlog))
+	for _, m := range backlog {
+		if err := writeSSE(w, m); err != nil {
+			return
+		}
+		sent[m.id] = true
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(streamPing)
+	defer ping.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-c.done:
+			return
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		case m := <-c.ch:
+			if m.id != "" && sent[m.id] {
+				continue
+			}
+			if err := writeSSE(w, m); err != nil {
+				return
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeSSE(w http.ResponseWriter, m streamMsg) error {
+	if m.id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", m.id); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", m.kind, m.data)
+	return err
+}
+
+// backlog returns the stored events after lastID that match filter, oldest
+// first. An unknown ID replays nothing rather than everything.
+func (s *Stream) backlog(ctx context.Context, lastID string, filter streamFilter) ([]streamMsg, error) {
+	var after int64
+	err := s.store.DB().QueryRowContext(ctx, `SELECT rowid FROM events WHERE id = ?`, lastID).Scan(&after)
+	if errors.Is(err, sql.ErrNoRows) {
+		s.log.Warn("stream: resume from unknown event", "last_event_id", lastID)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Filter in SQL, so the limit counts only events the client wants.
+	cond, args := filter.where()
+	rows, err := s.store.DB().QueryContext(ctx,
+		`SELECT id, source, type, payload, timestamp, COALESCE(parent_id, ''), COALESCE(correlation_id, '')
+		 FROM events WHERE rowid > ?`+cond+` ORDER BY rowid LIMIT ?`,
+		append(append([]any{after}, args...), streamBacklog)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var msgs []streamMsg
+	for rows.Next() {
+		var e plugin.Event
+		var payload string
+		if err := rows.Scan(&e.ID, &e.Source, &e.Type, &payload, &e.Timestamp, &e.ParentID, &e.CorrelationID); err != nil {
+			return nil, err
+		}
+		m := streamFields{e.Source, e.Type, ""}
+		if err := json.Unmarshal([]byte(payload), &e.Payload); err != nil {
+			s.log.Warn("stream: bad stored payload", "event_id", e.ID, "error", err)
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, streamMsg{id: e.ID, kind: "event", data: data, match: m})
+	}
+	return msgs, rows.Err()
+}
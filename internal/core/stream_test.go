@@ -0,0 +1,221 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/boozedog/smoothbrain/internal/plugin"
+	"github.com/boozedog/smoothbrain/internal/store"
+)
+
+type sseMsg struct {
+	id, event, data string
+}
+
+// readSSE parses messages from an SSE response body onto a channel.
+func readSSE(body io.Reader) <-chan sseMsg {
+	ch := make(chan sseMsg, 16)
+	go func() {
+		defer close(ch)
+		var m sseMsg
+		sc := bufio.NewScanner(body)
+		for sc.Scan() {
+			line := sc.Text()
+			switch {
+			case line == "":
+				if m.event != "" {
+					ch <- m
+				}
+				m = sseMsg{}
+			case strings.HasPrefix(line, "id: "):
+				m.id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				m.event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				m.data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}()
+	return ch
+}
+
+func next(t *testing.T, ch <-chan sseMsg) sseMsg {
+	t.Helper()
+	select {
+	case m, ok := <-ch:
+		if !ok {
+			t.Fatal("stream closed")
+		}
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for stream message")
+	}
+	return sseMsg{}
+}
+
+func newTestStream(t *testing.T) (*Stream, *Bus, *httptest.Server) {
+	t.Helper()
+	st, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	stream := NewStream(st, log)
+	bus := NewBus(st, log)
+	bus.Subscribe(stream.HandleEvent)
+	srv := httptest.NewServer(stream)
+	t.Cleanup(srv.Close)
+	t.Cleanup(stream.Close)
+	return stream, bus, srv
+}
+
+func openStream(t *testing.T, url, lastID string) <-chan sseMsg {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	return readSSE(resp.Body)
+}
+
+// waitClients waits until n stream clients are registered.
+func waitClients(t *testing.T, s *Stream, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		got := len(s.clients)
+		s.mu.Unlock()
+		if got == n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timeout waiting for %d stream clients", n)
+}
+
+func TestStream_EventsAndFilters(t *testing.T) {
+	stream, bus, srv := newTestStream(t)
+	all := openStream(t, srv.URL, "")
+	tdOnly := openStream(t, srv.URL+"?source=td", "")
+	waitClients(t, stream, 2)
+
+	bus.Emit(plugin.Event{ID: "e1", Source: "webhook", Type: "push", Payload: map[string]any{"n": 1}})
+	bus.Emit(plugin.Event{ID: "e2", Source: "td", Type: "create", Payload: map[string]any{}})
+
+	m := next(t, all)
+	var e plugin.Event
+	if err := json.Unmarshal([]byte(m.data), &e); err != nil {
+		t.Fatal(err)
+	}
+	if m.event != "event" || m.id != "e1" || e.Source != "webhook" || e.Payload["n"] != 1.0 {
+		t.Errorf("first message = %+v", m)
+	}
+	if m := next(t, all); m.id != "e2" {
+		t.Errorf("second message id = %q, want e2", m.id)
+	}
+	if m := next(t, tdOnly); m.id != "e2" {
+		t.Errorf("filtered stream got %q, want only e2", m.id)
+	}
+
+	stream.HandleRun(RunUpdate{RunID: 1, EventID: "e2", Source: "td", Type: "create", Route: "r", Status: "completed"})
+	m = next(t, tdOnly)
+	var u RunUpdate
+	if err := json.Unmarshal([]byte(m.data), &u); err != nil {
+		t.Fatal(err)
+	}
+	if m.event != "run" || m.id != "" || u.Route != "r" || u.Status != "completed" {
+		t.Errorf("run message = %+v", m)
+	}
+}
+
+func TestStream_ResumeFromLastEventID(t *testing.T) {
+	stream, bus, srv := newTestStream(t)
+	for _, id := range []string{"a", "b", "c"} {
+		bus.Emit(plugin.Event{ID: id, Source: "td", Type: "create", Payload: map[string]any{}})
+	}
+
+	ch := openStream(t, srv.URL, "a")
+	if m := next(t, ch); m.id != "b" {
+		t.Errorf("first resumed id = %q, want b", m.id)
+	}
+	if m := next(t, ch); m.id != "c" {
+		t.Errorf("second resumed id = %q, want c", m.id)
+	}
+	waitClients(t, stream, 1)
+	bus.Emit(plugin.Event{ID: "d", Source: "td", Type: "create", Payload: map[string]any{}})
+	if m := next(t, ch); m.id != "d" {
+		t.Errorf("live id after backlog = %q, want d", m.id)
+	}
+}
+
+func TestStream_ResumeFiltersBeforeLimit(t *testing.T) {
+	_, bus, srv := newTestStream(t)
+	bus.Emit(plugin.Event{ID: "start", Source: "td", Type: "create", Payload: map[string]any{}})
+	for i := range streamBacklog + 1 {
+		bus.Emit(plugin.Event{ID: fmt.Sprintf("noise-%d", i), Source: "webhook", Type: "push", Payload: map[string]any{}})
+	}
+	bus.Emit(plugin.Event{ID: "wanted", Source: "td", Type: "create", Payload: map[string]any{}})
+
+	ch := openStream(t, srv.URL+"?source=td", "start")
+	if m := next(t, ch); m.id != "wanted" {
+		t.Errorf("resumed id = %q, want wanted", m.id)
+	}
+}
+
+func TestStream_RouteFiltersRunsOnly(t *testing.T) {
+	stream, bus, srv := newTestStream(t)
+	ch := openStream(t, srv.URL+"?route=r", "")
+	waitClients(t, stream, 1)
+
+	bus.Emit(plugin.Event{ID: "e1", Source: "td", Type: "create", Payload: map[string]any{}})
+	stream.HandleRun(RunUpdate{RunID: 1, EventID: "e1", Source: "td", Type: "create", Route: "other", Status: "completed"})
+	stream.HandleRun(RunUpdate{RunID: 2, EventID: "e1", Source: "td", Type: "create", Route: "r", Status: "completed"})
+
+	if m := next(t, ch); m.event != "event" || m.id != "e1" {
+		t.Errorf("first message = %+v, want event e1", m)
+	}
+	var u RunUpdate
+	m := next(t, ch)
+	if err := json.Unmarshal([]byte(m.data), &u); err != nil {
+		t.Fatal(err)
+	}
+	if m.event != "run" || u.Route != "r" {
+		t.Errorf("second message = %+v, want the run of route r", m)
+	}
+}
+
+func TestStream_CloseEndsStreams(t *testing.T) {
+	stream, _, srv := newTestStream(t)
+	ch := openStream(t, srv.URL, "")
+	waitClients(t, stream, 1)
+	stream.Close()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Error("unexpected message after Close")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream still open after Close")
+	}
+}
@@ -2,15 +2,18 @@ package core
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
-	"strconv"
-	"strings"
+	"maps"
+	"math/rand/v2"
 	"sync"
 	"time"
 
 	"github.com/boozedog/smoothbrain/internal/config"
 	"github.com/boozedog/smoothbrain/internal/plugin"
+	"github.com/boozedog/smoothbrain/internal/schedule"
 	"github.com/boozedog/smoothbrain/internal/store"
 	"github.com/google/uuid"
 )
@@ -22,15 +25,40 @@ type Supervisor struct {
 	log    *slog.Logger
 	cancel context.CancelFunc
 	wg     sync.WaitGroup
+
+	router *Router
+
+	mu      sync.Mutex
+	ctx     context.Context      // set by Start; parent of manual runs
+	next    map[string]time.Time // task name -> pending fire time
+	running map[string]bool      // tasks with a run in progress
+	paused  map[string]bool      // tasks whose scheduled runs are skipped
 }
 
+var (
+	// ErrUnknownTask is returned for a task name not in the configuration.
+	ErrUnknownTask = errors.New("unknown supervisor task")
+	// ErrTaskRunning is returned by RunNow while the task is already running.
+	ErrTaskRunning = errors.New("supervisor task already running")
+)
+
 func NewSupervisor(tasks []config.SupervisorTask, bus *Bus, store *store.Store, log *slog.Logger) *Supervisor {
-	return &Supervisor{
-		tasks: tasks,
-		bus:   bus,
-		store: store,
-		log:   log,
+	s := &Supervisor{
+		tasks:   tasks,
+		bus:     bus,
+		store:   store,
+		log:     log,
+		next:    make(map[string]time.Time),
+		running: make(map[string]bool),
 	}
+	s.paused = s.loadPaused()
+	return s
+}
+
+// SetRouter lets the supervisor wait for the pipelines a task triggers so it
+// can record their outcome.
+func (s *Supervisor) SetRouter(r *Router) {
+	s.router = r
 }
 
 func (s *Supervisor) Start(ctx context.Context) {
@@ -38,6 +66,9 @@ func (s *Supervisor) Start(ctx context.Context) {
 		return
 	}
 	ctx, s.cancel = context.WithCancel(ctx)
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
 	for _, task := range s.tasks {
 		s.wg.Add(1)
 		go s.run(ctx, task)
@@ -56,108 +87,464 @@ func (s *Supervisor) Stop() {
 func (s *Supervisor) run(ctx context.Context, task config.SupervisorTask) {
 	defer s.wg.Done()
 
-	if strings.Contains(task.Schedule, "@") {
-		s.runDaily(ctx, task)
-	} else {
-		s.runInterval(ctx, task)
-	}
-}
-
-func (s *Supervisor) runDaily(ctx context.Context, task config.SupervisorTask) {
-	hour, min, err := parseDailySchedule(task.Schedule)
+	sched, jitter, err := taskSchedule(task)
 	if err != nil {
-		s.log.Error("invalid daily schedule", "task", task.Name, "schedule", task.Schedule, "error", err)
+		s.log.Error("invalid supervisor schedule", "task", task.Name, "schedule", task.Schedule, "error", err)
 		return
 	}
+	s.log.Info("scheduled supervisor task", "task", task.Name, "schedule", task.Schedule, "timezone", task.Timezone)
 
-	s.log.Info("scheduled daily task", "task", task.Name, "time", fmt.Sprintf("%02d:%02d", hour, min))
+	last := s.loadLastRun(task.Name)
+	if !last.IsZero() {
+		last = s.catchUp(ctx, task, sched, last)
+	}
 
 	for {
-		next := nextDailyRun(hour, min)
-		s.log.Debug("next run", "task", task.Name, "at", next)
+		// Resuming from the last run keeps interval tasks on their cadence
+		// across restarts.
+		now := time.Now()
+		next := sched.Next(now)
+		if !last.IsZero() {
+			if n := sched.Next(last); n.After(now) {
+				next = n
+			}
+		}
+		if next.IsZero() {
+			s.log.Warn("supervisor task has no future runs", "task", task.Name)
+			return
+		}
+		at := next
+		if jitter > 0 {
+			at = at.Add(rand.N(jitter))
+		}
+		s.setNext(task.Name, at)
+		s.log.Debug("next run", "task", task.Name, "at", at)
 
+		timer := time.NewTimer(time.Until(at))
 		select {
 		case <-ctx.Done():
+			timer.Stop()
 			return
-		case <-time.After(time.Until(next)):
-			s.fire(task)
+		case <-timer.C:
+		}
+		last = next
+		if s.isPaused(task.Name) {
+			s.log.Info("supervisor task paused, skipping run", "task", task.Name)
+			continue
 		}
+		// Fire asynchronously so a run still in progress at the next tick
+		// is detected and skipped rather than delaying the schedule.
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.fire(ctx, task, next)
+		}()
+	}
+}
+
+// maxCatchUp bounds how many missed runs the "all" policy replays.
+const maxCatchUp = 100
+
+// catchUp applies the task's catch-up policy to runs missed since last and
+// returns the latest missed fire time, or last if none were missed.
+func (s *Supervisor) catchUp(ctx context.Context, task config.SupervisorTask, sched schedule.Schedule, last time.Time) time.Time {
+	now := time.Now()
+	var missed []time.Time
+	total := 0
+	for t := sched.Next(last); !t.IsZero() && !t.After(now); t = sched.Next(t) {
+		total++
+		missed = append(missed, t)
+		if len(missed) > maxCatchUp {
+			missed = missed[1:]
+		}
+	}
+	if total == 0 {
+		return last
 	}
+	latest := missed[len(missed)-1]
+	if s.isPaused(task.Name) {
+		s.log.Info("supervisor task paused, not catching up", "task", task.Name, "missed", total)
+		return latest
+	}
+
+	switch task.CatchUp {
+	case config.CatchUpOnce:
+		missed = missed[len(missed)-1:]
+	case config.CatchUpAll:
+	default:
+		s.log.Info("skipping missed supervisor runs", "task", task.Name, "missed", total, "last_run", last)
+		return latest
+	}
+
+	s.log.Info("catching up missed supervisor runs", "task", task.Name, "missed", total, "running", len(missed))
+	for _, at := range missed {
+		if ctx.Err() != nil {
+			break
+		}
+		s.fire(ctx, task, at)
+	}
+	return latest
 }
 
-func (s *Supervisor) runInterval(ctx context.Context, task config.SupervisorTask) {
-	d, err := time.ParseDuration(task.Schedule)
+// taskSchedule parses a task's schedule in its timezone, plus its jitter.
+func taskSchedule(task config.SupervisorTask) (schedule.Schedule, time.Duration, error) {
+	loc, err := task.Location()
 	if err != nil {
-		s.log.Error("invalid interval schedule", "task", task.Name, "schedule", task.Schedule, "error", err)
-		return
+		return nil, 0, err
 	}
+	sched, err := schedule.Parse(task.Schedule, loc)
+	if err != nil {
+		return nil, 0, err
+	}
+	jitter, err := task.JitterDuration()
+	if err != nil {
+		return nil, 0, err
+	}
+	return sched, jitter, nil
+}
 
-	s.log.Info("scheduled interval task", "task", task.Name, "every", d)
+func (s *Supervisor) setNext(task string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.next[task] = at
+}
 
-	ticker := time.NewTicker(d)
-	defer ticker.Stop()
+// TaskSchedule describes a task's schedule and state for the UI.
+type TaskSchedule struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	Timezone string    `json:"timezone,omitempty"`
+	Next     time.Time `json:"next"` // zero if the schedule is invalid or exhausted
+	Paused   bool      `json:"paused"`
+	Running  bool      `json:"running"`
+}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			s.fire(task)
+// Schedules returns each task's next fire time. Running tasks report the
+// time they are waiting for, including jitter; others report the schedule's
+// next time from now.
+func (s *Supervisor) Schedules() []TaskSchedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]TaskSchedule, 0, len(s.tasks))
+	for _, task := range s.tasks {
+		ts := TaskSchedule{
+			Name:     task.Name,
+			Schedule: task.Schedule,
+			Timezone: task.Timezone,
+			Paused:   s.paused[task.Name],
+			Running:  s.running[task.Name],
+		}
+		if next, ok := s.next[task.Name]; ok {
+			ts.Next = next
+		} else if sched, _, err := taskSchedule(task); err == nil {
+			ts.Next = sched.Next(time.Now())
+		}
+		out = append(out, ts)
+	}
+	return out
+}
+
+// RunNow starts a run of the named task outside its schedule, even if the
+// task is paused. It returns without waiting for the run to finish. Manual
+// runs do not move the task's persisted last run.
+func (s *Supervisor) RunNow(name string) error {
+	task, ok := s.task(name)
+	if !ok {
+		return ErrUnknownTask
+	}
+	if !s.tryStart(name) {
+		return ErrTaskRunning
+	}
+	s.mu.Lock()
+	ctx := s.ctx
+	s.mu.Unlock()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	s.log.Info("supervisor task triggered manually", "task", name)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.finish(name)
+		s.execute(ctx, task, time.Time{})
+	}()
+	return nil
+}
+
+// Pause stops the named task's scheduled runs, including catch-up runs,
+// until Resume. The paused state survives restarts.
+func (s *Supervisor) Pause(name string) error {
+	return s.setPaused(name, true)
+}
+
+// Resume re-enables the named task's scheduled runs. Runs missed while it
+// was paused are not made up.
+func (s *Supervisor) Resume(name string) error {
+	return s.setPaused(name, false)
+}
+
+func (s *Supervisor) setPaused(name string, paused bool) error {
+	if _, ok := s.task(name); !ok {
+		return ErrUnknownTask
+	}
+	var err error
+	if paused {
+		_, err = s.store.Exec(context.Background(),
+			`INSERT OR IGNORE INTO supervisor_paused (task, paused_at) VALUES (?, ?)`, name, time.Now().UTC())
+	} else {
+		_, err = s.store.Exec(context.Background(), `DELETE FROM supervisor_paused WHERE task = ?`, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if paused {
+		s.paused[name] = true
+	} else {
+		delete(s.paused, name)
+	}
+	s.log.Info("supervisor task paused state changed", "task", name, "paused", paused)
+	return nil
+}
+
+func (s *Supervisor) isPaused(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.paused[name]
+}
+
+func (s *Supervisor) task(name string) (config.SupervisorTask, bool) {
+	for _, t := range s.tasks {
+		if t.Name == name {
+			return t, true
 		}
 	}
+	return config.SupervisorTask{}, false
+}
+
+// fire runs the task for its scheduled time unless the previous run is still
+// in progress, in which case the run is recorded as skipped. A skipped slot
+// still counts as the last run, so catch-up does not replay it.
+func (s *Supervisor) fire(ctx context.Context, task config.SupervisorTask, scheduled time.Time) {
+	if !s.tryStart(task.Name) {
+		s.log.Warn("supervisor task still running, skipping", "task", task.Name)
+		s.markRun(task.Name, scheduled)
+		s.logRun(task.Name, "", "skipped", 0, "previous run still in progress")
+		return
+	}
+	defer s.finish(task.Name)
+	s.execute(ctx, task, scheduled)
 }
 
-func (s *Supervisor) fire(task config.SupervisorTask) {
+// execute starts the task's run, waits for the pipelines it triggers and
+// records the outcome. scheduled is the nominal fire time persisted as the
+// task's last run; it is zero for manual runs.
+func (s *Supervisor) execute(ctx context.Context, task config.SupervisorTask, scheduled time.Time) {
 	s.log.Info("supervisor firing task", "task", task.Name)
+	start := time.Now()
 
+	// Each firing is the root of its own lineage; supervisor_log links the
+	// run to the event tree it started.
 	event := plugin.Event{
 		ID:        uuid.New().String(),
 		Source:    "supervisor",
 		Type:      task.Name,
-		Payload:   map[string]any{"message": task.Prompt},
+		Payload:   taskPayload(task),
 		Timestamp: time.Now(),
 	}
+
+	route, direct, err := s.taskRoute(task)
+	if err != nil {
+		s.log.Error("supervisor task cannot run", "task", task.Name, "error", err)
+		s.logRun(task.Name, "", "failed", 0, err.Error())
+		return
+	}
+	if direct {
+		// Record the event for history and lineage, but run only the task's
+		// own pipeline rather than every route matching supervisor events.
+		event = s.bus.Record(event)
+		s.markRun(task.Name, scheduled)
+		s.router.Run(route, event)
+		result, errMsg := runOutcome(s.store, event.ID)
+		s.logRun(task.Name, event.ID, result, time.Since(start), errMsg)
+		return
+	}
+
 	s.bus.Emit(event)
+	s.markRun(task.Name, scheduled)
 
-	_, err := s.store.DB().Exec(
-		`INSERT INTO supervisor_log (task, result, timestamp) VALUES (?, ?, ?)`,
-		task.Name, "emitted", time.Now(),
-	)
+	result, errMsg := "emitted", ""
+	if s.router != nil {
+		if err := s.router.Wait(ctx, event.ID); err != nil {
+			result, errMsg = "interrupted", err.Error()
+		} else {
+			result, errMsg = runOutcome(s.store, event.ID)
+		}
+	}
+	s.logRun(task.Name, event.ID, result, time.Since(start), errMsg)
+}
+
+// taskPayload builds a task's event payload: its payload map, plus its
+// prompt as "message" unless the payload already sets one.
+func taskPayload(task config.SupervisorTask) map[string]any {
+	payload := maps.Clone(task.Payload)
+	if payload == nil {
+		payload = make(map[string]any)
+	}
+	if _, ok := payload["message"]; !ok && (task.Prompt != "" || len(task.Payload) == 0) {
+		payload["message"] = task.Prompt
+	}
+	return payload
+}
+
+// taskRoute returns the route a task runs directly, if any: its named
+// route or its inline pipeline.
+func (s *Supervisor) taskRoute(task config.SupervisorTask) (config.RouteConfig, bool, error) {
+	if task.Route == "" && !task.Inline() {
+		return config.RouteConfig{}, false, nil
+	}
+	if s.router == nil {
+		return config.RouteConfig{}, false, errors.New("no router to run the task's pipeline")
+	}
+	if task.Route == "" {
+		return task.InlineRoute(), true, nil
+	}
+	route, ok := s.router.Route(task.Route)
+	if !ok {
+		return config.RouteConfig{}, false, fmt.Errorf("unknown route %q", task.Route)
+	}
+	return route, true, nil
+}
+
+// runOutcome summarizes the pipeline runs an event triggered.
+func runOutcome(st *store.Store, eventID string) (result, errMsg string) {
+	rows, err := st.DB().Query(`SELECT status, COALESCE(error, '') FROM pipeline_runs WHERE event_id = ?`, eventID)
 	if err != nil {
-		s.log.Error("failed to log supervisor task", "task", task.Name, "error", err)
+		return "unknown", err.Error()
+	}
+	defer func() { _ = rows.Close() }()
+
+	result = "unrouted"
+	for rows.Next() {
+		var status, e string
+		if err := rows.Scan(&status, &e); err != nil {
+			return "unknown", err.Error()
+		}
+		switch {
+		case status == "failed" || status == "interrupted":
+			if result != "failed" {
+				result, errMsg = status, e
+			}
+		case result == "unrouted":
+			result = status
+		}
 	}
+	return result, errMsg
 }
 
-// parseDailySchedule extracts hours and minutes from a "daily@HH:MM" string.
-func parseDailySchedule(schedule string) (int, int, error) {
-	parts := strings.SplitN(schedule, "@", 2)
-	if len(parts) != 2 {
-		return 0, 0, fmt.Errorf("expected daily@HH:MM, got %q", schedule)
+func (s *Supervisor) tryStart(task string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[task] {
+		return false
 	}
-	timeParts := strings.SplitN(parts[1], ":", 2)
-	if len(timeParts) != 2 {
-		return 0, 0, fmt.Errorf("expected HH:MM, got %q", parts[1])
+	s.running[task] = true
+	return true
+}
+
+func (s *Supervisor) finish(task string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, task)
+}
+
+func (s *Supervisor) logRun(task, eventID, result string, d time.Duration, errMsg string) {
+	_, err := s.store.Exec(context.Background(),
+		`INSERT INTO supervisor_log (task, result, timestamp, event_id, duration_ms, error) VALUES (?, ?, ?, ?, ?, ?)`,
+		task, result, time.Now(), nullStr(eventID), d.Milliseconds(), nullStr(errMsg),
+	)
+	if err != nil {
+		s.log.Error("failed to log supervisor task", "task", task, "error", err)
 	}
-	hour, err := strconv.Atoi(timeParts[0])
+}
+
+// TaskRun is one supervisor_log entry.
+type TaskRun struct {
+	ID         int64     `json:"id"`
+	Timestamp  time.Time `json:"timestamp"`
+	Result     string    `json:"result"`
+	EventID    string    `json:"event_id,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// History returns the named task's most recent runs, newest first.
+func (s *Supervisor) History(name string, limit int) ([]TaskRun, error) {
+	rows, err := s.store.DB().Query(
+		`SELECT id, timestamp, COALESCE(result, ''), COALESCE(event_id, ''), COALESCE(duration_ms, 0), COALESCE(error, '')
+		 FROM supervisor_log WHERE task = ? ORDER BY id DESC LIMIT ?`,
+		name, limit,
+	)
 	if err != nil {
-		return 0, 0, fmt.Errorf("invalid hour: %w", err)
+		return nil, err
 	}
-	min, err := strconv.Atoi(timeParts[1])
+	defer func() { _ = rows.Close() }()
+
+	runs := []TaskRun{}
+	for rows.Next() {
+		var r TaskRun
+		if err := rows.Scan(&r.ID, &r.Timestamp, &r.Result, &r.EventID, &r.DurationMs, &r.Error); err != nil {
+			return nil, err
+		}
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+func (s *Supervisor) loadPaused() map[string]bool {
+	paused := make(map[string]bool)
+	rows, err := s.store.DB().Query(`SELECT task FROM supervisor_paused`)
 	if err != nil {
-		return 0, 0, fmt.Errorf("invalid minute: %w", err)
+		s.log.Error("failed to load paused supervisor tasks", "error", err)
+		return paused
 	}
-	if hour < 0 || hour > 23 || min < 0 || min > 59 {
-		return 0, 0, fmt.Errorf("time out of range: %02d:%02d", hour, min)
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var task string
+		if rows.Scan(&task) == nil {
+			paused[task] = true
+		}
 	}
-	return hour, min, nil
+	return paused
 }
 
-// nextDailyRun returns the next occurrence of the given hour:minute in local time.
-func nextDailyRun(hour, min int) time.Time {
-	now := time.Now()
-	next := time.Date(now.Year(), now.Month(), now.Day(), hour, min, 0, 0, now.Location())
-	if !next.After(now) {
-		next = next.Add(24 * time.Hour)
+// markRun persists a scheduled run as the task's last run; manual runs
+// (zero scheduled time) leave it alone.
+func (s *Supervisor) markRun(task string, scheduled time.Time) {
+	if !scheduled.IsZero() {
+		s.saveLastRun(task, scheduled)
+	}
+}
+
+func (s *Supervisor) loadLastRun(task string) time.Time {
+	var last time.Time
+	err := s.store.DB().QueryRow(`SELECT last_run FROM supervisor_state WHERE task = ?`, task).Scan(&last)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		s.log.Error("failed to load supervisor state", "task", task, "error", err)
+	}
+	return last
+}
+
+func (s *Supervisor) saveLastRun(task string, at time.Time) {
+	_, err := s.store.Exec(context.Background(),
+		`INSERT INTO supervisor_state (task, last_run) VALUES (?, ?)
+		 ON CONFLICT(task) DO UPDATE SET last_run = excluded.last_run`,
+		task, at.UTC(),
+	)
+	if err != nil {
+		s.log.Error("failed to save supervisor state", "task", task, "error", err)
 	}
-	return next
 }
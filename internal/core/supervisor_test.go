@@ -2,8 +2,10 @@ package core
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log/slog"
+	"maps"
 	"testing"
 	"time"
 
@@ -12,100 +14,51 @@ import (
 	"github.com/boozedog/smoothbrain/internal/store"
 )
 
-func TestParseDailySchedule_Valid(t *testing.T) {
-	h, m, err := parseDailySchedule("daily@09:30")
-	if err != nil {
-		t.Fatal(err)
-	}
-	if h != 9 || m != 30 {
-		t.Errorf("got (%d, %d), want (9, 30)", h, m)
+func TestSupervisor_Schedules(t *testing.T) {
+	tasks := []config.SupervisorTask{
+		{Name: "weekly", Schedule: "@weekly", Timezone: "UTC"},
+		{Name: "broken", Schedule: "not a schedule"},
 	}
-}
+	sup, _, _ := newTestSupervisor(t, tasks)
 
-func TestParseDailySchedule_Midnight(t *testing.T) {
-	h, m, err := parseDailySchedule("daily@00:00")
-	if err != nil {
-		t.Fatal(err)
+	got := sup.Schedules()
+	if len(got) != 2 {
+		t.Fatalf("Schedules() len = %d, want 2", len(got))
 	}
-	if h != 0 || m != 0 {
-		t.Errorf("got (%d, %d), want (0, 0)", h, m)
+	next := got[0].Next
+	if next.IsZero() || !next.After(time.Now()) {
+		t.Errorf("weekly next = %v, want a future time", next)
 	}
-}
-
-func TestParseDailySchedule_EndOfDay(t *testing.T) {
-	h, m, err := parseDailySchedule("daily@23:59")
-	if err != nil {
-		t.Fatal(err)
+	if next.UTC().Weekday() != time.Sunday || next.UTC().Hour() != 0 {
+		t.Errorf("weekly next = %v, want Sunday midnight UTC", next.UTC())
 	}
-	if h != 23 || m != 59 {
-		t.Errorf("got (%d, %d), want (23, 59)", h, m)
+	if !got[1].Next.IsZero() {
+		t.Errorf("broken next = %v, want zero", got[1].Next)
 	}
 }
 
-func TestParseDailySchedule_NoAt(t *testing.T) {
-	_, _, err := parseDailySchedule("every5m")
-	if err == nil {
-		t.Error("expected error for schedule without @")
-	}
-}
-
-func TestParseDailySchedule_BadHour(t *testing.T) {
-	_, _, err := parseDailySchedule("daily@25:00")
-	if err == nil {
-		t.Error("expected error for hour=25")
-	}
-}
+func TestSupervisor_RunFiresOnSchedule(t *testing.T) {
+	tasks := []config.SupervisorTask{{Name: "fast", Schedule: "@every 1s", Jitter: "10ms"}}
+	sup, bus, _ := newTestSupervisor(t, tasks)
 
-func TestParseDailySchedule_BadMinute(t *testing.T) {
-	_, _, err := parseDailySchedule("daily@12:60")
-	if err == nil {
-		t.Error("expected error for minute=60")
-	}
-}
+	fired := make(chan plugin.Event, 1)
+	bus.Subscribe(func(e plugin.Event) {
+		select {
+		case fired <- e:
+		default:
+		}
+	})
 
-func TestParseDailySchedule_NotNumber(t *testing.T) {
-	_, _, err := parseDailySchedule("daily@ab:cd")
-	if err == nil {
-		t.Error("expected error for non-numeric time")
-	}
-}
+	sup.Start(context.Background())
+	defer sup.Stop()
 
-func TestParseDailySchedule_MissingColon(t *testing.T) {
-	_, _, err := parseDailySchedule("daily@0930")
-	if err == nil {
-		t.Error("expected error for missing colon")
-	}
-}
-
-func TestNextDailyRun_FutureToday(t *testing.T) {
-	// Schedule at 23:59 — almost certainly in the future
-	next := nextDailyRun(23, 59)
-	now := time.Now()
-
-	// Must be today or tomorrow depending on exact timing
-	if !next.After(now) {
-		t.Errorf("expected next (%v) to be after now (%v)", next, now)
-	}
-
-	// Should be on the same calendar day (unless we're running at exactly 23:59)
-	if next.Day() != now.Day() && next.Sub(now) > 24*time.Hour {
-		t.Errorf("expected next run today, got %v", next)
-	}
-}
-
-func TestNextDailyRun_PastToday(t *testing.T) {
-	// Schedule at 00:00 — almost certainly in the past
-	next := nextDailyRun(0, 0)
-	now := time.Now()
-
-	if !next.After(now) {
-		t.Errorf("expected next (%v) to be after now (%v)", next, now)
-	}
-
-	// Should be tomorrow
-	tomorrow := now.Add(24 * time.Hour)
-	if next.Day() != tomorrow.Day() {
-		t.Errorf("expected next run tomorrow (%d), got day %d", tomorrow.Day(), next.Day())
+	select {
+	case e := <-fired:
+		if e.Type != "fast" {
+			t.Errorf("fired type = %q, want fast", e.Type)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("task did not fire")
 	}
 }
 
@@ -146,7 +99,7 @@ func TestSupervisor_Fire(t *testing.T) {
 	var got plugin.Event
 	bus.Subscribe(func(e plugin.Event) { got = e })
 
-	sup.fire(task)
+	sup.fire(context.Background(), task, time.Now())
 
 	if got.Source != "supervisor" {
 		t.Errorf("event source = %q, want %q", got.Source, "supervisor")
@@ -160,10 +113,13 @@ func TestSupervisor_Fire(t *testing.T) {
 	if got.ID == "" {
 		t.Error("event ID is empty")
 	}
+	if got.ParentID != "" || got.CorrelationID != got.ID {
+		t.Errorf("lineage = (%q, %q), want root correlated with itself", got.ParentID, got.CorrelationID)
+	}
 
 	// Verify supervisor_log row was inserted
 	var count int
-	if err := st.DB().QueryRow("SELECT COUNT(*) FROM supervisor_log WHERE task = ?", "daily-summary").Scan(&count); err != nil {
+	if err := st.DB().QueryRow("SELECT COUNT(*) FROM supervisor_log WHERE task = ? AND event_id = ?", "daily-summary", got.ID).Scan(&count); err != nil {
 		t.Fatalf("query supervisor_log: %v", err)
 	}
 	if count != 1 {
@@ -198,3 +154,364 @@ func TestSupervisor_StopNilCancel(t *testing.T) {
 	// Stop without Start — should not panic
 	sup.Stop()
 }
+
+func countRuns(t *testing.T, st *store.Store, task, result string) int {
+	t.Helper()
+	var n int
+	if err := st.DB().QueryRow(`SELECT COUNT(*) FROM supervisor_log WHERE task = ? AND result = ?`, task, result).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestSupervisor_PersistsLastRun(t *testing.T) {
+	task := config.SupervisorTask{Name: "t", Schedule: "1h"}
+	sup, _, _ := newTestSupervisor(t, []config.SupervisorTask{task})
+
+	at := time.Date(2025, 6, 1, 7, 0, 0, 0, time.UTC)
+	sup.fire(context.Background(), task, at)
+
+	if got := sup.loadLastRun("t"); !got.Equal(at) {
+		t.Errorf("last run = %v, want %v", got, at)
+	}
+}
+
+func TestSupervisor_CatchUpPolicies(t *testing.T) {
+	for _, tt := range []struct {
+		policy string
+		want   int
+	}{
+		{"", 0},
+		{config.CatchUpSkip, 0},
+		{config.CatchUpOnce, 1},
+		{config.CatchUpAll, 3},
+	} {
+		t.Run("policy="+tt.policy, func(t *testing.T) {
+			task := config.SupervisorTask{Name: "t", Schedule: "1h", CatchUp: tt.policy}
+			sup, _, st := newTestSupervisor(t, []config.SupervisorTask{task})
+			sched, _, err := taskSchedule(task)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Down for three and a half intervals: three runs were missed.
+			last := time.Now().Add(-3*time.Hour - 30*time.Minute)
+			latest := sup.catchUp(context.Background(), task, sched, last)
+
+			if want := last.Add(3 * time.Hour); !latest.Equal(want) {
+				t.Errorf("catchUp() = %v, want %v", latest, want)
+			}
+			if got := countRuns(t, st, "t", "emitted"); got != tt.want {
+				t.Errorf("runs = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSupervisor_IntervalResumesCadence(t *testing.T) {
+	task := config.SupervisorTask{Name: "t", Schedule: "1h"}
+	sup, _, _ := newTestSupervisor(t, []config.SupervisorTask{task})
+	last := time.Now().Add(-20 * time.Minute).UTC()
+	sup.saveLastRun("t", last)
+
+	sup.Start(context.Background())
+	defer sup.Stop()
+
+	want := last.Add(time.Hour)
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		sup.mu.Lock()
+		next, ok := sup.next["t"]
+		sup.mu.Unlock()
+		if ok {
+			if !next.Equal(want) {
+				t.Errorf("next = %v, want %v", next, want)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("task was not scheduled")
+}
+
+func TestSupervisor_SkipsOverlappingRun(t *testing.T) {
+	task := config.SupervisorTask{Name: "t", Schedule: "1h"}
+	sup, _, st := newTestSupervisor(t, []config.SupervisorTask{task})
+
+	if !sup.tryStart("t") {
+		t.Fatal("tryStart() = false on idle task")
+	}
+	slot := time.Now().Truncate(time.Second)
+	sup.fire(context.Background(), task, slot)
+	sup.finish("t")
+
+	if last := sup.loadLastRun("t"); !last.Equal(slot) {
+		t.Errorf("last run = %v, want the skipped slot %v so catch-up does not replay it", last, slot)
+	}
+
+	if got := countRuns(t, st, "t", "skipped"); got != 1 {
+		t.Errorf("skipped runs = %d, want 1", got)
+	}
+	if got := countRuns(t, st, "t", "emitted"); got != 0 {
+		t.Errorf("emitted runs = %d, want 0", got)
+	}
+}
+
+func TestSupervisor_RecordsPipelineOutcome(t *testing.T) {
+	task := config.SupervisorTask{Name: "t", Schedule: "1h"}
+	sup, bus, st := newTestSupervisor(t, []config.SupervisorTask{task})
+
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	reg := plugin.NewRegistry(log, st)
+	reg.Register(&stubSink{name: "out", err: errors.New("delivery refused")})
+	router := NewRouter([]config.RouteConfig{
+		{Name: "r", Source: "supervisor", Sink: config.SinkConfig{Plugin: "out"}},
+	}, reg, st, log)
+	bus.Subscribe(router.HandleEvent)
+	sup.SetRouter(router)
+
+	sup.fire(context.Background(), task, time.Now())
+
+	var result, errMsg string
+	var dur int64
+	err := st.DB().QueryRow(
+		`SELECT result, COALESCE(error, ''), duration_ms FROM supervisor_log WHERE task = 't'`,
+	).Scan(&result, &errMsg, &dur)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != "failed" || errMsg != "delivery refused" {
+		t.Errorf("outcome = (%q, %q), want (failed, delivery refused)", result, errMsg)
+	}
+	if dur < 0 {
+		t.Errorf("duration_ms = %d", dur)
+	}
+}
+
+func TestTaskPayload(t *testing.T) {
+	tests := []struct {
+		task config.SupervisorTask
+		want map[string]any
+	}{
+		{config.SupervisorTask{Prompt: "hi"}, map[string]any{"message": "hi"}},
+		{config.SupervisorTask{}, map[string]any{"message": ""}},
+		{config.SupervisorTask{Payload: map[string]any{"days": 30.0}}, map[string]any{"days": 30.0}},
+		{
+			config.SupervisorTask{Prompt: "hi", Payload: map[string]any{"days": 30.0}},
+			map[string]any{"days": 30.0, "message": "hi"},
+		},
+		{
+			config.SupervisorTask{Prompt: "hi", Payload: map[string]any{"message": "explicit"}},
+			map[string]any{"message": "explicit"},
+		},
+	}
+	for _, tt := range tests {
+		got := taskPayload(tt.task)
+		if !maps.Equal(got, tt.want) {
+			t.Errorf("taskPayload(%+v) = %v, want %v", tt.task, got, tt.want)
+		}
+	}
+}
+
+// newDirectSupervisor wires a supervisor to a router with a "query"
+// transform, an "out" sink and a catch-all supervisor route.
+func newDirectSupervisor(t *testing.T, task config.SupervisorTask) (*Supervisor, *store.Store, *stubTransform, *stubSink, *stubSink) {
+	t.Helper()
+	sup, bus, st := newTestSupervisor(t, []config.SupervisorTask{task})
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	reg := plugin.NewRegistry(log, st)
+	query := &stubTransform{name: "query"}
+	out := &stubSink{name: "out"}
+	catchAll := &stubSink{name: "catch-all"}
+	reg.Register(query)
+	reg.Register(out)
+	reg.Register(catchAll)
+	router := NewRouter([]config.RouteConfig{
+		{Name: "notify", Source: "webhook", Pipeline: []config.StepConfig{{Plugin: "query", Action: "due"}}, Sink: config.SinkConfig{Plugin: "out"}},
+		{Name: "all-supervisor", Source: "supervisor", Sink: config.SinkConfig{Plugin: "catch-all"}},
+	}, reg, st, log)
+	bus.Subscribe(router.HandleEvent)
+	sup.SetRouter(router)
+	return sup, st, query, out, catchAll
+}
+
+func TestSupervisor_FireInlinePipeline(t *testing.T) {
+	task := config.SupervisorTask{
+		Name:     "inspections",
+		Schedule: "1h",
+		Payload:  map[string]any{"days": 30.0},
+		Pipeline: []config.StepConfig{{Plugin: "query", Action: "due"}},
+		Sink:     config.SinkConfig{Plugin: "out", Params: map[string]any{"channel": "fleet"}},
+	}
+	sup, st, query, out, catchAll := newDirectSupervisor(t, task)
+
+	sup.fire(context.Background(), task, time.Now())
+
+	if query.called != 1 {
+		t.Errorf("transform calls = %d, want 1", query.called)
+	}
+	if len(out.events) != 1 {
+		t.Fatalf("sink events = %d, want 1", len(out.events))
+	}
+	got := out.events[0].Payload
+	if got["days"] != 30.0 || got["channel"] != "fleet" || got["transformed_by_query"] != true {
+		t.Errorf("sink payload = %v", got)
+	}
+	if len(catchAll.events) != 0 {
+		t.Errorf("catch-all route received %d events, want 0", len(catchAll.events))
+	}
+	if n := countRuns(t, st, "inspections", "completed"); n != 1 {
+		t.Errorf("completed runs = %d, want 1", n)
+	}
+	var route string
+	if err := st.DB().QueryRow(`SELECT route FROM pipeline_runs`).Scan(&route); err != nil {
+		t.Fatal(err)
+	}
+	if route != "supervisor:inspections" {
+		t.Errorf("pipeline run route = %q, want supervisor:inspections", route)
+	}
+}
+
+func TestSupervisor_FirePluginShorthand(t *testing.T) {
+	task := config.SupervisorTask{Name: "ping", Schedule: "1h", Prompt: "ping", Plugin: "out"}
+	sup, _, _, out, _ := newDirectSupervisor(t, task)
+
+	sup.fire(context.Background(), task, time.Now())
+
+	if len(out.events) != 1 || out.events[0].Payload["message"] != "ping" {
+		t.Errorf("sink events = %v, want one with message ping", out.events)
+	}
+}
+
+func TestSupervisor_FireNamedRoute(t *testing.T) {
+	task := config.SupervisorTask{Name: "nightly", Schedule: "1h", Route: "notify"}
+	sup, st, query, out, catchAll := newDirectSupervisor(t, task)
+
+	sup.fire(context.Background(), task, time.Now())
+
+	if query.called != 1 || len(out.events) != 1 {
+		t.Errorf("notify route: transform calls = %d, sink events = %d, want 1 and 1", query.called, len(out.events))
+	}
+	if len(catchAll.events) != 0 {
+		t.Errorf("catch-all route received %d events, want 0", len(catchAll.events))
+	}
+	var eventID, route string
+	err := st.DB().QueryRow(`SELECT event_id FROM supervisor_log WHERE task = 'nightly' AND result = 'completed'`).Scan(&eventID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := st.DB().QueryRow(`SELECT COALESCE(route, '') FROM events WHERE id = ?`, eventID).Scan(&route); err != nil {
+		t.Fatal(err)
+	}
+	if route != "notify" {
+		t.Errorf("event route = %q, want notify", route)
+	}
+}
+
+func TestSupervisor_FireDirectWithoutRouter(t *testing.T) {
+	task := config.SupervisorTask{Name: "orphan", Schedule: "1h", Plugin: "out"}
+	sup, _, st := newTestSupervisor(t, []config.SupervisorTask{task})
+
+	sup.fire(context.Background(), task, time.Now())
+
+	if n := countRuns(t, st, "orphan", "failed"); n != 1 {
+		t.Errorf("failed runs = %d, want 1", n)
+	}
+}
+
+func TestSupervisor_RunNow(t *testing.T) {
+	task := config.SupervisorTask{Name: "t", Schedule: "1h"}
+	sup, _, st := newTestSupervisor(t, []config.SupervisorTask{task})
+
+	if err := sup.RunNow("missing"); !errors.Is(err, ErrUnknownTask) {
+		t.Errorf("RunNow(missing) = %v, want ErrUnknownTask", err)
+	}
+	if !sup.tryStart("t") {
+		t.Fatal("tryStart() = false on idle task")
+	}
+	if err := sup.RunNow("t"); !errors.Is(err, ErrTaskRunning) {
+		t.Errorf("RunNow while running = %v, want ErrTaskRunning", err)
+	}
+	sup.finish("t")
+
+	if err := sup.Pause("t"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sup.RunNow("t"); err != nil {
+		t.Fatalf("RunNow on paused task = %v, want nil", err)
+	}
+	sup.Stop()
+
+	if got := countRuns(t, st, "t", "emitted"); got != 1 {
+		t.Errorf("emitted runs = %d, want 1", got)
+	}
+	if last := sup.loadLastRun("t"); !last.IsZero() {
+		t.Errorf("last run = %v, want manual run not persisted", last)
+	}
+}
+
+func TestSupervisor_PauseResume(t *testing.T) {
+	task := config.SupervisorTask{Name: "t", Schedule: "1h", CatchUp: config.CatchUpAll}
+	sup, bus, st := newTestSupervisor(t, []config.SupervisorTask{task})
+
+	if err := sup.Pause("missing"); !errors.Is(err, ErrUnknownTask) {
+		t.Errorf("Pause(missing) = %v, want ErrUnknownTask", err)
+	}
+	if err := sup.Pause("t"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Paused state survives a restart.
+	sup = NewSupervisor([]config.SupervisorTask{task}, bus, st, sup.log)
+	if got := sup.Schedules()[0]; !got.Paused {
+		t.Fatal("task not paused after reload")
+	}
+	sched, _, err := taskSchedule(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sup.catchUp(context.Background(), task, sched, time.Now().Add(-3*time.Hour-30*time.Minute))
+	if got := countRuns(t, st, "t", "emitted"); got != 0 {
+		t.Errorf("runs while paused = %d, want 0", got)
+	}
+
+	if err := sup.Resume("t"); err != nil {
+		t.Fatal(err)
+	}
+	if got := sup.Schedules()[0]; got.Paused {
+		t.Error("task still paused after Resume")
+	}
+	var n int
+	if err := st.DB().QueryRow(`SELECT COUNT(*) FROM supervisor_paused`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("supervisor_paused rows = %d, want 0", n)
+	}
+}
+
+func TestSupervisor_History(t *testing.T) {
+	task := config.SupervisorTask{Name: "t", Schedule: "1h"}
+	sup, _, _ := newTestSupervisor(t, []config.SupervisorTask{task})
+
+	sup.logRun("t", "", "completed", 120*time.Millisecond, "")
+	sup.logRun("t", "", "failed", 0, "boom")
+	sup.logRun("other", "", "completed", 0, "")
+
+	runs, err := sup.History("t", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("History() len = %d, want 2", len(runs))
+	}
+	if runs[0].Result != "failed" || runs[0].Error != "boom" {
+		t.Errorf("newest run = %+v, want failed with error", runs[0])
+	}
+	if runs[1].Result != "completed" || runs[1].DurationMs != 120 {
+		t.Errorf("oldest run = %+v, want completed in 120ms", runs[1])
+	}
+	if runs, _ := sup.History("t", 1); len(runs) != 1 {
+		t.Errorf("History(limit 1) len = %d, want 1", len(runs))
+	}
+}
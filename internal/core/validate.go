@@ -0,0 +1,81 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/boozedog/smoothbrain/internal/config"
+	"github.com/boozedog/smoothbrain/internal/plugin"
+)
+
+// builtinSources emit events without being plugins: chained routes,
+// supervisor tasks and heartbeats.
+var builtinSources = []string{"route", "supervisor", "heartbeat"}
+
+// ValidateRoutes checks the configured routes, and supervisor tasks' inline
+// pipelines, against the registered plugins: every step must name a
+// transform and one of its declared actions, with params that fit the
+// action's schema, every sink a sink, and every source a plugin, built-in
+// source or ingest source. Ingest sources must not shadow a plugin or
+// built-in source. All problems are reported together.
+func ValidateRoutes(cfg *config.Config, reg *plugin.Registry) error {
+	routes := slices.Clone(cfg.Routes)
+	for _, t := range cfg.Supervisor.Tasks {
+		if t.Inline() {
+			routes = append(routes, t.InlineRoute())
+		}
+	}
+
+	var errs []error
+	for _, src := range cfg.Ingest.Sources {
+		if _, ok := reg.Get(src); ok || slices.Contains(builtinSources, src) {
+			errs = append(errs, fmt.Errorf("ingest source %q is already a plugin or built-in source", src))
+		}
+	}
+	for _, r := range routes {
+		fail := func(format string, args ...any) {
+			errs = append(errs, fmt.Errorf("route %q: "+format, append([]any{r.Name}, args...)...))
+		}
+		if _, ok := reg.Get(r.Source); !ok && !slices.Contains(builtinSources, r.Source) && !slices.Contains(cfg.Ingest.Sources, r.Source) {
+			fail("source %q is not a plugin, built-in source (%s) or ingest source", r.Source, strings.Join(builtinSources, ", "))
+		}
+		for i, step := range r.Pipeline {
+			p, ok := reg.Get(step.Plugin)
+			if !ok {
+				fail("pipeline[%d]: plugin %q is not configured", i, step.Plugin)
+				continue
+			}
+			if _, ok := p.(plugin.Transform); !ok {
+				fail("pipeline[%d]: plugin %q is not a transform", i, step.Plugin)
+				continue
+			}
+			if ap, ok := p.(plugin.ActionProvider); ok {
+				if names := actionNames(ap.Actions()); len(names) > 0 && !slices.Contains(names, step.Action) {
+					fail("pipeline[%d]: %s has no action %q (have %s)", i, step.Plugin, step.Action, strings.Join(names, ", "))
+					continue
+				}
+			}
+			if _, err := reg.StepParams(step.Plugin, step.Action, step.Params); err != nil {
+				fail("pipeline[%d]: %s %s params: %v", i, step.Plugin, step.Action, strings.ReplaceAll(err.Error(), "\n", "; "))
+			}
+		}
+		if r.Sink.Plugin != "" {
+			if p, ok := reg.Get(r.Sink.Plugin); !ok {
+				fail("sink plugin %q is not configured", r.Sink.Plugin)
+			} else if _, ok := p.(plugin.Sink); !ok {
+				fail("plugin %q is not a sink", r.Sink.Plugin)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func actionNames(actions []plugin.Action) []string {
+	names := make([]string, len(actions))
+	for i, a := range actions {
+		names[i] = a.Name
+	}
+	return names
+}
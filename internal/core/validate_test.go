@@ -0,0 +1,82 @@
+package core
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/boozedog/smoothbrain/internal/config"
+	"github.com/boozedog/smoothbrain/internal/plugin"
+)
+
+type actionTransform struct {
+	stubTransform
+}
+
+func (a *actionTransform) Actions() []plugin.Action {
+	return []plugin.Action{
+		{Name: "summarize", Params: []plugin.ConfigField{
+			{Name: "prompt", Type: plugin.FieldString},
+			{Name: "max_words", Type: plugin.FieldInt, Default: "100"},
+		}},
+		{Name: "classify"},
+	}
+}
+
+func TestValidateRoutes(t *testing.T) {
+	reg := plugin.NewRegistry(slog.New(slog.NewTextHandler(io.Discard, nil)), nil)
+	reg.Register(&actionTransform{stubTransform{name: "llm"}})
+	reg.Register(&stubTransform{name: "anything"})
+	reg.Register(&stubSink{name: "chat"})
+
+	valid := &config.Config{
+		Routes: []config.RouteConfig{
+			{Name: "ok", Source: "chat", Pipeline: []config.StepConfig{{Plugin: "llm", Action: "summarize", Params: map[string]any{"max_words": 50.0}}, {Plugin: "anything", Action: "whatever", Params: map[string]any{"free": "form"}}}, Sink: config.SinkConfig{Plugin: "chat"}},
+			{Name: "chained", Source: "route", Event: "x", Sink: config.SinkConfig{Plugin: "chat"}},
+			{Name: "pushed", Source: "shortcut", Sink: config.SinkConfig{Plugin: "chat"}},
+		},
+		Ingest: config.IngestConfig{Sources: []string{"shortcut"}},
+	}
+	if err := ValidateRoutes(valid, reg); err != nil {
+		t.Fatalf("ValidateRoutes(valid) error = %v", err)
+	}
+
+	invalid := &config.Config{
+		Routes: []config.RouteConfig{
+			{Name: "typo", Source: "chta", Pipeline: []config.StepConfig{{Plugin: "lmm", Action: "summarize"}}, Sink: config.SinkConfig{Plugin: "chat"}},
+			{Name: "bad-action", Source: "chat", Pipeline: []config.StepConfig{{Plugin: "llm", Action: "summarise"}}, Sink: config.SinkConfig{Plugin: "llm"}},
+			{Name: "bad-params", Source: "chat", Pipeline: []config.StepConfig{{Plugin: "llm", Action: "summarize", Params: map[string]any{"promt": "hi", "max_words": "lots"}}}},
+			{Name: "not-transform", Source: "heartbeat", Pipeline: []config.StepConfig{{Plugin: "chat", Action: "post"}}, Sink: config.SinkConfig{Plugin: "nope"}},
+		},
+		Supervisor: config.SupervisorConfig{Tasks: []config.SupervisorTask{
+			{Name: "daily", Pipeline: []config.StepConfig{{Plugin: "llm", Action: "nap"}}, Plugin: "chat"},
+		}},
+		Ingest: config.IngestConfig{Sources: []string{"chat", "heartbeat"}},
+	}
+	err := ValidateRoutes(invalid, reg)
+	if err == nil {
+		t.Fatal("ValidateRoutes(invalid) succeeded")
+	}
+	want := []string{
+		`ingest source "chat" is already a plugin or built-in source`,
+		`ingest source "heartbeat" is already a plugin or built-in source`,
+		`route "typo": source "chta" is not a plugin, built-in source (route, supervisor, heartbeat) or ingest source`,
+		`route "typo": pipeline[0]: plugin "lmm" is not configured`,
+		`route "bad-action": pipeline[0]: llm has no action "summarise" (have summarize, classify)`,
+		`route "bad-action": plugin "llm" is not a sink`,
+		`route "bad-params": pipeline[0]: llm summarize params: max_words: must be int, got lots; unknown param "promt" (did you mean "prompt"?)`,
+		`route "not-transform": pipeline[0]: plugin "chat" is not a transform`,
+		`route "not-transform": sink plugin "nope" is not configured`,
+		`route "supervisor:daily": pipeline[0]: llm has no action "nap"`,
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != len(want) {
+		t.Errorf("got %d problems, want %d:\n%v", len(lines), len(want), err)
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("error does not contain %q", w)
+		}
+	}
+}
@@ -7,11 +7,14 @@ import (
 	"hash/fnv"
 	"html"
 	"log/slog"
+	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/boozedog/smoothbrain/internal/config"
+	"github.com/boozedog/smoothbrain/internal/outbound"
 	"github.com/boozedog/smoothbrain/internal/plugin"
 	"github.com/boozedog/smoothbrain/internal/store"
 )
@@ -22,6 +25,8 @@ type eventView struct {
 	Type          string
 	Timestamp     string
 	Route         string
+	ParentID      string
+	CorrelationID string
 	PrettyPayload string
 	Runs          []pipelineRun
 }
@@ -29,31 +34,37 @@ type eventView struct {
 func toEventViews(events []map[string]any, s *store.Store, log *slog.Logger) []eventView {
 	views := make([]eventView, 0, len(events))
 	for _, e := range events {
-		v := eventView{
-			ID:        str(e["id"]),
-			Source:    str(e["source"]),
-			Type:      str(e["type"]),
-			Timestamp: str(e["timestamp"]),
-			Route:     str(e["route"]),
-		}
-
-		// Pretty-print and syntax-highlight the JSON payload.
-		if raw, ok := e["payload"].(json.RawMessage); ok {
-			var buf json.RawMessage
-			if json.Unmarshal(raw, &buf) == nil {
-				if pp, err := json.MarshalIndent(buf, "", "  "); err == nil {
-					v.PrettyPayload = colorizeJSON(string(pp))
-				}
+		views = append(views, toEventView(e, s, log))
+	}
+	return views
+}
+
+func toEventView(e map[string]any, s *store.Store, log *slog.Logger) eventView {
+	v := eventView{
+		ID:            str(e["id"]),
+		Source:        str(e["source"]),
+		Type:          str(e["type"]),
+		Timestamp:     str(e["timestamp"]),
+		Route:         str(e["route"]),
+		ParentID:      str(e["parent_id"]),
+		CorrelationID: str(e["correlation_id"]),
+	}
+
+	// Pretty-print and syntax-highlight the JSON payload.
+	if raw, ok := e["payload"].(json.RawMessage); ok {
+		var buf json.RawMessage
+		if json.Unmarshal(raw, &buf) == nil {
+			if pp, err := json.MarshalIndent(buf, "", "  "); err == nil {
+				v.PrettyPayload = colorizeJSON(string(pp))
 			}
 		}
-		if v.PrettyPayload == "" {
-			v.PrettyPayload = html.EscapeString(str(e["payload"]))
-		}
-
-		v.Runs = queryPipelineRuns(s, log, v.ID)
-		views = append(views, v)
 	}
-	return views
+	if v.PrettyPayload == "" {
+		v.PrettyPayload = html.EscapeString(str(e["payload"]))
+	}
+
+	v.Runs = queryPipelineRuns(s, log, v.ID)
+	return v
 }
 
 func runBadgeClass(status string) string {
@@ -69,6 +80,10 @@ func runBadgeClass(status string) string {
 	}
 }
 
+func runElemID(id int64) string {
+	return "run-" + strconv.FormatInt(id, 10)
+}
+
 func shortID(id string) string {
 	if len(id) > 8 {
 		return id[:8]
@@ -111,14 +126,204 @@ func sourceLabelStyle(name string) string {
 type statusInfo struct {
 	Plugins []pluginStatus
 	Routes  []routeStatus
+	Tasks   []taskStatus
+}
+
+type taskStatus struct {
+	Name     string
+	Schedule string
+	Timezone string
+	Next     string
+	In       string
+	Last     string
+	Paused   bool
+	Running  bool
+	Runs     []taskRunView
+}
+
+type taskRunView struct {
+	Result string
+	Title  string
+}
+
+func toTaskStatuses(schedules []TaskSchedule, now time.Time) []taskStatus {
+	out := make([]taskStatus, 0, len(schedules))
+	for _, ts := range schedules {
+		t := taskStatus{
+			Name:     ts.Name,
+			Schedule: ts.Schedule,
+			Timezone: ts.Timezone,
+			Next:     "—",
+			Last:     "—",
+			Paused:   ts.Paused,
+			Running:  ts.Running,
+		}
+		if t.Timezone == "" {
+			t.Timezone = "local"
+		}
+		if !ts.Next.IsZero() {
+			t.Next = ts.Next.Format("2006-01-02 15:04 MST")
+			t.In = ts.Next.Sub(now).Round(time.Second).String()
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// addTaskRuns fills in a task's last run and recent outcomes, newest first.
+func (t *taskStatus) addTaskRuns(runs []TaskRun) {
+	for _, r := range runs {
+		title := r.Timestamp.Format("2006-01-02 15:04:05") + " · " + durationStr(r.DurationMs)
+		if r.Error != "" {
+			title += " · " + r.Error
+		}
+		t.Runs = append(t.Runs, taskRunView{Result: r.Result, Title: title})
+	}
+	if len(runs) > 0 {
+		t.Last = runs[0].Timestamp.Format("2006-01-02 15:04 MST")
+	}
+}
+
+// taskURL returns the API path for an action on a supervisor task.
+func taskURL(name, action string) string {
+	return "/api/supervisor/" + url.PathEscape(name) + "/" + action
+}
+
+// pluginURL returns the API path for an action or route under a plugin.
+func pluginURL(name, path string) string {
+	return "/api/plugins/" + url.PathEscape(name) + "/" + path
+}
+
+type heartbeatStatus struct {
+	Name     string
+	Watching string
+	Every    string
+	LastSeen string
+	Due      string
+	State    string
+	Health   string
+	Title    string
+}
+
+func toHeartbeatStatuses(beats []HeartbeatStatus, now time.Time) []heartbeatStatus {
+	out := make([]heartbeatStatus, 0, len(beats))
+	for _, b := range beats {
+		h := heartbeatStatus{
+			Name:     b.Name,
+			Watching: "ping",
+			Every:    b.Every,
+			LastSeen: "never",
+			Due:      b.Due.Format("2006-01-02 15:04 MST"),
+			State:    "ok",
+			Health:   "ok",
+		}
+		if b.Source != "" {
+			h.Watching = b.Source
+			if b.Event != "" {
+				h.Watching += "/" + b.Event
+			}
+		}
+		if !b.LastSeen.IsZero() {
+			h.LastSeen = b.LastSeen.Format("2006-01-02 15:04 MST")
+			h.Title = "seen " + now.Sub(b.LastSeen).Round(time.Second).String() + " ago"
+		}
+		if b.Missed {
+			h.State, h.Health = "missed", "error"
+			h.Title = "missed since " + b.MissedAt.Format("2006-01-02 15:04:05")
+		}
+		out = append(out, h)
+	}
+	return out
 }
 
 type pluginStatus struct {
-	Name    string
-	Types   string
+	Name     string
+	Types    string
+	Color    string
+	Health   string
+	Message  string
+	Settings []plugin.Setting // secrets masked
+	Actions  []plugin.Action
+	HTTP     string // outbound request summary; empty if the plugin made none
+	HTTPErr  string // last outbound error, shown as a tooltip
+}
+
+// httpSummary condenses a plugin's outbound request counts, e.g.
+// "42 req · 3 retried · 1 rate-limited · 2 failed · avg 310ms".
+func httpSummary(s outbound.Stats) string {
+	parts := []string{fmt.Sprintf("%d req", s.Requests)}
+	if s.Retries > 0 {
+		parts = append(parts, fmt.Sprintf("%d retried", s.Retries))
+	}
+	if s.RateLimited > 0 {
+		parts = append(parts, fmt.Sprintf("%d rate-limited", s.RateLimited))
+	}
+	if s.Failures > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed", s.Failures))
+	}
+	parts = append(parts, "avg "+s.AvgLatency().Round(time.Millisecond).String())
+	return strings.Join(parts, " · ")
+}
+
+// settingClass dims settings left at their default.
+func settingClass(s plugin.Setting) string {
+	if s.Default {
+		return "setting-default"
+	}
+	return ""
+}
+
+// actionSignature summarizes an action's params and the payload keys it
+// reads and writes, e.g. "limit int=10 · reads message · writes summary".
+func actionSignature(a plugin.Action) string {
+	var parts []string
+	for _, f := range a.Params {
+		p := f.Name + " " + string(f.Type)
+		if f.Required {
+			p += "!"
+		}
+		if f.Default != "" {
+			p += "=" + f.Default
+		}
+		parts = append(parts, p)
+	}
+	if len(a.Reads) > 0 {
+		parts = append(parts, "reads "+strings.Join(a.Reads, ", "))
+	}
+	if len(a.Writes) > 0 {
+		parts = append(parts, "writes "+strings.Join(a.Writes, ", "))
+	}
+	return strings.Join(parts, " · ")
+}
+
+type panelView struct {
+	Plugin  string
+	Title   string
 	Color   string
-	Health  string
-	Message string
+	URL     string
+	Trigger string // hx-trigger loading the panel's content
+}
+
+// buildPanelViews lists every plugin's dashboard panels in registration
+// order.
+func buildPanelViews(reg *plugin.Registry) []panelView {
+	var views []panelView
+	for _, p := range reg.All() {
+		for _, panel := range reg.Panels(p.Name) {
+			v := panelView{
+				Plugin:  p.Name,
+				Title:   panel.Title,
+				Color:   sourceColor(p.Name),
+				URL:     pluginURL(p.Name, "panels/"+url.PathEscape(panel.Name)),
+				Trigger: "load",
+			}
+			if panel.Refresh > 0 {
+				v.Trigger += fmt.Sprintf(", every %ds", max(1, int(panel.Refresh.Seconds())))
+			}
+			views = append(views, v)
+		}
+	}
+	return views
 }
 
 type routeStatus struct {
@@ -217,6 +422,14 @@ func buildStatusInfo(ctx context.Context, reg *plugin.Registry, routes []config.
 			ps.Health = string(hr.Status.Status)
 			ps.Message = hr.Status.Message
 		}
+		ps.Settings, _ = reg.Settings(p.Name)
+		ps.Actions = reg.Actions(p.Name)
+		if st, ok := reg.HTTPStats(p.Name); ok && st.Requests > 0 {
+			ps.HTTP = httpSummary(st)
+			if st.LastError != "" {
+				ps.HTTPErr = st.LastErrorAt.Format(time.DateTime) + ": " + st.LastError
+			}
+		}
 		info.Plugins = append(info.Plugins, ps)
 	}
 
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/boozedog/smoothbrain/internal/store"
 )
@@ -248,3 +249,55 @@ func TestToEventViews_Populated(t *testing.T) {
 		t.Errorf("PrettyPayload should contain 'main', got %q", views[0].PrettyPayload)
 	}
 }
+
+func TestToTaskStatuses(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	got := toTaskStatuses([]TaskSchedule{
+		{Name: "a", Schedule: "@hourly", Next: now.Add(90 * time.Second)},
+		{Name: "b", Schedule: "bad", Timezone: "UTC"},
+	}, now)
+	if got[0].Timezone != "local" || got[0].In != "1m30s" || got[0].Next != "2025-01-01 12:01 UTC" {
+		t.Errorf("task a = %+v", got[0])
+	}
+	if got[1].Next != "—" || got[1].In != "" {
+		t.Errorf("task b = %+v, want no next run", got[1])
+	}
+}
+
+func TestTaskStatus_AddTaskRuns(t *testing.T) {
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	ts := toTaskStatuses([]TaskSchedule{{Name: "a", Schedule: "@hourly", Paused: true}}, at)[0]
+	if ts.Last != "—" || !ts.Paused {
+		t.Errorf("task before runs = %+v", ts)
+	}
+	ts.addTaskRuns([]TaskRun{
+		{Timestamp: at, Result: "failed", DurationMs: 5, Error: "boom"},
+		{Timestamp: at.Add(-time.Hour), Result: "completed"},
+	})
+	if ts.Last != "2025-01-01 12:00 UTC" {
+		t.Errorf("Last = %q, want newest run", ts.Last)
+	}
+	if len(ts.Runs) != 2 || ts.Runs[0].Title != "2025-01-01 12:00:00 · 5ms · boom" {
+		t.Errorf("Runs = %+v", ts.Runs)
+	}
+}
+
+func TestTaskURL_Escapes(t *testing.T) {
+	if got := taskURL("a/b c", "run"); got != "/api/supervisor/a%2Fb%20c/run" {
+		t.Errorf("taskURL() = %q", got)
+	}
+}
+
+func TestToHeartbeatStatuses(t *testing.T) {
+	at := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	got := toHeartbeatStatuses([]HeartbeatStatus{
+		{Name: "backup", Every: "24h", Due: at},
+		{Name: "feed", Source: "miniflux", Event: "entry", Every: "1h", LastSeen: at.Add(-2 * time.Hour), Missed: true, MissedAt: at},
+	}, at)
+	if b := got[0]; b.Watching != "ping" || b.LastSeen != "never" || b.Health != "ok" {
+		t.Errorf("backup = %+v", b)
+	}
+	if f := got[1]; f.Watching != "miniflux/entry" || f.State != "missed" || f.Health != "error" || f.LastSeen != "2025-01-01 10:00 UTC" {
+		t.Errorf("feed = %+v", f)
+	}
+}
@@ -0,0 +1,382 @@
+// Package outbound is the HTTP layer plugins use to call external services.
+// Requests are rate limited per host, retried on 429 and 5xx responses
+// (honouring Retry-After), and refused by a per-plugin, per-host circuit
+// breaker after repeated failures.
+package outbound
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrCircuitOpen is returned, without sending the request, while the
+// breaker for the request's host is open.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// maxRetryAfter caps how long a Retry-After is honoured; a server asking for
+// a longer wait gets its response returned instead.
+const maxRetryAfter = time.Minute
+
+// retryBase is the first retry backoff, doubled on each attempt; tests
+// shorten it.
+var retryBase = 500 * time.Millisecond
+
+// Options configures a Pool.
+type Options struct {
+	RateLimits       map[string]float64 // requests per second by host; others are unlimited
+	MaxRetries       int
+	BreakerThreshold int           // consecutive failures that open a breaker
+	BreakerCooldown  time.Duration // how long a breaker stays open before a trial request
+}
+
+// DefaultOptions retries three times and opens a breaker after five
+// consecutive failures for 30 seconds.
+func DefaultOptions() Options {
+	return Options{MaxRetries: 3, BreakerThreshold: 5, BreakerCooldown: 30 * time.Second}
+}
+
+// Pool holds the per-host rate limits shared by every plugin and a
+// Transport for each plugin.
+type Pool struct {
+	opts Options
+	base http.RoundTripper
+	log  *slog.Logger
+
+	mu         sync.Mutex
+	hosts      map[string]*hostLimit
+	transports map[string]*Transport
+}
+
+func New(opts Options, log *slog.Logger) *Pool {
+	return &Pool{
+		opts:       opts,
+		base:       http.DefaultTransport,
+		log:        log,
+		hosts:      make(map[string]*hostLimit),
+		transports: make(map[string]*Transport),
+	}
+}
+
+// Transport returns the named plugin's transport, creating it on first use.
+func (p *Pool) Transport(name string) *Transport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t, ok := p.transports[name]
+	if !ok {
+		t = &Transport{pool: p, name: name, breakers: make(map[string]*breaker)}
+		p.transports[name] = t
+	}
+	return t
+}
+
+// Lookup returns the named plugin's transport if it has one.
+func (p *Pool) Lookup(name string) (*Transport, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t, ok := p.transports[name]
+	return t, ok
+}
+
+func (p *Pool) host(host string) *hostLimit {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h, ok := p.hosts[host]
+	if !ok {
+		h = &hostLimit{}
+		if rps := p.opts.RateLimits[host]; rps > 0 {
+			h.limiter = rate.NewLimiter(rate.Limit(rps), max(1, int(math.Ceil(rps))))
+		}
+		p.hosts[host] = h
+	}
+	return h
+}
+
+// hostLimit paces requests to one host: its configured rate, and any pause
+// a 429's Retry-After asked for.
+type hostLimit struct {
+	limiter *rate.Limiter // nil when unlimited
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+func (h *hostLimit) wait(req *http.Request) error {
+	h.mu.Lock()
+	d := time.Until(h.pausedUntil)
+	h.mu.Unlock()
+	if d > 0 {
+		if err := sleep(req, d); err != nil {
+			return err
+		}
+	}
+	if h.limiter == nil {
+		return nil
+	}
+	return h.limiter.Wait(req.Context())
+}
+
+func (h *hostLimit) pause(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if until := time.Now().Add(d); until.After(h.pausedUntil) {
+		h.pausedUntil = until
+	}
+}
+
+// Stats counts one plugin's outbound requests.
+type Stats struct {
+	Requests     int64 // attempts sent, including retries
+	Failures     int64 // network errors and 5xx responses
+	Retries      int64
+	RateLimited  int64 // 429 responses
+	TotalLatency time.Duration
+	LastError    string
+	LastErrorAt  time.Time
+}
+
+// AvgLatency is the mean time per attempt.
+func (s Stats) AvgLatency() time.Duration {
+	if s.Requests == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Requests)
+}
+
+// breaker opens after BreakerThreshold consecutive failures. Once the
+// cooldown has passed it lets one trial request through: success closes it,
+// failure opens it again.
+type breaker struct {
+	failures  int
+	openUntil time.Time // zero while closed
+	trial     bool      // a trial request is in flight
+}
+
+// Transport is one plugin's http.RoundTripper.
+type Transport struct {
+	pool *Pool
+	name string
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+	stats    Stats
+}
+
+// Stats returns a snapshot of the plugin's request counts.
+func (t *Transport) Stats() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// OpenHosts returns the hosts whose breaker is open or waiting on a trial
+// request.
+func (t *Transport) OpenHosts() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var hosts []string
+	for host, b := range t.breakers {
+		if !b.openUntil.IsZero() {
+			hosts = append(hosts, host)
+		}
+	}
+	slices.Sort(hosts)
+	return hosts
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if err := t.allow(host); err != nil {
+		return nil, err
+	}
+	limit := t.pool.host(host)
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			// A failed attempt may have opened the breaker.
+			if err := t.allow(host); err != nil {
+				return nil, err
+			}
+		}
+		if err := limit.wait(req); err != nil {
+			t.release(host)
+			return nil, err
+		}
+		r := req
+		if attempt > 0 {
+			r = req.Clone(req.Context())
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					t.release(host)
+					return nil, err
+				}
+				r.Body = body
+			}
+		}
+
+		start := time.Now()
+		resp, err := t.pool.base.RoundTrip(r)
+		t.record(host, resp, err, time.Since(start))
+
+		delay, retry := t.retryDelay(req, resp, err, attempt, replayable)
+		if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+			limit.pause(delay)
+		}
+		if !retry {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			_ = resp.Body.Close()
+		}
+		t.mu.Lock()
+		t.stats.Retries++
+		t.mu.Unlock()
+		t.pool.log.Debug("retrying outbound request", "plugin", t.name, "host", host, "attempt", attempt+1, "delay", delay)
+		if err := sleep(req, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// allow reports ErrCircuitOpen while host's breaker is open, and claims the
+// trial request once its cooldown has passed.
+func (t *Transport) allow(host string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := t.breaker(host)
+	if b.openUntil.IsZero() {
+		return nil
+	}
+	if b.trial || time.Now().Before(b.openUntil) {
+		return fmt.Errorf("%w for %s", ErrCircuitOpen, host)
+	}
+	b.trial = true
+	return nil
+}
+
+// release gives up a trial request that was never sent.
+func (t *Transport) release(host string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.breaker(host).trial = false
+}
+
+func (t *Transport) record(host string, resp *http.Response, err error, latency time.Duration) {
+	failed := err != nil || resp.StatusCode >= 500
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stats.Requests++
+	t.stats.TotalLatency += latency
+	if resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+		t.stats.RateLimited++
+	}
+
+	b := t.breaker(host)
+	if !failed {
+		b.failures, b.openUntil, b.trial = 0, time.Time{}, false
+		return
+	}
+	t.stats.Failures++
+	if err != nil {
+		t.stats.LastError = err.Error()
+	} else {
+		t.stats.LastError = fmt.Sprintf("%s: %s", host, resp.Status)
+	}
+	t.stats.LastErrorAt = time.Now()
+
+	b.failures++
+	if b.trial || b.failures >= t.pool.opts.BreakerThreshold {
+		b.openUntil = time.Now().Add(t.pool.opts.BreakerCooldown)
+		b.trial = false
+		t.pool.log.Warn("circuit breaker open", "plugin", t.name, "host", host, "failures", b.failures, "cooldown", t.pool.opts.BreakerCooldown)
+	}
+}
+
+// breaker returns host's breaker; t.mu must be held.
+func (t *Transport) breaker(host string) *breaker {
+	b, ok := t.breakers[host]
+	if !ok {
+		b = &breaker{}
+		t.breakers[host] = b
+	}
+	return b
+}
+
+// retryDelay decides whether an attempt is retried and after how long. A 429,
+// or a 503 with Retry-After, means the request was not processed, so any
+// replayable request is retried. Network errors and other 502, 503 and 504
+// responses may come after the request was acted on, so only idempotent
+// methods are retried.
+func (t *Transport) retryDelay(req *http.Request, resp *http.Response, err error, attempt int, replayable bool) (time.Duration, bool) {
+	idempotent := replayable && isIdempotent(req.Method)
+	if err != nil {
+		return backoff(attempt), idempotent && req.Context().Err() == nil && attempt < t.pool.opts.MaxRetries
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		d, ok := retryAfter(resp.Header)
+		if ok {
+			return d, replayable && d <= maxRetryAfter && attempt < t.pool.opts.MaxRetries
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return backoff(attempt), replayable && attempt < t.pool.opts.MaxRetries
+		}
+		return backoff(attempt), idempotent && attempt < t.pool.opts.MaxRetries
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return backoff(attempt), idempotent && attempt < t.pool.opts.MaxRetries
+	}
+	return 0, false
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+// backoff doubles retryBase per attempt, with jitter.
+func backoff(attempt int) time.Duration {
+	d := retryBase << attempt
+	return d/2 + rand.N(d/2+1)
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date.
+func retryAfter(h http.Header) (time.Duration, bool) {
+	v := h.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return max(0, time.Duration(secs)*time.Second), true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return max(0, time.Until(at)), true
+	}
+	return 0, false
+}
+
+func sleep(req *http.Request, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
@@ -0,0 +1,205 @@
+package outbound
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func init() {
+	retryBase = time.Millisecond
+}
+
+func newClient(opts Options) (*http.Client, *Transport) {
+	t := New(opts, slog.New(slog.DiscardHandler)).Transport("test")
+	return &http.Client{Transport: t}, t
+}
+
+func TestTransport_RetriesRateLimited(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "ping" {
+			t.Errorf("attempt %d body = %q, want ping", calls.Load()+1, body)
+		}
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("pong"))
+	}))
+	defer srv.Close()
+
+	client, tr := newClient(DefaultOptions())
+	resp, err := client.Post(srv.URL, "text/plain", strings.NewReader("ping"))
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+
+	s := tr.Stats()
+	if s.Requests != 2 || s.Retries != 1 || s.RateLimited != 1 || s.Failures != 0 {
+		t.Errorf("stats = %+v, want 2 requests, 1 retry, 1 rate-limited", s)
+	}
+}
+
+func TestTransport_NoRetryForLongRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client, _ := newClient(DefaultOptions())
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || calls.Load() != 1 {
+		t.Errorf("status %d after %d calls, want 503 after 1", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestTransport_DoesNotRetryPostOnServerErrors(t *testing.T) {
+	for _, status := range []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		var calls atomic.Int32
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls.Add(1)
+			w.WriteHeader(status)
+		}))
+
+		client, _ := newClient(DefaultOptions())
+		resp, err := client.Post(srv.URL, "text/plain", strings.NewReader("x"))
+		if err != nil {
+			t.Fatalf("Post: %v", err)
+		}
+		_ = resp.Body.Close()
+		if calls.Load() != 1 {
+			t.Errorf("%d: calls = %d, want 1", status, calls.Load())
+		}
+
+		resp, err = client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		_ = resp.Body.Close()
+		if calls.Load() != 1+4 {
+			t.Errorf("%d: calls = %d, want GET retried 3 times", status, calls.Load())
+		}
+		srv.Close()
+	}
+}
+
+func TestTransport_RetriesPostOn503WithRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	client, _ := newClient(DefaultOptions())
+	resp, err := client.Post(srv.URL, "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatalf("Post: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls.Load() != 2 {
+		t.Errorf("status %d after %d calls, want 200 after 2", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestTransport_CircuitBreaker(t *testing.T) {
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	client, tr := newClient(Options{MaxRetries: 0, BreakerThreshold: 2, BreakerCooldown: 50 * time.Millisecond})
+	for range 2 {
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		_ = resp.Body.Close()
+	}
+	if hosts := tr.OpenHosts(); len(hosts) != 1 {
+		t.Fatalf("OpenHosts = %v, want the test server", hosts)
+	}
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Get with open breaker: err = %v, want ErrCircuitOpen", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	healthy.Store(true)
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("trial Get: %v", err)
+	}
+	_ = resp.Body.Close()
+	if hosts := tr.OpenHosts(); len(hosts) != 0 {
+		t.Errorf("OpenHosts = %v after successful trial, want none", hosts)
+	}
+	if s := tr.Stats(); s.Failures != 2 || s.LastError == "" {
+		t.Errorf("stats = %+v, want 2 failures with a last error", s)
+	}
+}
+
+func TestTransport_RetriesStopWhenBreakerOpens(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	client, _ := newClient(Options{MaxRetries: 3, BreakerThreshold: 2, BreakerCooldown: time.Minute})
+	if _, err := client.Get(srv.URL); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Get: err = %v, want ErrCircuitOpen", err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want retries to stop once the breaker opened after 2", calls.Load())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		want   time.Duration
+		ok     bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, true},
+	} {
+		h := http.Header{}
+		if tc.header != "" {
+			h.Set("Retry-After", tc.header)
+		}
+		got, ok := retryAfter(h)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v, %v", tc.header, got, ok, tc.want, tc.ok)
+		}
+	}
+}
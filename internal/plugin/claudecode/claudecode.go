@@ -11,21 +11,36 @@ import (
 	"github.com/boozedog/smoothbrain/internal/plugin"
 )
 
+func init() {
+	plugin.RegisterType("claudecode", func(log *slog.Logger) plugin.Plugin { return New(log) })
+}
+
 type Config struct {
 	Binary string `json:"binary,omitempty"` // path to claude binary, default "claude"
 	Model  string `json:"model,omitempty"`
 }
 
+func (p *Plugin) ConfigSchema() []plugin.ConfigField {
+	return []plugin.ConfigField{
+		{Name: "binary", Type: plugin.FieldString, Default: "claude", Description: "Path to the claude CLI"},
+		{Name: "model", Type: plugin.FieldString, Description: "Model passed to --model"},
+	}
+}
+
 type Plugin struct {
-	cfg Config
-	log *slog.Logger
+	name string
+	cfg  Config
+	log  *slog.Logger
 }
 
 func New(log *slog.Logger) *Plugin {
-	return &Plugin{log: log}
+	return &Plugin{name: "claudecode", log: log}
 }
 
-func (p *Plugin) Name() string { return "claudecode" }
+func (p *Plugin) Name() string { return p.name }
+
+// SetName sets the instance name.
+func (p *Plugin) SetName(name string) { p.name = name }
 
 func (p *Plugin) Init(cfg json.RawMessage) error {
 	if cfg != nil {
@@ -42,6 +57,20 @@ func (p *Plugin) Start(ctx context.Context, bus plugin.EventBus) error {
 
 func (p *Plugin) Stop() error { return nil }
 
+func (p *Plugin) Actions() []plugin.Action {
+	return []plugin.Action{
+		{
+			Name:        "ask",
+			Description: "Runs the message as a Claude Code prompt and returns the answer as summary",
+			Params: []plugin.ConfigField{
+				{Name: "system_prompt", Type: plugin.FieldString, Description: "Passed as --system-prompt"},
+			},
+			Reads:  []string{"message"},
+			Writes: []string{"summary"},
+		},
+	}
+}
+
 func (p *Plugin) Transform(ctx context.Context, event plugin.Event, action string, params map[string]any) (plugin.Event, error) {
 	switch action {
 	case "ask":
@@ -0,0 +1,373 @@
+// Package exec hosts plugins that run as separate processes and speak
+// JSON-RPC over stdin/stdout. See exec.md for the protocol.
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/boozedog/smoothbrain/internal/plugin"
+	"github.com/google/uuid"
+)
+
+func init() {
+	plugin.RegisterType("exec", func(log *slog.Logger) plugin.Plugin { return New(log) })
+}
+
+const (
+	defaultTimeout = 30 * time.Second
+	// stopGrace is how long a process gets to exit after Stop before it is
+	// killed.
+	stopGrace = 5 * time.Second
+	// maxRestartDelay caps the backoff between restarts. A process that ran
+	// at least this long before crashing restarts after minRestartDelay.
+	maxRestartDelay = time.Minute
+)
+
+// minRestartDelay is the first backoff after a crash; tests shorten it.
+var minRestartDelay = time.Second
+
+type Config struct {
+	Command string            `json:"command"`
+	Args    []string          `json:"args"`
+	Env     map[string]string `json:"env"`
+	Dir     string            `json:"dir"`
+	Timeout string            `json:"timeout"` // per call, Go duration, default 30s
+	// Config is passed to the process's Init call.
+	Config json.RawMessage `json:"config"`
+}
+
+func (p *Plugin) ConfigSchema() []plugin.ConfigField {
+	return []plugin.ConfigField{
+		{Name: "command", Type: plugin.FieldString, Required: true, Description: "Executable to run"},
+		{Name: "args", Type: plugin.FieldStrings},
+		{Name: "env", Type: plugin.FieldMap, Secret: true, Description: "Extra environment variables"},
+		{Name: "dir", Type: plugin.FieldString, Description: "Working directory"},
+		{Name: "timeout", Type: plugin.FieldDuration, Default: "30s", Description: "Timeout for each call"},
+		{Name: "config", Type: plugin.FieldJSON, Secret: true, Description: "Passed to the process in Init"},
+	}
+}
+
+type Plugin struct {
+	name    string
+	cfg     Config
+	timeout time.Duration
+	baseLog *slog.Logger
+	log     *slog.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu        sync.Mutex
+	actions   []plugin.Action
+	bus       plugin.EventBus
+	proc      *process
+	startedAt time.Time
+	restarts  int
+	lastErr   string
+}
+
+func New(log *slog.Logger) *Plugin {
+	return &Plugin{name: "exec", baseLog: log, log: log}
+}
+
+func (p *Plugin) Name() string { return p.name }
+
+// SetName sets the instance name, used as the default source of the events
+// the process emits.
+func (p *Plugin) SetName(name string) { p.name = name }
+
+// Init starts the process and sends it Init with the "config" block. A
+// process that fails to start or rejects its config fails Init.
+func (p *Plugin) Init(cfg json.RawMessage) error {
+	if err := json.Unmarshal(cfg, &p.cfg); err != nil {
+		return fmt.Errorf("exec config: %w", err)
+	}
+	if p.cfg.Command == "" {
+		return fmt.Errorf("exec %s: command must not be empty", p.name)
+	}
+	p.timeout = defaultTimeout
+	if p.cfg.Timeout != "" {
+		d, err := time.ParseDuration(p.cfg.Timeout)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("exec %s: timeout %q must be a positive duration", p.name, p.cfg.Timeout)
+		}
+		p.timeout = d
+	}
+	p.log = p.baseLog.With("plugin", p.name)
+	if err := p.launch(context.Background(), false); err != nil {
+		return err
+	}
+
+	// Actions is optional; a process without it accepts any action.
+	var res struct {
+		Actions []plugin.Action `json:"actions"`
+	}
+	if err := p.call(context.Background(), "Actions", nil, &res); err != nil && !isMethodNotFound(err) {
+		p.mu.Lock()
+		proc := p.proc
+		p.proc = nil
+		p.mu.Unlock()
+		proc.close(stopGrace)
+		return err
+	}
+	p.mu.Lock()
+	p.actions = res.Actions
+	p.mu.Unlock()
+	return nil
+}
+
+// Actions returns the actions the process declared at Init.
+func (p *Plugin) Actions() []plugin.Action {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.actions
+}
+
+// Start sends Start and begins supervising the process, restarting it with
+// backoff whenever it exits. A process that has exited since Init, or since
+// a failed Start, is launched again first, so the registry's Start retries
+// can recover it.
+func (p *Plugin) Start(ctx context.Context, bus plugin.EventBus) error {
+	p.mu.Lock()
+	p.bus = bus
+	p.mu.Unlock()
+	if proc := p.current(); proc == nil || proc.exited() {
+		if err := p.launch(ctx, true); err != nil {
+			return err
+		}
+	} else if err := p.call(ctx, "Start", nil, nil); err != nil {
+		return err
+	}
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.wg.Add(1)
+	go p.supervise(ctx)
+	return nil
+}
+
+func (p *Plugin) Stop() error {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+
+	proc := p.current()
+	if proc == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), min(p.timeout, stopGrace))
+	err := proc.call(ctx, "Stop", nil, nil)
+	cancel()
+	if err != nil && !isMethodNotFound(err) && !errors.Is(err, errProcessExited) {
+		p.log.Warn("exec plugin stop failed", "error", err)
+	}
+	proc.close(stopGrace)
+	return nil
+}
+
+// launch spawns a process and initializes it; started also sends Start, for
+// restarts.
+func (p *Plugin) launch(ctx context.Context, started bool) error {
+	proc, err := spawn(p.cfg, p.log, p.handleNotification)
+	if err != nil {
+		return fmt.Errorf("exec %s: start %s: %w", p.name, p.cfg.Command, err)
+	}
+	p.mu.Lock()
+	p.proc = proc
+	p.startedAt = time.Now()
+	p.mu.Unlock()
+
+	params := map[string]any{"name": p.name, "config": p.cfg.Config}
+	err = p.call(ctx, "Init", params, nil)
+	if err == nil && started {
+		err = p.call(ctx, "Start", nil, nil)
+	}
+	if err != nil {
+		proc.close(stopGrace)
+		return err
+	}
+	p.log.Info("exec plugin process started", "command", p.cfg.Command, "pid", proc.cmd.Process.Pid)
+	return nil
+}
+
+func (p *Plugin) supervise(ctx context.Context) {
+	defer p.wg.Done()
+	delay := minRestartDelay
+	for {
+		proc := p.current()
+		select {
+		case <-ctx.Done():
+			return
+		case <-proc.done:
+		}
+		if ctx.Err() != nil {
+			return
+		}
+
+		p.mu.Lock()
+		ranFor := time.Since(p.startedAt)
+		p.lastErr = proc.exitErr().Error()
+		p.mu.Unlock()
+		p.log.Error("exec plugin process exited", "error", proc.exitErr(), "ran_for", ranFor.Round(time.Millisecond))
+		if ranFor >= maxRestartDelay {
+			delay = minRestartDelay
+		}
+
+		for {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			delay = min(delay*2, maxRestartDelay)
+
+			err := p.launch(ctx, true)
+			p.mu.Lock()
+			p.restarts++
+			if err != nil {
+				p.lastErr = err.Error()
+			}
+			p.mu.Unlock()
+			if err == nil {
+				break
+			}
+			p.log.Error("exec plugin restart failed", "error", err, "retry_in", delay)
+		}
+	}
+}
+
+func (p *Plugin) current() *process {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.proc
+}
+
+// call sends a request to the current process with the per-call timeout.
+func (p *Plugin) call(ctx context.Context, method string, params, result any) error {
+	proc := p.current()
+	if proc == nil {
+		return fmt.Errorf("exec %s: not running", p.name)
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	err := proc.call(ctx, method, params, result)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("exec %s: %s timed out after %s", p.name, method, p.timeout)
+	default:
+		return fmt.Errorf("exec %s: %s: %w", p.name, method, err)
+	}
+}
+
+// Transform sends the event to the process. Fields in the returned event
+// replace the original's; a result without a payload keeps the original.
+func (p *Plugin) Transform(ctx context.Context, event plugin.Event, action string, params map[string]any) (plugin.Event, error) {
+	var res struct {
+		Event plugin.Event `json:"event"`
+	}
+	res.Event = event
+	res.Event.Payload = nil
+	err := p.call(ctx, "Transform", map[string]any{"event": event, "action": action, "params": params}, &res)
+	if err != nil {
+		return event, err
+	}
+	if res.Event.Payload == nil {
+		res.Event.Payload = event.Payload
+	}
+	return res.Event, nil
+}
+
+func (p *Plugin) HandleEvent(ctx context.Context, event plugin.Event) error {
+	return p.call(ctx, "HandleEvent", map[string]any{"event": event}, nil)
+}
+
+// HealthCheck reports the process's own HealthCheck result, or ok if it does
+// not implement one. A process that is down is an error.
+func (p *Plugin) HealthCheck(ctx context.Context) plugin.HealthStatus {
+	proc := p.current()
+	if proc == nil || proc.exited() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return plugin.HealthStatus{
+			Status:  plugin.StatusError,
+			Message: fmt.Sprintf("not running (%d restarts): %s", p.restarts, p.lastErr),
+		}
+	}
+	var res plugin.HealthStatus
+	err := proc.call(ctx, "HealthCheck", nil, &res)
+	switch {
+	case isMethodNotFound(err):
+		return plugin.HealthStatus{Status: plugin.StatusOK, Message: "running"}
+	case err != nil:
+		return plugin.HealthStatus{Status: plugin.StatusError, Message: err.Error()}
+	}
+	if res.Status == "" {
+		res.Status = plugin.StatusOK
+	}
+	return res
+}
+
+func isMethodNotFound(err error) bool {
+	var rpcErr *rpcError
+	return errors.As(err, &rpcErr) && rpcErr.Code == codeMethodNotFound
+}
+
+// handleNotification handles Emit and Log notifications from the process.
+func (p *Plugin) handleNotification(method string, params json.RawMessage) {
+	switch method {
+	case "Emit":
+		var n struct {
+			Event plugin.Event `json:"event"`
+		}
+		if err := json.Unmarshal(params, &n); err != nil {
+			p.log.Warn("exec plugin sent invalid Emit", "error", err)
+			return
+		}
+		p.mu.Lock()
+		bus := p.bus
+		p.mu.Unlock()
+		if bus == nil {
+			p.log.Warn("exec plugin emitted an event before Start, dropping it", "type", n.Event.Type)
+			return
+		}
+		e := n.Event
+		if e.ID == "" {
+			e.ID = uuid.NewString()
+		}
+		if e.Source == "" {
+			e.Source = p.name
+		}
+		if e.Timestamp.IsZero() {
+			e.Timestamp = time.Now()
+		}
+		if e.Payload == nil {
+			e.Payload = map[string]any{}
+		}
+		bus.Emit(e)
+	case "Log":
+		var n struct {
+			Level   string `json:"level"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(params, &n); err != nil {
+			p.log.Warn("exec plugin sent invalid Log", "error", err)
+			return
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(n.Level)); err != nil {
+			level = slog.LevelInfo
+		}
+		p.log.Log(context.Background(), level, n.Message)
+	default:
+		p.log.Warn("exec plugin sent unknown notification", "method", method)
+	}
+}
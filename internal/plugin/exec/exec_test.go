@@ -0,0 +1,260 @@
+package exec
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boozedog/smoothbrain/internal/plugin"
+)
+
+// TestHelperProcess is the fake plugin process. It only runs when started by
+// the tests below.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("SMOOTHBRAIN_EXEC_HELPER") != "1" {
+		return
+	}
+	in := bufio.NewScanner(os.Stdin)
+	out := json.NewEncoder(os.Stdout)
+	var greeting string
+	for in.Scan() {
+		var req struct {
+			ID     int64           `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(in.Bytes(), &req); err != nil {
+			os.Exit(2)
+		}
+		reply := func(result any) {
+			_ = out.Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		}
+		switch req.Method {
+		case "Init":
+			var p struct {
+				Config struct {
+					Greeting string `json:"greeting"`
+				} `json:"config"`
+			}
+			_ = json.Unmarshal(req.Params, &p)
+			greeting = p.Config.Greeting
+			if p.Config.Greeting == "" {
+				_ = out.Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "error": map[string]any{"code": 1, "message": "greeting required"}})
+				continue
+			}
+			reply(map[string]any{})
+		case "Start":
+			_ = out.Encode(map[string]any{"jsonrpc": "2.0", "method": "Log", "params": map[string]any{"level": "debug", "message": "starting"}})
+			_ = out.Encode(map[string]any{"jsonrpc": "2.0", "method": "Emit", "params": map[string]any{"event": map[string]any{"type": "started", "payload": map[string]any{"pid": os.Getpid()}}}})
+			reply(map[string]any{})
+		case "Transform":
+			var p struct {
+				Event  plugin.Event `json:"event"`
+				Action string       `json:"action"`
+			}
+			_ = json.Unmarshal(req.Params, &p)
+			if p.Action == "slow" {
+				time.Sleep(2 * time.Second)
+			}
+			msg, _ := p.Event.Payload["message"].(string)
+			reply(map[string]any{"event": map[string]any{"payload": map[string]any{"message": strings.ToUpper(msg)}}})
+		case "HandleEvent":
+			os.Exit(3) // crash, to exercise restarts
+		case "Actions":
+			if greeting == "no actions" {
+				_ = out.Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "error": map[string]any{"code": 1, "message": "actions unavailable"}})
+				continue
+			}
+			reply(map[string]any{"actions": []map[string]string{{"name": "shout", "description": "Uppercases the message"}, {"name": "slow"}}})
+		case "HealthCheck":
+			reply(map[string]any{"status": "degraded", "message": "fine-ish"})
+		default:
+			_ = out.Encode(map[string]any{"jsonrpc": "2.0", "id": req.ID, "error": map[string]any{"code": codeMethodNotFound, "message": "method not found"}})
+		}
+	}
+	os.Exit(0)
+}
+
+type captureBus struct {
+	mu     sync.Mutex
+	events []plugin.Event
+}
+
+func (b *captureBus) Emit(e plugin.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, e)
+}
+
+func (b *captureBus) count(typ string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := 0
+	for _, e := range b.events {
+		if e.Type == typ {
+			n++
+		}
+	}
+	return n
+}
+
+func helperConfig(extra string) json.RawMessage {
+	exe, _ := json.Marshal(os.Args[0])
+	return json.RawMessage(fmt.Sprintf(`{
+		"command": %s,
+		"args": ["-test.run=TestHelperProcess"],
+		"env": {"SMOOTHBRAIN_EXEC_HELPER": "1"},
+		"config": {"greeting": "hi"}%s
+	}`, exe, extra))
+}
+
+func startHelper(t *testing.T, extra string) (*Plugin, *captureBus) {
+	t.Helper()
+	p := New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.SetName("exec:test")
+	if err := p.Init(helperConfig(extra)); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	bus := &captureBus{}
+	if err := p.Start(context.Background(), bus); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { _ = p.Stop() })
+	return p, bus
+}
+
+func TestPlugin_Lifecycle(t *testing.T) {
+	p, bus := startHelper(t, "")
+
+	if bus.count("started") != 1 {
+		t.Fatalf("emitted events = %+v, want one started", bus.events)
+	}
+	if e := bus.events[0]; e.Source != "exec:test" || e.ID == "" || e.Timestamp.IsZero() {
+		t.Errorf("emitted event = %+v, want defaults filled in", e)
+	}
+
+	in := plugin.Event{ID: "e1", Source: "mattermost", Type: "x", Payload: map[string]any{"message": "hello"}}
+	out, err := p.Transform(context.Background(), in, "shout", nil)
+	if err != nil {
+		t.Fatalf("Transform() error = %v", err)
+	}
+	if out.ID != "e1" || out.Source != "mattermost" || out.Payload["message"] != "HELLO" {
+		t.Errorf("Transform() = %+v", out)
+	}
+
+	if a := p.Actions(); len(a) != 2 || a[0].Name != "shout" || a[0].Description == "" {
+		t.Errorf("Actions() = %+v", a)
+	}
+
+	if h := p.HealthCheck(context.Background()); h.Status != plugin.StatusDegraded || h.Message != "fine-ish" {
+		t.Errorf("HealthCheck() = %+v", h)
+	}
+}
+
+func TestPlugin_InitError(t *testing.T) {
+	p := New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	cfg := strings.Replace(string(helperConfig("")), `"greeting": "hi"`, `"greeting": ""`, 1)
+	err := p.Init(json.RawMessage(cfg))
+	if err == nil || !strings.Contains(err.Error(), "greeting required") {
+		t.Errorf("Init() error = %v, want the process's error", err)
+	}
+	if err := p.Init(json.RawMessage(`{}`)); err == nil {
+		t.Error("Init() without command succeeded")
+	}
+}
+
+func TestPlugin_InitActionsErrorStopsProcess(t *testing.T) {
+	p := New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	cfg := strings.Replace(string(helperConfig("")), `"greeting": "hi"`, `"greeting": "no actions"`, 1)
+	err := p.Init(json.RawMessage(cfg))
+	if err == nil || !strings.Contains(err.Error(), "actions unavailable") {
+		t.Errorf("Init() error = %v, want the Actions error", err)
+	}
+	if p.current() != nil {
+		t.Error("process left running after Init failed")
+	}
+}
+
+func TestPlugin_StartRelaunchesProcessThatExitedAfterInit(t *testing.T) {
+	p := New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := p.Init(helperConfig("")); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	first := p.current()
+	_ = first.cmd.Process.Kill()
+	<-first.done
+
+	bus := &captureBus{}
+	if err := p.Start(context.Background(), bus); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	t.Cleanup(func() { _ = p.Stop() })
+	if p.current() == first || bus.count("started") != 1 {
+		t.Errorf("Start() did not relaunch the process (started events = %d)", bus.count("started"))
+	}
+}
+
+func TestPlugin_CallTimeout(t *testing.T) {
+	p, _ := startHelper(t, `, "timeout": "100ms"`)
+	_, err := p.Transform(context.Background(), plugin.Event{Payload: map[string]any{}}, "slow", nil)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("Transform() error = %v, want timeout", err)
+	}
+}
+
+func TestPlugin_RestartsAfterCrash(t *testing.T) {
+	minRestartDelay = 10 * time.Millisecond
+	t.Cleanup(func() { minRestartDelay = time.Second })
+	p, bus := startHelper(t, "")
+
+	if err := p.HandleEvent(context.Background(), plugin.Event{}); err == nil {
+		t.Fatal("HandleEvent() on crashing process succeeded")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for bus.count("started") < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("process was not restarted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	out, err := p.Transform(context.Background(), plugin.Event{Payload: map[string]any{"message": "again"}}, "shout", nil)
+	if err != nil || out.Payload["message"] != "AGAIN" {
+		t.Errorf("Transform() after restart = %+v, %v", out, err)
+	}
+	p.mu.Lock()
+	restarts := p.restarts
+	p.mu.Unlock()
+	if restarts != 1 {
+		t.Errorf("restarts = %d, want 1", restarts)
+	}
+}
+
+func TestProcess_CallKillsProcessNotReadingStdin(t *testing.T) {
+	proc, err := spawn(Config{Command: "sleep", Args: []string{"30"}}, slog.New(slog.NewTextHandler(io.Discard, nil)), func(string, json.RawMessage) {})
+	if err != nil {
+		t.Skipf("spawn sleep: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// Larger than a pipe buffer, so the write blocks.
+	err = proc.call(ctx, "Transform", strings.Repeat("x", 1<<20), nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("call() error = %v, want deadline exceeded", err)
+	}
+	select {
+	case <-proc.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("process was not killed")
+	}
+}
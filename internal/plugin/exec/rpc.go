@@ -0,0 +1,264 @@
+package exec
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	osexec "os/exec"
+	"sync"
+	"time"
+)
+
+// maxMessageSize bounds a single JSON-RPC line read from the process.
+const maxMessageSize = 16 << 20
+
+// JSON-RPC error code for a method the process does not implement.
+const codeMethodNotFound = -32601
+
+var errProcessExited = errors.New("process exited")
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int64  `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// rpcMessage is a line from the process: a response to a request (ID set)
+// or a notification (Method set).
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// process is one running plugin executable and its JSON-RPC connection.
+type process struct {
+	cmd    *osexec.Cmd
+	stdin  io.WriteCloser
+	log    *slog.Logger
+	notify func(method string, params json.RawMessage)
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan rpcMessage
+
+	stderrDone chan struct{}
+	done       chan struct{} // closed once the process has exited
+	err        error         // why it exited; set before done is closed
+}
+
+// spawn starts the configured executable. notify receives the process's
+// notifications from the read loop, so it must not block for long.
+func spawn(cfg Config, log *slog.Logger, notify func(string, json.RawMessage)) (*process, error) {
+	cmd := osexec.Command(cfg.Command, cfg.Args...)
+	cmd.Dir = cfg.Dir
+	cmd.Env = os.Environ()
+	for k, v := range cfg.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	p := &process{
+		cmd:        cmd,
+		stdin:      stdin,
+		log:        log,
+		notify:     notify,
+		pending:    make(map[int64]chan rpcMessage),
+		stderrDone: make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go p.logStderr(stderr)
+	go p.readLoop(stdout)
+	return p, nil
+}
+
+func (p *process) logStderr(r io.Reader) {
+	defer close(p.stderrDone)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		p.log.Info("exec plugin stderr", "line", sc.Text())
+	}
+}
+
+// readLoop dispatches responses and notifications until stdout closes, then
+// reaps the process and fails any calls still waiting.
+func (p *process) readLoop(r io.Reader) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for sc.Scan() {
+		var msg rpcMessage
+		if err := json.Unmarshal(sc.Bytes(), &msg); err != nil {
+			p.log.Warn("exec plugin sent invalid JSON", "error", err)
+			continue
+		}
+		switch {
+		case msg.ID != nil:
+			p.mu.Lock()
+			ch, ok := p.pending[*msg.ID]
+			delete(p.pending, *msg.ID)
+			p.mu.Unlock()
+			if ok {
+				ch <- msg
+			}
+		case msg.Method != "":
+			p.notify(msg.Method, msg.Params)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		p.log.Warn("exec plugin output error, killing process", "error", err)
+		_ = p.cmd.Process.Kill()
+	}
+
+	<-p.stderrDone // Wait closes the pipes, so drain stderr first
+	err := p.cmd.Wait()
+	if err == nil {
+		err = errProcessExited
+	}
+	p.mu.Lock()
+	p.err = err
+	pending := p.pending
+	p.pending = nil
+	p.mu.Unlock()
+	close(p.done)
+	for _, ch := range pending {
+		close(ch)
+	}
+}
+
+// call sends a request and decodes its result into result, if non-nil.
+func (p *process) call(ctx context.Context, method string, params, result any) error {
+	ch := make(chan rpcMessage, 1)
+	p.mu.Lock()
+	if p.pending == nil {
+		p.mu.Unlock()
+		return p.exitErr()
+	}
+	p.nextID++
+	id := p.nextID
+	p.pending[id] = ch
+	p.mu.Unlock()
+
+	line, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		p.forget(id)
+		return err
+	}
+	if err := p.write(ctx, append(line, '\n')); err != nil {
+		p.forget(id)
+		return err
+	}
+
+	select {
+	case msg, ok := <-ch:
+		if !ok {
+			return p.exitErr()
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil && len(msg.Result) > 0 {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				return fmt.Errorf("decode %s result: %w", method, err)
+			}
+		}
+		return nil
+	case <-ctx.Done():
+		p.forget(id)
+		return ctx.Err()
+	}
+}
+
+// write sends line to the process. A process that stops reading its stdin
+// would block the write forever, so if ctx ends first the process is killed;
+// the write then fails and a new process is spawned.
+func (p *process) write(ctx context.Context, line []byte) error {
+	errc := make(chan error, 1)
+	go func() {
+		p.writeMu.Lock()
+		defer p.writeMu.Unlock()
+		_, err := p.stdin.Write(line)
+		errc <- err
+	}()
+	select {
+	case err := <-errc:
+		if err != nil {
+			return fmt.Errorf("write request: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		p.log.Warn("exec plugin not reading requests, killing process")
+		_ = p.cmd.Process.Kill()
+		return fmt.Errorf("write request: %w", ctx.Err())
+	}
+}
+
+func (p *process) forget(id int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.pending, id)
+}
+
+func (p *process) exitErr() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if errors.Is(p.err, errProcessExited) {
+		return p.err
+	}
+	return fmt.Errorf("%w: %v", errProcessExited, p.err)
+}
+
+// exited reports whether the process has exited.
+func (p *process) exited() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// close asks the process to exit by closing its stdin and kills it if it is
+// still running after grace.
+func (p *process) close(grace time.Duration) {
+	_ = p.stdin.Close()
+	select {
+	case <-p.done:
+	case <-time.After(grace):
+		_ = p.cmd.Process.Kill()
+		<-p.done
+	}
+}
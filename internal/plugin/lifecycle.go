@@ -0,0 +1,228 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// ErrUnknownPlugin is returned for a plugin name that is not registered.
+var ErrUnknownPlugin = errors.New("unknown plugin")
+
+// maxStartDelay caps the backoff between attempts to start a plugin whose
+// Start fails.
+const maxStartDelay = 5 * time.Minute
+
+// minStartDelay is the first retry delay after a failed Start; tests shorten
+// it.
+var minStartDelay = time.Second
+
+// runState tracks one plugin's lifecycle: the context it was started with
+// and, while Start keeps failing, the retry loop.
+type runState struct {
+	cancel   context.CancelFunc // cancels the plugin's context and any retries
+	done     chan struct{}      // closed once started or retrying has stopped
+	started  bool
+	err      string // why the plugin is not running
+	attempts int    // failed Start attempts since the last (re)start
+}
+
+// StartAll starts every plugin with its own context, so a single plugin can
+// be stopped and restarted later. A plugin whose Start fails is reported as
+// an error by CheckHealth and retried with backoff until it succeeds or the
+// registry is stopped.
+func (r *Registry) StartAll(ctx context.Context, bus EventBus) {
+	r.mu.Lock()
+	r.ctx, r.bus = ctx, bus
+	order := append([]Plugin(nil), r.order...)
+	r.mu.Unlock()
+	for _, p := range order {
+		_ = r.start(p)
+	}
+}
+
+// Restart stops the named plugin, initializes it again with its config and
+// starts it. A Start error is returned but, as at startup, retried in the
+// background. Routed Transform and HandleEvent calls already under way finish
+// first; new ones wait until the plugin has been started again.
+func (r *Registry) Restart(name string) error {
+	p, ok := r.Get(name)
+	if !ok {
+		return ErrUnknownPlugin
+	}
+	r.mu.RLock()
+	ctx, cfg := r.ctx, r.configs[name]
+	r.mu.RUnlock()
+	if ctx == nil {
+		return errors.New("plugins not started")
+	}
+	if cfg == nil {
+		cfg = []byte("{}")
+	}
+
+	r.restartMu.Lock()
+	defer r.restartMu.Unlock()
+	r.mu.RLock()
+	gate := r.gates[name]
+	r.mu.RUnlock()
+	gate.Lock()
+	defer gate.Unlock()
+	r.log.Info("restarting plugin", "plugin", name)
+	r.stop(p)
+	if err := r.protect(name, "Init", func() error { return p.Init(cfg) }); err != nil {
+		r.setRunState(name, &runState{cancel: func() {}, done: closedChan(), err: "init failed: " + err.Error()})
+		return fmt.Errorf("init plugin %s: %w", name, err)
+	}
+	return r.start(p)
+}
+
+// start starts p, or begins retrying it in the background if Start fails.
+func (r *Registry) start(p Plugin) error {
+	name := p.Name()
+	r.mu.RLock()
+	parent, bus := r.ctx, r.bus
+	r.mu.RUnlock()
+	ctx, cancel := context.WithCancel(parent)
+	st := &runState{cancel: cancel, done: make(chan struct{})}
+	r.setRunState(name, st)
+
+	err := r.tryStart(ctx, p, bus, st)
+	if err == nil {
+		close(st.done)
+		return nil
+	}
+	go r.retryStart(ctx, p, bus, st)
+	return fmt.Errorf("start plugin %s: %w", name, err)
+}
+
+func (r *Registry) tryStart(ctx context.Context, p Plugin, bus EventBus, st *runState) error {
+	name := p.Name()
+	err := r.protect(name, "Start", func() error { return p.Start(ctx, bus) })
+	r.runMu.Lock()
+	if err == nil {
+		st.started, st.err = true, ""
+	} else {
+		st.attempts++
+		st.err = fmt.Sprintf("start failed (attempt %d): %v", st.attempts, err)
+	}
+	attempts := st.attempts
+	r.runMu.Unlock()
+
+	if err != nil {
+		r.log.Error("plugin start failed", "plugin", name, "attempt", attempts, "error", err)
+		return err
+	}
+	r.log.Info("plugin started", "plugin", name)
+	return nil
+}
+
+func (r *Registry) retryStart(ctx context.Context, p Plugin, bus EventBus, st *runState) {
+	defer close(st.done)
+	delay := minStartDelay
+	for {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if r.tryStart(ctx, p, bus, st) == nil {
+			return
+		}
+		delay = min(delay*2, maxStartDelay)
+	}
+}
+
+// stop cancels p's context and any Start retries, then calls its Stop.
+func (r *Registry) stop(p Plugin) {
+	name := p.Name()
+	r.runMu.Lock()
+	st := r.runs[name]
+	r.runMu.Unlock()
+	if st != nil {
+		st.cancel()
+		<-st.done
+	}
+	if err := r.protect(name, "Stop", p.Stop); err != nil {
+		r.log.Error("plugin stop error", "plugin", name, "error", err)
+	} else {
+		r.log.Info("plugin stopped", "plugin", name)
+	}
+}
+
+func (r *Registry) setRunState(name string, st *runState) {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+	r.runs[name] = st
+}
+
+// notRunning returns the error health for a plugin that failed to start, if
+// it has.
+func (r *Registry) notRunning(name string) (HealthStatus, bool) {
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+	st, ok := r.runs[name]
+	if !ok || st.started {
+		return HealthStatus{}, false
+	}
+	return HealthStatus{Status: StatusError, Message: st.err}, true
+}
+
+// protect calls fn, turning a panic into an error so one misbehaving plugin
+// cannot take the process down.
+func (r *Registry) protect(name, method string, fn func() error) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			r.log.Error("plugin panicked", "plugin", name, "method", method, "panic", v, "stack", string(debug.Stack()))
+			err = fmt.Errorf("plugin %s panicked in %s: %v", name, method, v)
+		}
+	}()
+	return fn()
+}
+
+func closedChan() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}
+
+// safeTransform and safeSink recover panics in a plugin's Transform and
+// HandleEvent, returning them as errors so the route records a failed step.
+// They also hold the plugin's gate, so a call never overlaps a restart.
+type safeTransform struct {
+	Plugin
+	t    Transform
+	r    *Registry
+	name string
+	gate *sync.RWMutex
+}
+
+func (s safeTransform) Transform(ctx context.Context, event Event, action string, params map[string]any) (Event, error) {
+	s.gate.RLock()
+	defer s.gate.RUnlock()
+	out := event
+	err := s.r.protect(s.name, "Transform", func() error {
+		var err error
+		out, err = s.t.Transform(ctx, event, action, params)
+		return err
+	})
+	return out, err
+}
+
+type safeSink struct {
+	Plugin
+	s    Sink
+	r    *Registry
+	name string
+	gate *sync.RWMutex
+}
+
+func (s safeSink) HandleEvent(ctx context.Context, event Event) error {
+	s.gate.RLock()
+	defer s.gate.RUnlock()
+	return s.r.protect(s.name, "HandleEvent", func() error { return s.s.HandleEvent(ctx, event) })
+}
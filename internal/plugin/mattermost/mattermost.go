@@ -12,6 +12,7 @@ import (
 	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -20,6 +21,10 @@ import (
 	"github.com/google/uuid"
 )
 
+func init() {
+	plugin.RegisterType("mattermost", func(log *slog.Logger) plugin.Plugin { return New(log) })
+}
+
 type Config struct {
 	URL       string `json:"url"`
 	Token     string `json:"token"`
@@ -27,7 +32,17 @@ type Config struct {
 	Listen    bool   `json:"listen"`
 }
 
+func (p *Plugin) ConfigSchema() []plugin.ConfigField {
+	return []plugin.ConfigField{
+		{Name: "url", Type: plugin.FieldString, Required: true, Description: "Server URL"},
+		{Name: "token", Type: plugin.FieldString, Secret: true, Description: "Bot access token"},
+		{Name: "token_file", Type: plugin.FieldString, Description: "File containing the bot token"},
+		{Name: "listen", Type: plugin.FieldBool, Default: "false", Description: "Receive chat commands over WebSocket"},
+	}
+}
+
 type Plugin struct {
+	name   string
 	cfg    Config
 	token  string
 	client *http.Client
@@ -39,19 +54,42 @@ type Plugin struct {
 	botName     string
 	wsCancel    context.CancelFunc
 	wsConnected atomic.Bool
+	wg          sync.WaitGroup
 
 	// Command dispatch.
 	commands []plugin.CommandInfo
+
+	// state remembers which event each reply thread came from.
+	state plugin.StateStore
+}
+
+// threadCauseTTL is how long a reply thread keeps linking new messages to
+// the event that started it.
+const threadCauseTTL = 7 * 24 * time.Hour
+
+// threadCause is the event whose reply started a thread.
+type threadCause struct {
+	EventID       string `json:"event_id"`
+	CorrelationID string `json:"correlation_id"`
 }
 
 func New(log *slog.Logger) *Plugin {
 	return &Plugin{
+		name:   "mattermost",
 		client: &http.Client{Timeout: 30 * time.Second},
 		log:    log,
 	}
 }
 
-func (p *Plugin) Name() string { return "mattermost" }
+func (p *Plugin) Name() string { return p.name }
+
+func (p *Plugin) SetState(state plugin.StateStore) { p.state = state }
+
+// SetName sets the instance name. A listening instance emits its events
+// under that name, so routes can tell servers apart.
+func (p *Plugin) SetName(name string) { p.name = name }
+
+func (p *Plugin) SetTransport(rt http.RoundTripper) { p.client.Transport = rt }
 
 func (p *Plugin) Init(cfg json.RawMessage) error {
 	if err := json.Unmarshal(cfg, &p.cfg); err != nil {
@@ -82,6 +120,7 @@ func (p *Plugin) Start(ctx context.Context, bus plugin.EventBus) error {
 
 	wsCtx, cancel := context.WithCancel(ctx)
 	p.wsCancel = cancel
+	p.wg.Add(1)
 	go p.listenWS(wsCtx)
 	return nil
 }
@@ -90,6 +129,7 @@ func (p *Plugin) Stop() error {
 	if p.wsCancel != nil {
 		p.wsCancel()
 	}
+	p.wg.Wait()
 	return nil
 }
 
@@ -159,6 +199,7 @@ func (p *Plugin) fetchBotUser(ctx context.Context) error {
 
 // listenWS is the outer reconnection loop with exponential backoff.
 func (p *Plugin) listenWS(ctx context.Context) {
+	defer p.wg.Done()
 	backoff := time.Second
 	const maxBackoff = 30 * time.Second
 
@@ -322,9 +363,9 @@ func (p *Plugin) handleWSMessage(data []byte) {
 		p.log.Error("mattermost: add reaction", "error", err)
 	}
 
-	p.bus.Emit(plugin.Event{
+	event := plugin.Event{
 		ID:        uuid.NewString(),
-		Source:    "mattermost",
+		Source:    p.name,
 		Type:      subcmd,
 		Timestamp: time.Now(),
 		Payload: map[string]any{
@@ -337,7 +378,43 @@ func (p *Plugin) handleWSMessage(data []byte) {
 			"sender_name":  ev.Data.SenderName,
 			"channel_type": ev.Data.ChannelType,
 		},
-	})
+	}
+	// A command in a thread we replied to follows from that reply's event.
+	if cause, ok := p.threadCause(post.RootID); ok {
+		event.ParentID = cause.EventID
+		event.CorrelationID = cause.CorrelationID
+	}
+	p.bus.Emit(event)
+}
+
+func (p *Plugin) threadCause(rootID string) (threadCause, bool) {
+	if rootID == "" || p.state == nil {
+		return threadCause{}, false
+	}
+	var c threadCause
+	ok, err := p.state.Get(context.Background(), "thread:"+rootID, &c)
+	if err != nil {
+		p.log.Warn("mattermost: load thread cause", "root_id", rootID, "error", err)
+	}
+	return c, ok && err == nil
+}
+
+// rememberThread links a reply thread to the event the router was handling
+// when the reply was posted.
+func (p *Plugin) rememberThread(ctx context.Context, rootID string, event plugin.Event) {
+	if p.state == nil {
+		return
+	}
+	if cause, ok := plugin.CauseFromContext(ctx); ok {
+		event = cause
+	}
+	c := threadCause{EventID: event.ID, CorrelationID: event.CorrelationID}
+	if c.CorrelationID == "" {
+		c.CorrelationID = event.ID
+	}
+	if err := p.state.Set(ctx, "thread:"+rootID, c, threadCauseTTL); err != nil {
+		p.log.Warn("mattermost: save thread cause", "root_id", rootID, "error", err)
+	}
 }
 
 func (p *Plugin) isKnownCommand(name string) bool {
@@ -481,8 +558,9 @@ func (p *Plugin) HandleEvent(ctx context.Context, event plugin.Event) error {
 	}
 
 	// Thread replies: if the event carries a post_id, reply in-thread.
+	var rootID string
 	if postID, _ := event.Payload["post_id"].(string); postID != "" {
-		rootID, _ := event.Payload["root_id"].(string)
+		rootID, _ = event.Payload["root_id"].(string)
 		if rootID == "" {
 			rootID = postID
 		}
@@ -531,6 +609,9 @@ func (p *Plugin) HandleEvent(ctx context.Context, event plugin.Event) error {
 	}
 
 	p.log.Info("mattermost message sent", "channel", channel, "event_id", event.ID)
+	if rootID != "" {
+		p.rememberThread(ctx, rootID, event)
+	}
 
 	// Remove thinking reaction now that the reply is posted.
 	if postID, _ := event.Payload["post_id"].(string); postID != "" {
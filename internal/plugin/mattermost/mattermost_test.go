@@ -12,6 +12,7 @@ import (
 	"testing"
 
 	"github.com/boozedog/smoothbrain/internal/plugin"
+	"github.com/boozedog/smoothbrain/internal/store"
 )
 
 func discardLogger() *slog.Logger {
@@ -393,6 +394,36 @@ func TestHandleEvent_ThreadReply(t *testing.T) {
 	}
 }
 
+func TestThreadReplyDerivesFromCause(t *testing.T) {
+	st, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	p, bus := newTestWSPlugin(t, acceptAllHandler)
+	p.SetState(plugin.NewStateStore(st, "mattermost"))
+
+	// The router hands the sink the pipeline output, with the triggering
+	// event in the context.
+	cause := plugin.Event{ID: "cmd-1", CorrelationID: "root-1"}
+	ctx := plugin.WithCause(context.Background(), cause)
+	reply := plugin.Event{ID: "cmd-1", Payload: map[string]any{"channel": "chan123", "post_id": "post123", "summary": "done"}}
+	if err := p.HandleEvent(ctx, reply); err != nil {
+		t.Fatalf("HandleEvent() error = %v", err)
+	}
+
+	post, _ := json.Marshal(map[string]any{"id": "post999", "message": "ask more", "channel_id": "chan123", "user_id": "user456", "root_id": "post123"})
+	msg, _ := json.Marshal(map[string]any{"event": "posted", "data": map[string]any{"post": string(post), "channel_type": "D"}})
+	p.handleWSMessage(msg)
+
+	if bus.len() != 1 {
+		t.Fatalf("expected 1 event, got %d", bus.len())
+	}
+	if ev := bus.get(0); ev.ParentID != "cmd-1" || ev.CorrelationID != "root-1" {
+		t.Errorf("lineage = (%q, %q), want (cmd-1, root-1)", ev.ParentID, ev.CorrelationID)
+	}
+}
+
 func TestHandleEvent_APIError(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -0,0 +1,78 @@
+package obsidian
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/a-h/templ"
+	"github.com/boozedog/smoothbrain/internal/plugin"
+)
+
+// RegisterAPI serves vault search as JSON, and as HTML for the dashboard
+// panel.
+func (p *Plugin) RegisterAPI(reg plugin.APIRegistrar) {
+	reg.RegisterAPI(http.MethodGet, "search", p.handleSearch)
+	reg.RegisterAPI(http.MethodGet, "search/html", p.handleSearchHTML)
+}
+
+func (p *Plugin) Panels() []plugin.Panel {
+	return []plugin.Panel{{
+		Name:  "search",
+		Title: "Vault search",
+		Render: func(r *http.Request) templ.Component {
+			return searchPanel(searchURL(p.name), p.noteCount(r.Context()))
+		},
+	}}
+}
+
+func searchURL(name string) string {
+	return "/api/plugins/" + url.PathEscape(name) + "/search/html"
+}
+
+// searchRequest runs the search given by the q and limit query parameters.
+// An empty query finds nothing.
+func (p *Plugin) searchRequest(r *http.Request) ([]SearchResult, error) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		return nil, nil
+	}
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	return p.Search(q, limit)
+}
+
+func (p *Plugin) handleSearch(w http.ResponseWriter, r *http.Request) {
+	results, err := p.searchRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if results == nil {
+		results = []SearchResult{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(results); err != nil {
+		p.log.Error("obsidian: encode search results", "error", err)
+	}
+}
+
+func (p *Plugin) handleSearchHTML(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html")
+	if r.URL.Query().Get("q") == "" {
+		return
+	}
+	results, err := p.searchRequest(r)
+	if err := searchResults(results, err).Render(r.Context(), w); err != nil {
+		p.log.Error("obsidian: render search results", "error", err)
+	}
+}
+
+func (p *Plugin) noteCount(ctx context.Context) int {
+	var n int
+	if err := p.store.DB().QueryRowContext(ctx, "SELECT COUNT(*) FROM obsidian_notes WHERE vault = ?", p.name).Scan(&n); err != nil {
+		p.log.Warn("obsidian: count notes", "error", err)
+	}
+	return n
+}
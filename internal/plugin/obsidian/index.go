@@ -1,22 +1,28 @@
 package obsidian
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/boozedog/smoothbrain/internal/store"
 )
 
 type SearchResult struct {
-	Path    string
-	Title   string
-	Excerpt string
-	Score   float64
+	Path    string  `json:"path"`
+	Title   string  `json:"title"`
+	Excerpt string  `json:"excerpt"`
+	Score   float64 `json:"score"`
 }
 
-func (p *Plugin) initSchema() error {
-	schema := `
+func init() {
+	store.Register("obsidian", store.Migration{
+		Version:     1,
+		Description: "notes table and full-text index",
+		SQL: `
 CREATE TABLE IF NOT EXISTS obsidian_notes (
     path TEXT PRIMARY KEY,
     title TEXT,
@@ -49,9 +55,48 @@ CREATE TRIGGER IF NOT EXISTS obsidian_notes_au AFTER UPDATE ON obsidian_notes BE
     INSERT INTO obsidian_fts(rowid, title, fields, content)
     VALUES (new.rowid, new.title, new.fields, new.content);
 END;
-`
-	_, err := p.db.Exec(schema)
-	return err
+`,
+	}, store.Migration{
+		Version:     2,
+		Description: "scope notes by vault instance",
+		SQL: `
+CREATE TABLE obsidian_notes_new (
+    vault TEXT NOT NULL DEFAULT 'obsidian',
+    path TEXT NOT NULL,
+    title TEXT,
+    fields TEXT,
+    content TEXT,
+    modified_at INTEGER,
+    PRIMARY KEY (vault, path)
+);
+-- Keep rowids so the external-content FTS index stays valid.
+INSERT INTO obsidian_notes_new (rowid, path, title, fields, content, modified_at)
+    SELECT rowid, path, title, fields, content, modified_at FROM obsidian_notes;
+DROP TABLE obsidian_notes;
+ALTER TABLE obsidian_notes_new RENAME TO obsidian_notes;
+
+CREATE TRIGGER obsidian_notes_ai AFTER INSERT ON obsidian_notes BEGIN
+    INSERT INTO obsidian_fts(rowid, title, fields, content)
+    VALUES (new.rowid, new.title, new.fields, new.content);
+END;
+
+CREATE TRIGGER obsidian_notes_ad AFTER DELETE ON obsidian_notes BEGIN
+    INSERT INTO obsidian_fts(obsidian_fts, rowid, title, fields, content)
+    VALUES ('delete', old.rowid, old.title, old.fields, old.content);
+END;
+
+CREATE TRIGGER obsidian_notes_au AFTER UPDATE ON obsidian_notes BEGIN
+    INSERT INTO obsidian_fts(obsidian_fts, rowid, title, fields, content)
+    VALUES ('delete', old.rowid, old.title, old.fields, old.content);
+    INSERT INTO obsidian_fts(rowid, title, fields, content)
+    VALUES (new.rowid, new.title, new.fields, new.content);
+END;
+`,
+	})
+}
+
+func (p *Plugin) initSchema() error {
+	return p.store.Migrate("obsidian")
 }
 
 func (p *Plugin) IndexFile(relPath string) error {
@@ -69,15 +114,15 @@ func (p *Plugin) IndexFile(relPath string) error {
 	note := ParseNote(relPath, string(data))
 	fieldsJSON, _ := json.Marshal(note.Fields)
 
-	_, err = p.db.Exec(`
-		INSERT INTO obsidian_notes (path, title, fields, content, modified_at)
-		VALUES (?, ?, ?, ?, ?)
-		ON CONFLICT(path) DO UPDATE SET
+	_, err = p.store.Exec(context.Background(), `
+		INSERT INTO obsidian_notes (vault, path, title, fields, content, modified_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(vault, path) DO UPDATE SET
 			title = excluded.title,
 			fields = excluded.fields,
 			content = excluded.content,
 			modified_at = excluded.modified_at`,
-		relPath, note.Title, string(fieldsJSON), note.Raw, info.ModTime().Unix(),
+		p.name, relPath, note.Title, string(fieldsJSON), note.Raw, info.ModTime().Unix(),
 	)
 	if err != nil {
 		return fmt.Errorf("obsidian: index %s: %w", relPath, err)
@@ -91,7 +136,7 @@ func (p *Plugin) IndexVault() error {
 	p.log.Info("indexing vault", "path", p.cfg.VaultPath)
 
 	// Build map of existing mtime in DB.
-	rows, err := p.db.Query("SELECT path, modified_at FROM obsidian_notes")
+	rows, err := p.store.DB().Query("SELECT path, modified_at FROM obsidian_notes WHERE vault = ?", p.name)
 	if err != nil {
 		return fmt.Errorf("obsidian: query existing: %w", err)
 	}
@@ -149,7 +194,7 @@ func (p *Plugin) IndexVault() error {
 
 	// Remove stale entries (files that no longer exist).
 	for path := range existing {
-		if _, err := p.db.Exec("DELETE FROM obsidian_notes WHERE path = ?", path); err != nil {
+		if _, err := p.store.Exec(context.Background(), "DELETE FROM obsidian_notes WHERE vault = ? AND path = ?", p.name, path); err != nil {
 			p.log.Warn("remove stale entry failed", "path", path, "error", err)
 		}
 	}
@@ -163,16 +208,16 @@ func (p *Plugin) Search(query string, limit int) ([]SearchResult, error) {
 		limit = 10
 	}
 
-	rows, err := p.db.Query(`
+	rows, err := p.store.DB().Query(`
 		SELECT n.path, n.title,
 		       snippet(obsidian_fts, 2, '**', '**', '...', 32) AS excerpt,
 		       bm25(obsidian_fts, 5.0, 3.0, 1.0) AS score
 		FROM obsidian_fts f
 		JOIN obsidian_notes n ON f.rowid = n.rowid
-		WHERE obsidian_fts MATCH ?
+		WHERE obsidian_fts MATCH ? AND n.vault = ?
 		ORDER BY score
 		LIMIT ?`,
-		query, limit,
+		query, p.name, limit,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("obsidian: search: %w", err)
@@ -2,7 +2,6 @@ package obsidian
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -12,27 +11,43 @@ import (
 	"time"
 
 	"github.com/boozedog/smoothbrain/internal/plugin"
+	"github.com/boozedog/smoothbrain/internal/store"
 )
 
+func init() {
+	plugin.RegisterType("obsidian", func(log *slog.Logger) plugin.Plugin { return New(log) })
+}
+
 type Config struct {
 	VaultPath string `json:"vault_path"`
 }
 
+func (p *Plugin) ConfigSchema() []plugin.ConfigField {
+	return []plugin.ConfigField{
+		{Name: "vault_path", Type: plugin.FieldString, Default: "~/obsidian/smoothbrain"},
+	}
+}
+
 type Plugin struct {
+	name    string
 	cfg     Config
-	db      *sql.DB
+	store   *store.Store
 	bus     plugin.EventBus
 	log     *slog.Logger
 	watcher *Watcher
 }
 
 func New(log *slog.Logger) *Plugin {
-	return &Plugin{log: log}
+	return &Plugin{name: "obsidian", log: log}
 }
 
-func (p *Plugin) Name() string { return "obsidian" }
+func (p *Plugin) Name() string { return p.name }
 
-func (p *Plugin) SetStore(db *sql.DB) { p.db = db }
+// SetName sets the instance name, which also scopes the vault's search
+// index.
+func (p *Plugin) SetName(name string) { p.name = name }
+
+func (p *Plugin) SetStore(s *store.Store) { p.store = s }
 
 func (p *Plugin) Init(cfg json.RawMessage) error {
 	p.cfg = Config{VaultPath: "~/obsidian/smoothbrain"}
@@ -87,6 +102,60 @@ func (p *Plugin) HealthCheck(_ context.Context) plugin.HealthStatus {
 	return plugin.HealthStatus{Status: plugin.StatusOK}
 }
 
+func (p *Plugin) Actions() []plugin.Action {
+	return []plugin.Action{
+		{
+			Name:        "search",
+			Description: "Full-text searches the vault for the message",
+			Params: []plugin.ConfigField{
+				{Name: "limit", Type: plugin.FieldInt, Default: "10", Description: "Maximum results"},
+			},
+			Reads:  []string{"message"},
+			Writes: []string{"summary"},
+		},
+		{
+			Name:        "read",
+			Description: "Returns a note, from the path param or the message",
+			Params: []plugin.ConfigField{
+				{Name: "path", Type: plugin.FieldString, Description: "Vault-relative note path; .md may be omitted"},
+			},
+			Reads:  []string{"message"},
+			Writes: []string{"summary"},
+		},
+		{
+			Name:        "query",
+			Description: "Lists notes under dir, optionally filtered by an inline field",
+			Params: []plugin.ConfigField{
+				{Name: "dir", Type: plugin.FieldString, Required: true, Description: "Vault-relative directory"},
+				{Name: "field", Type: plugin.FieldString, Description: "Only notes with this inline field"},
+				{Name: "within_days", Type: plugin.FieldInt, Description: "Only notes whose field is a date this recent"},
+			},
+			Writes: []string{"summary"},
+		},
+		{
+			Name:        "write_note",
+			Description: "Appends the message to today's diary",
+			Params:      []plugin.ConfigField{},
+			Reads:       []string{"message"},
+			Writes:      []string{"summary"},
+		},
+		{
+			Name:        "write_link",
+			Description: "Adds the message as a link in today's note",
+			Params:      []plugin.ConfigField{},
+			Reads:       []string{"message"},
+			Writes:      []string{"summary"},
+		},
+		{
+			Name:        "write_log",
+			Description: "Appends a maintenance row to a vehicle's note",
+			Params:      []plugin.ConfigField{},
+			Reads:       []string{"vehicle", "description", "miles", "cost", "location"},
+			Writes:      []string{"summary"},
+		},
+	}
+}
+
 func (p *Plugin) Transform(ctx context.Context, event plugin.Event, action string, params map[string]any) (plugin.Event, error) {
 	switch action {
 	case "search":
@@ -113,8 +182,8 @@ func (p *Plugin) search(_ context.Context, event plugin.Event, params map[string
 	}
 
 	limit := 10
-	if l, ok := params["limit"].(float64); ok {
-		limit = int(l)
+	if l, ok := params["limit"].(int); ok {
+		limit = l
 	}
 
 	results, err := p.Search(query, limit)
@@ -165,10 +234,7 @@ func (p *Plugin) read(_ context.Context, event plugin.Event, params map[string]a
 func (p *Plugin) query(_ context.Context, event plugin.Event, params map[string]any) (plugin.Event, error) {
 	dir, _ := params["dir"].(string)
 	field, _ := params["field"].(string)
-	withinDays := 0
-	if d, ok := params["within_days"].(float64); ok {
-		withinDays = int(d)
-	}
+	withinDays, _ := params["within_days"].(int)
 
 	if dir == "" {
 		return event, fmt.Errorf("obsidian query: missing dir param")
@@ -2,29 +2,31 @@ package obsidian
 
 import (
 	"context"
-	"database/sql"
+	"encoding/json"
 	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/boozedog/smoothbrain/internal/plugin"
-	_ "modernc.org/sqlite"
+	"github.com/boozedog/smoothbrain/internal/store"
 )
 
 func newTestObsidian(t *testing.T) *Plugin {
 	t.Helper()
 	dir := t.TempDir()
-	db, err := sql.Open("sqlite", ":memory:")
+	st, err := store.OpenUnmigrated(":memory:")
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Cleanup(func() { _ = db.Close() })
+	t.Cleanup(func() { _ = st.Close() })
 	p := New(slog.New(slog.NewTextHandler(io.Discard, nil)))
 	p.cfg.VaultPath = dir
-	p.db = db
+	p.store = st
 	if err := p.initSchema(); err != nil {
 		t.Fatal(err)
 	}
@@ -150,3 +152,70 @@ func TestQuery_DirEscapesVault(t *testing.T) {
 		t.Errorf("error %q should contain %q", err.Error(), "escapes vault")
 	}
 }
+
+func TestSearch_ScopedToVault(t *testing.T) {
+	work := newTestObsidian(t)
+	home := New(work.log)
+	home.SetName("obsidian:home")
+	home.cfg.VaultPath = t.TempDir()
+	home.store = work.store
+
+	for _, p := range []*Plugin{work, home} {
+		if err := os.WriteFile(filepath.Join(p.cfg.VaultPath, "note.md"), []byte("# Note\nshared keyword from "+p.Name()), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.IndexVault(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, p := range []*Plugin{work, home} {
+		results, err := p.Search("keyword", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || !strings.Contains(results[0].Excerpt, p.Name()) {
+			t.Errorf("%s: results = %+v, want only its own note", p.Name(), results)
+		}
+	}
+}
+
+func TestSearchAPI(t *testing.T) {
+	p := newTestObsidian(t)
+	if err := os.WriteFile(filepath.Join(p.cfg.VaultPath, "garden.md"), []byte("# Garden\nplant tomatoes in may"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.IndexVault(); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	p.handleSearch(rec, httptest.NewRequest(http.MethodGet, "/api/plugins/obsidian/search?q=tomatoes", nil))
+	var results []SearchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	if len(results) != 1 || results[0].Path != "garden.md" {
+		t.Errorf("results = %+v, want garden.md", results)
+	}
+
+	rec = httptest.NewRecorder()
+	p.handleSearch(rec, httptest.NewRequest(http.MethodGet, "/api/plugins/obsidian/search?q=%22", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid query status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec = httptest.NewRecorder()
+	p.handleSearchHTML(rec, httptest.NewRequest(http.MethodGet, "/api/plugins/obsidian/search/html?q=tomatoes", nil))
+	if !strings.Contains(rec.Body.String(), "garden.md") {
+		t.Errorf("html = %q, want garden.md listed", rec.Body.String())
+	}
+
+	var panel strings.Builder
+	if err := p.Panels()[0].Render(httptest.NewRequest(http.MethodGet, "/", nil)).Render(context.Background(), &panel); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(panel.String(), "1 notes indexed") || !strings.Contains(panel.String(), `hx-get="/api/plugins/obsidian/search/html"`) {
+		t.Errorf("panel = %q", panel.String())
+	}
+}
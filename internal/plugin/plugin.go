@@ -2,10 +2,14 @@ package plugin
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 	"time"
+
+	"github.com/a-h/templ"
+	"github.com/boozedog/smoothbrain/internal/store"
+	"github.com/google/uuid"
 )
 
 type Event struct {
@@ -14,6 +18,43 @@ type Event struct {
 	Type      string         `json:"type"`
 	Payload   map[string]any `json:"payload"`
 	Timestamp time.Time      `json:"timestamp"`
+
+	// ParentID is the event that caused this one. CorrelationID is shared by
+	// every event descending from the same root; the bus fills it in when
+	// empty.
+	ParentID      string `json:"parent_id,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// Derive returns a new event caused by e, inheriting its correlation ID.
+func (e Event) Derive(source, typ string, payload map[string]any) Event {
+	corr := e.CorrelationID
+	if corr == "" {
+		corr = e.ID
+	}
+	return Event{
+		ID:            uuid.NewString(),
+		Source:        source,
+		Type:          typ,
+		Payload:       payload,
+		Timestamp:     time.Now(),
+		ParentID:      e.ID,
+		CorrelationID: corr,
+	}
+}
+
+type causeKey struct{}
+
+// WithCause returns a context carrying the event being handled, so plugins
+// emitting follow-up events from a transform or sink can Derive from it.
+func WithCause(ctx context.Context, e Event) context.Context {
+	return context.WithValue(ctx, causeKey{}, e)
+}
+
+// CauseFromContext returns the event stored by WithCause.
+func CauseFromContext(ctx context.Context) (Event, bool) {
+	e, ok := ctx.Value(causeKey{}).(Event)
+	return e, ok
 }
 
 type EventBus interface {
@@ -27,6 +68,17 @@ type Plugin interface {
 	Stop() error
 }
 
+// Factory creates an unconfigured plugin. Its Name before any SetName is the
+// plugin's type.
+type Factory func(log *slog.Logger) Plugin
+
+// Named is implemented by plugins that can run as several instances. The
+// registry calls SetName before Init; the plugin reports the instance name
+// as its Name and uses it as the Source of the events it emits.
+type Named interface {
+	SetName(name string)
+}
+
 type Sink interface {
 	Plugin
 	HandleEvent(ctx context.Context, event Event) error
@@ -37,6 +89,26 @@ type Transform interface {
 	Transform(ctx context.Context, event Event, action string, params map[string]any) (Event, error)
 }
 
+// Action describes an action a transform accepts.
+type Action struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Params describes the route step params the action takes. The router
+	// coerces params to these types before calling Transform; when nil,
+	// params are passed through unchecked.
+	Params []ConfigField `json:"params,omitempty"`
+	// Reads and Writes list the payload keys the action uses and sets.
+	Reads  []string `json:"reads,omitempty"`
+	Writes []string `json:"writes,omitempty"`
+}
+
+// ActionProvider is implemented by transforms that declare their actions, so
+// routes naming an unknown action are rejected at startup. A transform that
+// returns no actions accepts any.
+type ActionProvider interface {
+	Actions() []Action
+}
+
 // CommandInfo describes a subcommand that a source plugin can dispatch.
 type CommandInfo struct {
 	Name        string
@@ -54,8 +126,16 @@ type WebhookRegistrar interface {
 }
 
 // StoreAware is implemented by plugins that need access to the SQLite database.
+// Reads go through Store.DB; writes must use Store.Exec or Store.Write.
 type StoreAware interface {
-	SetStore(db *sql.DB)
+	SetStore(s *store.Store)
+}
+
+// HTTPAware is implemented by plugins that call external HTTP services. The
+// registry hands each one a transport that rate limits, retries and
+// circuit-breaks its requests; plugins install it on their http.Client.
+type HTTPAware interface {
+	SetTransport(rt http.RoundTripper)
 }
 
 // WebhookSource is implemented by plugins that provide webhook endpoints.
@@ -63,6 +143,39 @@ type WebhookSource interface {
 	RegisterWebhook(reg WebhookRegistrar)
 }
 
+// APIRegistrar lets a plugin mount HTTP handlers under
+// /api/plugins/{name}/, where name is the plugin's own instance name.
+type APIRegistrar interface {
+	// RegisterAPI serves handler for method, GET or POST, at
+	// /api/plugins/{name}/{path}.
+	RegisterAPI(method, path string, handler http.HandlerFunc)
+}
+
+// APIMux is where the registry mounts plugin API routes, each under the name
+// of the plugin that registered it.
+type APIMux interface {
+	MountAPI(name, method, path string, handler http.HandlerFunc)
+}
+
+// APIProvider is implemented by plugins that expose their state over HTTP.
+// Unlike webhooks, these routes sit behind the dashboard's login.
+type APIProvider interface {
+	RegisterAPI(reg APIRegistrar)
+}
+
+// Panel is a card a plugin contributes to the dashboard's Plugins tab.
+type Panel struct {
+	Name    string // unique per plugin, used in the panel's URL
+	Title   string
+	Refresh time.Duration // re-render interval; zero renders once
+	Render  func(r *http.Request) templ.Component
+}
+
+// PanelProvider is implemented by plugins that contribute dashboard panels.
+type PanelProvider interface {
+	Panels() []Panel
+}
+
 type Status string
 
 const (
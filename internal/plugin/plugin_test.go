@@ -0,0 +1,37 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEvent_Derive(t *testing.T) {
+	root := Event{ID: "root", Source: "mattermost", Type: "summarize"}
+
+	child := root.Derive("route", "summary.ready", map[string]any{"k": "v"})
+	if child.ID == "" || child.ID == root.ID {
+		t.Errorf("child ID = %q, want a fresh ID", child.ID)
+	}
+	if child.ParentID != "root" || child.CorrelationID != "root" {
+		t.Errorf("child lineage = (%q, %q), want (root, root)", child.ParentID, child.CorrelationID)
+	}
+
+	grandchild := child.Derive("mattermost", "notify", nil)
+	if grandchild.ParentID != child.ID {
+		t.Errorf("grandchild ParentID = %q, want %q", grandchild.ParentID, child.ID)
+	}
+	if grandchild.CorrelationID != "root" {
+		t.Errorf("grandchild CorrelationID = %q, want root", grandchild.CorrelationID)
+	}
+}
+
+func TestCauseFromContext(t *testing.T) {
+	if _, ok := CauseFromContext(context.Background()); ok {
+		t.Fatal("expected no cause on empty context")
+	}
+	ctx := WithCause(context.Background(), Event{ID: "evt-1"})
+	e, ok := CauseFromContext(ctx)
+	if !ok || e.ID != "evt-1" {
+		t.Errorf("CauseFromContext() = (%v, %v), want evt-1", e.ID, ok)
+	}
+}
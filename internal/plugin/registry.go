@@ -2,39 +2,130 @@ package plugin
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/boozedog/smoothbrain/internal/outbound"
+	"github.com/boozedog/smoothbrain/internal/store"
 )
 
 type Registry struct {
 	plugins map[string]Plugin
 	order   []Plugin
+	configs map[string]json.RawMessage
+	ctx     context.Context // parent of each plugin's context; set by StartAll
+	bus     EventBus
 	mu      sync.RWMutex
-	db      *sql.DB
+	store   *store.Store
+	http    *outbound.Pool
 	log     *slog.Logger
+
+	runMu     sync.Mutex
+	runs      map[string]*runState
+	restartMu sync.Mutex
+	// gates hold routed calls to a plugin off while Restart stops and
+	// re-initializes it.
+	gates map[string]*sync.RWMutex
 }
 
-func NewRegistry(log *slog.Logger, db *sql.DB) *Registry {
+func NewRegistry(log *slog.Logger, s *store.Store) *Registry {
 	return &Registry{
 		plugins: make(map[string]Plugin),
-		db:      db,
+		runs:    make(map[string]*runState),
+		gates:   make(map[string]*sync.RWMutex),
+		store:   s,
+		http:    outbound.New(outbound.DefaultOptions(), log),
 		log:     log,
 	}
 }
 
+// SetOutbound replaces the pool HTTPAware plugins' transports come from. It
+// must be called before InitAll.
+func (r *Registry) SetOutbound(pool *outbound.Pool) {
+	r.http = pool
+}
+
+// HTTPStats returns the named plugin's outbound request counts, or false if
+// it has made no requests through the shared transport.
+func (r *Registry) HTTPStats(name string) (outbound.Stats, bool) {
+	t, ok := r.http.Lookup(name)
+	if !ok {
+		return outbound.Stats{}, false
+	}
+	return t.Stats(), true
+}
+
 func (r *Registry) Register(p Plugin) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.plugins[p.Name()] = p
 	r.order = append(r.order, p)
+	r.gates[p.Name()] = new(sync.RWMutex)
 	r.log.Info("plugin registered", "plugin", p.Name())
 }
 
+// RegisterConfigured creates one plugin per entry in configs from the types
+// added with RegisterType. An entry's key is the instance name; its type is
+// the entry's "type" field, or else the part of the key before a ":", so
+// "mattermost:work" is a second mattermost. Entries with "enabled": false are
+// skipped.
+func (r *Registry) RegisterConfigured(configs map[string]json.RawMessage) error {
+	return r.registerInstances(registeredTypes(), configs)
+}
+
+func (r *Registry) registerInstances(types map[string]Factory, configs map[string]json.RawMessage) error {
+	for _, name := range slices.Sorted(maps.Keys(configs)) {
+		typ, enabled, err := parseInstance(name, configs[name])
+		if err != nil {
+			return err
+		}
+		if !enabled {
+			r.log.Info("plugin disabled", "plugin", name)
+			continue
+		}
+		f, ok := types[typ]
+		if !ok {
+			return fmt.Errorf("plugin %s: unknown type %q", name, typ)
+		}
+		p := f(r.log)
+		if name != typ {
+			n, ok := p.(Named)
+			if !ok {
+				return fmt.Errorf("plugin %s: type %q does not support multiple instances", name, typ)
+			}
+			n.SetName(name)
+		}
+		r.Register(p)
+	}
+	return nil
+}
+
+// parseInstance reads the type and enabled flag of a plugin config entry.
+func parseInstance(key string, cfg json.RawMessage) (typ string, enabled bool, err error) {
+	var c struct {
+		Type    string `json:"type"`
+		Enabled *bool  `json:"enabled"`
+	}
+	if len(cfg) > 0 {
+		if err := json.Unmarshal(cfg, &c); err != nil {
+			return "", false, fmt.Errorf("plugin %s config: %w", key, err)
+		}
+	}
+	typ = c.Type
+	if typ == "" {
+		typ, _, _ = strings.Cut(key, ":")
+	}
+	return typ, c.Enabled == nil || *c.Enabled, nil
+}
+
 func (r *Registry) Get(name string) (Plugin, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -50,7 +141,10 @@ func (r *Registry) GetSink(name string) (Sink, bool) {
 		return nil, false
 	}
 	s, ok := p.(Sink)
-	return s, ok
+	if !ok {
+		return nil, false
+	}
+	return safeSink{Plugin: s, s: s, r: r, name: name, gate: r.gates[name]}, true
 }
 
 func (r *Registry) GetTransform(name string) (Transform, bool) {
@@ -61,16 +155,88 @@ func (r *Registry) GetTransform(name string) (Transform, bool) {
 		return nil, false
 	}
 	t, ok := p.(Transform)
-	return t, ok
+	if !ok {
+		return nil, false
+	}
+	return safeTransform{Plugin: t, t: t, r: r, name: name, gate: r.gates[name]}, true
 }
 
-func (r *Registry) InitAll(configs map[string]json.RawMessage) error {
+// ValidateConfigs checks each Configurable plugin's entry in configs against
+// its schema, reporting every problem rather than stopping at the first.
+func (r *Registry) ValidateConfigs(configs map[string]json.RawMessage) error {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	var errs []error
+	for _, p := range r.order {
+		c, ok := p.(Configurable)
+		if !ok {
+			continue
+		}
+		if err := ValidateConfig(c.ConfigSchema(), configs[p.Name()]); err != nil {
+			for _, line := range strings.Split(err.Error(), "\n") {
+				errs = append(errs, fmt.Errorf("plugin %s: %s", p.Name(), line))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Settings returns the named plugin's effective config with secrets masked,
+// or false if it is unknown or has no schema.
+func (r *Registry) Settings(name string) ([]Setting, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.plugins[name].(Configurable)
+	if !ok {
+		return nil, false
+	}
+	return Settings(c.ConfigSchema(), r.configs[name]), true
+}
+
+// Actions returns the actions the named plugin declares, or nil if it does
+// not declare any.
+func (r *Registry) Actions(name string) []Action {
+	p, ok := r.Get(name)
+	if !ok {
+		return nil
+	}
+	ap, ok := p.(ActionProvider)
+	if !ok {
+		return nil
+	}
+	return ap.Actions()
+}
+
+// StepParams coerces a route step's params to the named action's param
+// schema. Params for an action without one are returned unchanged.
+func (r *Registry) StepParams(name, action string, params map[string]any) (map[string]any, error) {
+	for _, a := range r.Actions(name) {
+		if a.Name == action && a.Params != nil {
+			return CoerceParams(a.Params, params)
+		}
+	}
+	return params, nil
+}
+
+// InitAll validates configs and initializes every plugin in registration
+// order.
+func (r *Registry) InitAll(configs map[string]json.RawMessage) error {
+	if err := r.ValidateConfigs(configs); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.configs = configs
 	for _, p := range r.order {
 		name := p.Name()
 		if sa, ok := p.(StoreAware); ok {
-			sa.SetStore(r.db)
+			sa.SetStore(r.store)
+		}
+		if sa, ok := p.(StateAware); ok {
+			sa.SetState(NewStateStore(r.store, name))
+		}
+		if ha, ok := p.(HTTPAware); ok {
+			ha.SetTransport(r.http.Transport(name))
 		}
 		cfg, ok := configs[name]
 		if !ok {
@@ -84,30 +250,52 @@ func (r *Registry) InitAll(configs map[string]json.RawMessage) error {
 	return nil
 }
 
-func (r *Registry) StartAll(ctx context.Context, bus EventBus) error {
+// RegisterWebhooks discovers plugins that implement WebhookSource and registers their handlers.
+func (r *Registry) RegisterWebhooks(reg WebhookRegistrar) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	for _, p := range r.order {
-		name := p.Name()
-		if err := p.Start(ctx, bus); err != nil {
-			return fmt.Errorf("start plugin %s: %w", name, err)
+		if ws, ok := p.(WebhookSource); ok {
+			ws.RegisterWebhook(reg)
 		}
-		r.log.Info("plugin started", "plugin", name)
 	}
-	return nil
 }
 
-// RegisterWebhooks discovers plugins that implement WebhookSource and registers their handlers.
-func (r *Registry) RegisterWebhooks(reg WebhookRegistrar) {
+// RegisterAPIs mounts the HTTP routes of plugins that implement APIProvider.
+// Each plugin's routes go under its own name, so one cannot serve another's.
+func (r *Registry) RegisterAPIs(mux APIMux) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	for _, p := range r.order {
-		if ws, ok := p.(WebhookSource); ok {
-			ws.RegisterWebhook(reg)
+		if ap, ok := p.(APIProvider); ok {
+			ap.RegisterAPI(apiRegistrar{mux: mux, name: p.Name()})
 		}
 	}
 }
 
+// apiRegistrar mounts one plugin's routes under its name.
+type apiRegistrar struct {
+	mux  APIMux
+	name string
+}
+
+func (a apiRegistrar) RegisterAPI(method, path string, handler http.HandlerFunc) {
+	a.mux.MountAPI(a.name, method, path, handler)
+}
+
+// Panels returns the dashboard panels the named plugin contributes, or nil.
+func (r *Registry) Panels(name string) []Panel {
+	p, ok := r.Get(name)
+	if !ok {
+		return nil
+	}
+	pp, ok := p.(PanelProvider)
+	if !ok {
+		return nil
+	}
+	return pp.Panels()
+}
+
 // PluginInfo describes a registered plugin for the status UI.
 type PluginInfo struct {
 	Name  string
@@ -146,6 +334,7 @@ type HealthResult struct {
 
 // CheckHealth queries all plugins for their health status. Plugins implementing
 // HealthChecker are called with a per-plugin timeout; others default to StatusOK.
+// A healthy plugin with an open circuit breaker is reported as degraded.
 func (r *Registry) CheckHealth(ctx context.Context, timeout time.Duration) []HealthResult {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
@@ -153,13 +342,22 @@ func (r *Registry) CheckHealth(ctx context.Context, timeout time.Duration) []Hea
 	results := make([]HealthResult, 0, len(r.order))
 	for _, p := range r.order {
 		hr := HealthResult{Name: p.Name()}
-		if hc, ok := p.(HealthChecker); ok {
+		if st, ok := r.notRunning(hr.Name); ok {
+			hr.Status = st
+		} else if hc, ok := p.(HealthChecker); ok {
 			tctx, cancel := context.WithTimeout(ctx, timeout)
 			hr.Status = hc.HealthCheck(tctx)
 			cancel()
 		} else {
 			hr.Status = HealthStatus{Status: StatusOK}
 		}
+		if hr.Status.Status == StatusOK {
+			if t, ok := r.http.Lookup(hr.Name); ok {
+				if hosts := t.OpenHosts(); len(hosts) > 0 {
+					hr.Status = HealthStatus{Status: StatusDegraded, Message: "circuit open: " + strings.Join(hosts, ", ")}
+				}
+			}
+		}
 		results = append(results, hr)
 	}
 	return results
@@ -204,12 +402,6 @@ func (r *Registry) StopAll() {
 	defer r.mu.RUnlock()
 	// Stop in reverse registration order.
 	for i := len(r.order) - 1; i >= 0; i-- {
-		p := r.order[i]
-		name := p.Name()
-		if err := p.Stop(); err != nil {
-			r.log.Error("plugin stop error", "plugin", name, "error", err)
-		} else {
-			r.log.Info("plugin stopped", "plugin", name)
-		}
+		r.stop(r.order[i])
 	}
 }
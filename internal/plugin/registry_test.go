@@ -2,15 +2,20 @@ package plugin
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"errors"
 	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
-	_ "modernc.org/sqlite"
+	"github.com/boozedog/smoothbrain/internal/outbound"
+	"github.com/boozedog/smoothbrain/internal/store"
 )
 
 // --- stub types ---
@@ -67,21 +72,34 @@ func (s *stubHealthPlugin) HealthCheck(context.Context) HealthStatus { return s.
 
 type stubStoreAwarePlugin struct {
 	stubPlugin
-	db *sql.DB
+	store *store.Store
 }
 
-func (s *stubStoreAwarePlugin) SetStore(db *sql.DB) { s.db = db }
+func (s *stubStoreAwarePlugin) SetStore(st *store.Store) { s.store = st }
+
+type stubHTTPPlugin struct {
+	stubPlugin
+	client http.Client
+}
+
+func (s *stubHTTPPlugin) SetTransport(rt http.RoundTripper) { s.client.Transport = rt }
+
+type stubNamedPlugin struct {
+	stubPlugin
+}
+
+func (s *stubNamedPlugin) SetName(name string) { s.name = name }
 
 // --- helpers ---
 
 func newTestRegistry(t *testing.T) *Registry {
 	t.Helper()
-	db, err := sql.Open("sqlite", ":memory:")
+	st, err := store.Open(":memory:")
 	if err != nil {
 		t.Fatal(err)
 	}
-	t.Cleanup(func() { _ = db.Close() })
-	return NewRegistry(slog.New(slog.NewTextHandler(io.Discard, nil)), db)
+	t.Cleanup(func() { _ = st.Close() })
+	return NewRegistry(slog.New(slog.NewTextHandler(io.Discard, nil)), st)
 }
 
 // --- tests ---
@@ -196,8 +214,8 @@ func TestRegistry_InitAll_StoreAware(t *testing.T) {
 	if err := r.InitAll(nil); err != nil {
 		t.Fatal(err)
 	}
-	if p.db == nil {
-		t.Error("expected StoreAware plugin to receive non-nil DB")
+	if p.store == nil {
+		t.Error("expected StoreAware plugin to receive non-nil store")
 	}
 }
 
@@ -208,9 +226,7 @@ func TestRegistry_StartAll(t *testing.T) {
 	r.Register(a)
 	r.Register(b)
 
-	if err := r.StartAll(context.Background(), nil); err != nil {
-		t.Fatal(err)
-	}
+	r.StartAll(context.Background(), nil)
 	if !a.started {
 		t.Error("expected alpha to be started")
 	}
@@ -219,6 +235,125 @@ func TestRegistry_StartAll(t *testing.T) {
 	}
 }
 
+// flakyPlugin fails Start until failures runs out and panics in Transform
+// and HandleEvent.
+type flakyPlugin struct {
+	stubPlugin
+	mu       sync.Mutex
+	failures int
+	starts   int
+	inits    int
+}
+
+func (f *flakyPlugin) Init(json.RawMessage) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inits++
+	return nil
+}
+
+func (f *flakyPlugin) Start(context.Context, EventBus) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.starts++
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("not yet")
+	}
+	return nil
+}
+
+func (f *flakyPlugin) counts() (inits, starts int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.inits, f.starts
+}
+
+func (f *flakyPlugin) Transform(context.Context, Event, string, map[string]any) (Event, error) {
+	panic("transform exploded")
+}
+
+func (f *flakyPlugin) HandleEvent(context.Context, Event) error {
+	var m map[string]int
+	m["boom"]++
+	return nil
+}
+
+func TestRegistry_RecoversPanics(t *testing.T) {
+	r := newTestRegistry(t)
+	r.Register(&flakyPlugin{stubPlugin: stubPlugin{name: "flaky"}})
+
+	tx, _ := r.GetTransform("flaky")
+	in := Event{ID: "e1"}
+	out, err := tx.Transform(context.Background(), in, "x", nil)
+	if err == nil || !strings.Contains(err.Error(), "transform exploded") {
+		t.Errorf("Transform() error = %v, want recovered panic", err)
+	}
+	if out.ID != "e1" {
+		t.Errorf("Transform() returned %+v, want the input event", out)
+	}
+
+	sink, _ := r.GetSink("flaky")
+	if err := sink.HandleEvent(context.Background(), in); err == nil || !strings.Contains(err.Error(), "panicked in HandleEvent") {
+		t.Errorf("HandleEvent() error = %v, want recovered panic", err)
+	}
+}
+
+func TestRegistry_StartRetries(t *testing.T) {
+	minStartDelay = 5 * time.Millisecond
+	t.Cleanup(func() { minStartDelay = time.Second })
+	r := newTestRegistry(t)
+	p := &flakyPlugin{stubPlugin: stubPlugin{name: "flaky"}, failures: 2}
+	r.Register(p)
+
+	r.StartAll(context.Background(), nil)
+	t.Cleanup(r.StopAll)
+	hr := r.CheckHealth(context.Background(), time.Second)
+	if hr[0].Status.Status != StatusError || !strings.Contains(hr[0].Status.Message, "start failed (attempt 1): not yet") {
+		t.Errorf("health after failed start = %+v", hr[0].Status)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, starts := p.counts(); starts == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("plugin start was not retried")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if hr := r.CheckHealth(context.Background(), time.Second); hr[0].Status.Status != StatusOK {
+		t.Errorf("health after retries = %+v, want ok", hr[0].Status)
+	}
+}
+
+func TestRegistry_Restart(t *testing.T) {
+	r := newTestRegistry(t)
+	p := &flakyPlugin{stubPlugin: stubPlugin{name: "flaky"}}
+	r.Register(p)
+	if err := r.Restart("flaky"); err == nil {
+		t.Error("Restart() before StartAll succeeded")
+	}
+	if err := r.InitAll(nil); err != nil {
+		t.Fatal(err)
+	}
+	r.StartAll(context.Background(), nil)
+
+	if err := r.Restart("flaky"); err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+	if inits, starts := p.counts(); inits != 2 || starts != 2 {
+		t.Errorf("inits, starts = %d, %d, want 2, 2", inits, starts)
+	}
+	if !p.stopped {
+		t.Error("plugin was not stopped before restart")
+	}
+	if err := r.Restart("nope"); !errors.Is(err, ErrUnknownPlugin) {
+		t.Errorf("Restart(unknown) error = %v, want ErrUnknownPlugin", err)
+	}
+}
+
 func TestRegistry_StopAll_ReverseOrder(t *testing.T) {
 	r := newTestRegistry(t)
 	var seq []string
@@ -307,6 +442,35 @@ func TestRegistry_CheckHealth_Custom(t *testing.T) {
 	}
 }
 
+func TestRegistry_CheckHealth_CircuitOpen(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	r := newTestRegistry(t)
+	r.SetOutbound(outbound.New(outbound.Options{BreakerThreshold: 1, BreakerCooldown: time.Minute}, r.log))
+	p := &stubHTTPPlugin{stubPlugin: stubPlugin{name: "api"}}
+	r.Register(p)
+	if err := r.InitAll(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := p.client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	_ = resp.Body.Close()
+
+	results := r.CheckHealth(context.Background(), time.Second)
+	if results[0].Status.Status != StatusDegraded || !strings.Contains(results[0].Status.Message, "circuit open") {
+		t.Errorf("health = %+v, want degraded with circuit open", results[0].Status)
+	}
+	if st, ok := r.HTTPStats("api"); !ok || st.Failures != 1 {
+		t.Errorf("HTTPStats = %+v, %v; want 1 failure", st, ok)
+	}
+}
+
 func TestRegistry_AggregateHealth_AllOK(t *testing.T) {
 	r := newTestRegistry(t)
 	r.Register(&stubPlugin{name: "a"})
@@ -334,3 +498,132 @@ func TestRegistry_AggregateHealth_Degraded(t *testing.T) {
 		t.Errorf("aggregate status = %q, want %q", agg.Status, StatusDegraded)
 	}
 }
+
+func TestRegistry_RegisterInstances(t *testing.T) {
+	r := newTestRegistry(t)
+	types := map[string]Factory{
+		"chat":   func(*slog.Logger) Plugin { return &stubNamedPlugin{stubPlugin{name: "chat"}} },
+		"single": func(*slog.Logger) Plugin { return &stubPlugin{name: "single"} },
+		"unused": func(*slog.Logger) Plugin { return &stubPlugin{name: "unused"} },
+	}
+	configs := map[string]json.RawMessage{
+		"chat":      json.RawMessage(`{}`),
+		"chat:work": json.RawMessage(`{"url":"https://work"}`),
+		"chat:old":  json.RawMessage(`{"enabled":false}`),
+		"home-chat": json.RawMessage(`{"type":"chat","enabled":true}`),
+		"single":    json.RawMessage(`{}`),
+	}
+	if err := r.registerInstances(types, configs); err != nil {
+		t.Fatalf("registerInstances() error = %v", err)
+	}
+	var names []string
+	for _, info := range r.All() {
+		names = append(names, info.Name)
+	}
+	want := []string{"chat", "chat:work", "home-chat", "single"}
+	if !slices.Equal(names, want) {
+		t.Errorf("registered = %v, want %v", names, want)
+	}
+}
+
+func TestRegistry_RegisterInstances_Errors(t *testing.T) {
+	types := map[string]Factory{"single": func(*slog.Logger) Plugin { return &stubPlugin{name: "single"} }}
+	for _, tc := range []struct {
+		name, key, cfg, want string
+	}{
+		{"unknown type", "other:x", `{}`, "unknown type"},
+		{"not named", "single:two", `{}`, "multiple instances"},
+		{"bad config", "single:two", `[]`, "config"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newTestRegistry(t)
+			err := r.registerInstances(types, map[string]json.RawMessage{tc.key: json.RawMessage(tc.cfg)})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("registerInstances() error = %v, want it to mention %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestRegisterType(t *testing.T) {
+	RegisterType("test-type", func(*slog.Logger) Plugin { return &stubPlugin{name: "test-type"} })
+	t.Cleanup(func() { unregisterType("test-type") })
+	if !slices.Contains(Types(), "test-type") {
+		t.Errorf("Types() = %v, want it to include test-type", Types())
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("duplicate RegisterType did not panic")
+		}
+	}()
+	RegisterType("test-type", nil)
+}
+
+// seqSink records its lifecycle calls; HandleEvent blocks until release is
+// closed.
+type seqSink struct {
+	stubPlugin
+	mu      sync.Mutex
+	seq     []string
+	release chan struct{}
+}
+
+func (s *seqSink) record(step string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq = append(s.seq, step)
+}
+
+func (s *seqSink) Init(json.RawMessage) error { s.record("init"); return nil }
+func (s *seqSink) Stop() error                { s.record("stop"); return nil }
+func (s *seqSink) HandleEvent(context.Context, Event) error {
+	s.record("handle")
+	<-s.release
+	s.record("handled")
+	return nil
+}
+
+func TestRegistry_RestartWaitsForRoutedCalls(t *testing.T) {
+	r := newTestRegistry(t)
+	p := &seqSink{stubPlugin: stubPlugin{name: "sink"}, release: make(chan struct{})}
+	r.Register(p)
+	if err := r.InitAll(nil); err != nil {
+		t.Fatal(err)
+	}
+	r.StartAll(context.Background(), nil)
+	t.Cleanup(r.StopAll)
+
+	sink, _ := r.GetSink("sink")
+	handled := make(chan struct{})
+	go func() {
+		_ = sink.HandleEvent(context.Background(), Event{})
+		close(handled)
+	}()
+	for {
+		p.mu.Lock()
+		n := len(p.seq)
+		p.mu.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	restarted := make(chan error)
+	go func() { restarted <- r.Restart("sink") }()
+	select {
+	case <-restarted:
+		t.Fatal("Restart() returned while HandleEvent was running")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(p.release)
+	<-handled
+	if err := <-restarted; err != nil {
+		t.Fatalf("Restart() error = %v", err)
+	}
+
+	want := []string{"init", "handle", "handled", "stop", "init"}
+	if !slices.Equal(p.seq, want) {
+		t.Errorf("calls = %v, want %v", p.seq, want)
+	}
+}
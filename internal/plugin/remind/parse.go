@@ -0,0 +1,224 @@
+package remind
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultHour is the time of day used when a day is given without a time.
+const defaultHour = 9
+
+var errNoTime = errors.New("could not find a time; try `in 2h ...`, `tomorrow 9am ...` or `at 17:30 ...`")
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+var units = map[string]time.Duration{
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+// parseReminder splits a reminder request such as "in 2h check the backup" or
+// "tomorrow 9am renew registration" into its due time and text. Relative
+// times are measured from now; calendar times are interpreted in now's
+// location. A leading "me" and a "to" before the text are dropped.
+func parseReminder(s string, now time.Time) (time.Time, string, error) {
+	words := strings.Fields(s)
+	if len(words) > 0 && strings.EqualFold(words[0], "me") {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		return time.Time{}, "", errNoTime
+	}
+
+	var due time.Time
+	var n int
+	var err error
+	switch first := strings.ToLower(words[0]); {
+	case first == "in":
+		due, n, err = parseRelative(words[1:], now)
+		n++
+	case first == "at":
+		due, n, err = parseAt(words[1:], now)
+		n++
+	case first == "today" || first == "tomorrow":
+		day := now
+		if first == "tomorrow" {
+			day = now.AddDate(0, 0, 1)
+		}
+		due, n, err = parseDayTime(words[1:], day, first == "today")
+		n++
+	case first == "on" || first == "next":
+		if len(words) < 2 {
+			return time.Time{}, "", errNoTime
+		}
+		wd, ok := weekdays[strings.ToLower(words[1])]
+		if !ok {
+			return time.Time{}, "", errNoTime
+		}
+		due, n, err = parseDayTime(words[2:], nextWeekday(now, wd), false)
+		n += 2
+	default:
+		wd, ok := weekdays[first]
+		if !ok {
+			return time.Time{}, "", errNoTime
+		}
+		due, n, err = parseDayTime(words[1:], nextWeekday(now, wd), false)
+		n++
+	}
+	if err != nil {
+		return time.Time{}, "", err
+	}
+	if !due.After(now) {
+		return time.Time{}, "", fmt.Errorf("%s is in the past", due.Format("Mon Jan 2 15:04"))
+	}
+
+	words = words[n:]
+	if len(words) > 0 && strings.EqualFold(words[0], "to") {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		return time.Time{}, "", errors.New("what should I remind you about?")
+	}
+	return due, strings.Join(words, " "), nil
+}
+
+// parseRelative parses "2h", "1h30m", "2 hours" or "an hour" and reports how
+// many words it consumed.
+func parseRelative(words []string, now time.Time) (time.Time, int, error) {
+	if len(words) == 0 {
+		return time.Time{}, 0, errNoTime
+	}
+	if d, err := time.ParseDuration(words[0]); err == nil && d > 0 {
+		return now.Add(d), 1, nil
+	}
+	if len(words) < 2 {
+		return time.Time{}, 0, fmt.Errorf("unknown duration %q", words[0])
+	}
+	var count int
+	switch strings.ToLower(words[0]) {
+	case "a", "an":
+		count = 1
+	default:
+		c, err := strconv.Atoi(words[0])
+		if err != nil || c <= 0 {
+			return time.Time{}, 0, fmt.Errorf("unknown duration %q", words[0]+" "+words[1])
+		}
+		count = c
+	}
+	unit, ok := units[strings.ToLower(words[1])]
+	if !ok {
+		return time.Time{}, 0, fmt.Errorf("unknown duration %q", words[0]+" "+words[1])
+	}
+	return now.Add(time.Duration(count) * unit), 2, nil
+}
+
+// parseAt parses a time of day, rolling over to tomorrow if it has passed.
+func parseAt(words []string, now time.Time) (time.Time, int, error) {
+	h, m, n, ok := parseClock(words)
+	if !ok {
+		return time.Time{}, 0, errNoTime
+	}
+	due := atClock(now, h, m)
+	if !due.After(now) {
+		due = atClock(now.AddDate(0, 0, 1), h, m)
+	}
+	return due, n, nil
+}
+
+// parseDayTime parses an optional "[at] <time>" on day. Without a time the
+// reminder is due at defaultHour, unless requireTime is set.
+func parseDayTime(words []string, day time.Time, requireTime bool) (time.Time, int, error) {
+	skip := 0
+	if len(words) > 0 && strings.EqualFold(words[0], "at") {
+		skip = 1
+	}
+	h, m, n, ok := parseClock(words[skip:])
+	if !ok {
+		if skip > 0 || requireTime {
+			return time.Time{}, 0, errNoTime
+		}
+		return atClock(day, defaultHour, 0), 0, nil
+	}
+	return atClock(day, h, m), skip + n, nil
+}
+
+// parseClock parses "9am", "9:30pm", "9 am" or "17:30" and reports how many
+// words it consumed.
+func parseClock(words []string) (hour, minute, n int, ok bool) {
+	if len(words) == 0 {
+		return 0, 0, 0, false
+	}
+	s := strings.ToLower(words[0])
+	n = 1
+	suffix := ""
+	for _, sfx := range []string{"am", "pm"} {
+		if strings.HasSuffix(s, sfx) {
+			suffix, s = sfx, strings.TrimSuffix(s, sfx)
+			break
+		}
+	}
+	if suffix == "" && len(words) > 1 {
+		if next := strings.ToLower(words[1]); next == "am" || next == "pm" {
+			suffix, n = next, 2
+		}
+	}
+
+	hs, ms, hasMinutes := strings.Cut(s, ":")
+	hour, err := strconv.Atoi(hs)
+	if err != nil {
+		return 0, 0, 0, false
+	}
+	if hasMinutes {
+		if len(ms) != 2 {
+			return 0, 0, 0, false
+		}
+		if minute, err = strconv.Atoi(ms); err != nil || minute < 0 || minute > 59 {
+			return 0, 0, 0, false
+		}
+	} else if suffix == "" {
+		// A bare number is too ambiguous to be a time.
+		return 0, 0, 0, false
+	}
+
+	switch suffix {
+	case "":
+		if hour < 0 || hour > 23 {
+			return 0, 0, 0, false
+		}
+	default:
+		if hour < 1 || hour > 12 {
+			return 0, 0, 0, false
+		}
+		hour %= 12
+		if suffix == "pm" {
+			hour += 12
+		}
+	}
+	return hour, minute, n, true
+}
+
+func atClock(day time.Time, hour, minute int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, day.Location())
+}
+
+// nextWeekday returns the next wd strictly after now's day.
+func nextWeekday(now time.Time, wd time.Weekday) time.Time {
+	days := (int(wd) - int(now.Weekday()) + 7) % 7
+	if days == 0 {
+		days = 7
+	}
+	return now.AddDate(0, 0, days)
+}
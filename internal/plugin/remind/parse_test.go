@@ -0,0 +1,58 @@
+package remind
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseReminder(t *testing.T) {
+	// Wednesday afternoon.
+	now := time.Date(2025, 6, 4, 14, 30, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		in   string
+		due  time.Time
+		text string
+	}{
+		{"in 2h check the backup", now.Add(2 * time.Hour), "check the backup"},
+		{"me in 1h30m to stretch", now.Add(90 * time.Minute), "stretch"},
+		{"in 3 days water plants", now.Add(72 * time.Hour), "water plants"},
+		{"in an hour call back", now.Add(time.Hour), "call back"},
+		{"tomorrow 9am renew registration", time.Date(2025, 6, 5, 9, 0, 0, 0, time.UTC), "renew registration"},
+		{"tomorrow at 9:30 pm take out bins", time.Date(2025, 6, 5, 21, 30, 0, 0, time.UTC), "take out bins"},
+		{"tomorrow pay rent", time.Date(2025, 6, 5, 9, 0, 0, 0, time.UTC), "pay rent"},
+		{"today 5pm leave", time.Date(2025, 6, 4, 17, 0, 0, 0, time.UTC), "leave"},
+		{"at 17:45 standup", time.Date(2025, 6, 4, 17, 45, 0, 0, time.UTC), "standup"},
+		{"at 8am run", time.Date(2025, 6, 5, 8, 0, 0, 0, time.UTC), "run"},
+		{"friday 3pm demo", time.Date(2025, 6, 6, 15, 0, 0, 0, time.UTC), "demo"},
+		{"on wednesday review", time.Date(2025, 6, 11, 9, 0, 0, 0, time.UTC), "review"},
+	} {
+		t.Run(tt.in, func(t *testing.T) {
+			due, text, err := parseReminder(tt.in, now)
+			if err != nil {
+				t.Fatalf("parseReminder() error = %v", err)
+			}
+			if !due.Equal(tt.due) || text != tt.text {
+				t.Errorf("parseReminder() = (%v, %q), want (%v, %q)", due, text, tt.due, tt.text)
+			}
+		})
+	}
+}
+
+func TestParseReminder_Errors(t *testing.T) {
+	now := time.Date(2025, 6, 4, 14, 30, 0, 0, time.UTC)
+	for _, in := range []string{
+		"",
+		"check the backup",
+		"in 2h",
+		"in soon check",
+		"today 9am too late",
+		"today check",
+		"at 25:00 nope",
+		"at 13pm nope",
+		"tomorrow at noon lunch",
+	} {
+		if _, _, err := parseReminder(in, now); err == nil {
+			t.Errorf("parseReminder(%q) = nil error, want error", in)
+		}
+	}
+}
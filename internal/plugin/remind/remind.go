@@ -0,0 +1,375 @@
+package remind
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/boozedog/smoothbrain/internal/plugin"
+	"github.com/boozedog/smoothbrain/internal/store"
+	"github.com/google/uuid"
+)
+
+func init() {
+	plugin.RegisterType("remind", func(log *slog.Logger) plugin.Plugin { return New(log) })
+	store.Register("remind", store.Migration{
+		Version:     1,
+		Description: "reminders table",
+		SQL: `
+CREATE TABLE IF NOT EXISTS reminders (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    due_at INTEGER NOT NULL,
+    message TEXT NOT NULL,
+    channel_id TEXT NOT NULL,
+    post_id TEXT,
+    root_id TEXT,
+    user_id TEXT,
+    event_id TEXT,
+    correlation_id TEXT,
+    status TEXT NOT NULL DEFAULT 'pending',
+    created_at INTEGER NOT NULL,
+    fired_at INTEGER
+);
+CREATE INDEX IF NOT EXISTS idx_reminders_status_due ON reminders(status, due_at);
+`,
+	})
+}
+
+// idleWait bounds how long the scheduler sleeps when nothing is pending.
+const idleWait = time.Hour
+
+type Config struct {
+	// Timezone is the IANA zone used for calendar times such as "tomorrow
+	// 9am". Empty means local time.
+	Timezone string `json:"timezone"`
+}
+
+func (p *Plugin) ConfigSchema() []plugin.ConfigField {
+	return []plugin.ConfigField{
+		{Name: "timezone", Type: plugin.FieldString, Description: "IANA zone for calendar times; the server's local zone when empty"},
+	}
+}
+
+type Plugin struct {
+	cfg   Config
+	loc   *time.Location
+	store *store.Store
+	bus   plugin.EventBus
+	log   *slog.Logger
+	now   func() time.Time
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func New(log *slog.Logger) *Plugin {
+	return &Plugin{
+		log:  log,
+		now:  time.Now,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+func (p *Plugin) Name() string { return "remind" }
+
+func (p *Plugin) SetStore(s *store.Store) { p.store = s }
+
+func (p *Plugin) Init(cfg json.RawMessage) error {
+	if err := json.Unmarshal(cfg, &p.cfg); err != nil {
+		return fmt.Errorf("remind config: %w", err)
+	}
+	p.loc = time.Local
+	if p.cfg.Timezone != "" {
+		loc, err := time.LoadLocation(p.cfg.Timezone)
+		if err != nil {
+			return fmt.Errorf("remind: timezone: %w", err)
+		}
+		p.loc = loc
+	}
+	return p.store.Migrate("remind")
+}
+
+// Start launches the scheduler. Reminders that fell due while smoothbrain
+// was down fire immediately.
+func (p *Plugin) Start(ctx context.Context, bus plugin.EventBus) error {
+	p.bus = bus
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.wg.Add(1)
+	go p.run(ctx)
+	return nil
+}
+
+func (p *Plugin) Stop() error {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	p.wg.Wait()
+	return nil
+}
+
+func (p *Plugin) HealthCheck(ctx context.Context) plugin.HealthStatus {
+	var n int
+	err := p.store.DB().QueryRowContext(ctx, `SELECT COUNT(*) FROM reminders WHERE status = 'pending'`).Scan(&n)
+	if err != nil {
+		return plugin.HealthStatus{Status: plugin.StatusError, Message: err.Error()}
+	}
+	return plugin.HealthStatus{Status: plugin.StatusOK, Message: fmt.Sprintf("%d pending", n)}
+}
+
+// Transform handles chat commands. The "command" action reads the event's
+// message: "list", "cancel <id>", or a new reminder such as "in 2h check the
+// backup". The reply is set as the event's summary.
+func (p *Plugin) Actions() []plugin.Action {
+	return []plugin.Action{
+		{
+			Name:        "command",
+			Description: "Creates a reminder from the message, or lists or cancels them",
+			Params:      []plugin.ConfigField{},
+			Reads:       []string{"message", "channel", "post_id", "root_id", "user_id"},
+			Writes:      []string{"summary"},
+		},
+	}
+}
+
+func (p *Plugin) Transform(ctx context.Context, event plugin.Event, action string, params map[string]any) (plugin.Event, error) {
+	switch action {
+	case "command":
+		return p.command(ctx, event)
+	default:
+		return event, fmt.Errorf("remind: unknown action %q", action)
+	}
+}
+
+func (p *Plugin) command(ctx context.Context, event plugin.Event) (plugin.Event, error) {
+	msg, _ := event.Payload["message"].(string)
+	channel, _ := event.Payload["channel"].(string)
+	if channel == "" {
+		return event, fmt.Errorf("remind: no channel in event payload")
+	}
+
+	var summary string
+	var err error
+	sub, rest, _ := strings.Cut(strings.TrimSpace(msg), " ")
+	switch strings.ToLower(sub) {
+	case "list":
+		summary, err = p.list(ctx, channel)
+	case "cancel":
+		summary, err = p.cancelReminder(ctx, channel, rest)
+	default:
+		summary, err = p.create(ctx, event, channel, msg)
+	}
+	if err != nil {
+		return event, err
+	}
+	event.Payload["summary"] = summary
+	return event, nil
+}
+
+func (p *Plugin) create(ctx context.Context, event plugin.Event, channel, msg string) (string, error) {
+	now := p.now().In(p.loc)
+	due, text, err := parseReminder(msg, now)
+	if err != nil {
+		return "", fmt.Errorf("remind: %w", err)
+	}
+
+	postID, _ := event.Payload["post_id"].(string)
+	rootID, _ := event.Payload["root_id"].(string)
+	userID, _ := event.Payload["user_id"].(string)
+	// The due event follows from the event the router was handling, even if
+	// the pipeline rewrote the one this transform was given.
+	if cause, ok := plugin.CauseFromContext(ctx); ok {
+		event.ID, event.CorrelationID = cause.ID, cause.CorrelationID
+	}
+	corr := event.CorrelationID
+	if corr == "" {
+		corr = event.ID
+	}
+	res, err := p.store.Exec(ctx,
+		`INSERT INTO reminders (due_at, message, channel_id, post_id, root_id, user_id, event_id, correlation_id, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		due.Unix(), text, channel, postID, rootID, userID, event.ID, corr, now.Unix(),
+	)
+	if err != nil {
+		return "", fmt.Errorf("remind: save: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return "", fmt.Errorf("remind: save: %w", err)
+	}
+	p.poke()
+
+	p.log.Info("remind: reminder created", "id", id, "due", due, "channel", channel)
+	return fmt.Sprintf("Reminder #%d set for %s (in %s).", id, formatDue(due), due.Sub(now).Round(time.Minute)), nil
+}
+
+func (p *Plugin) list(ctx context.Context, channel string) (string, error) {
+	rows, err := p.store.DB().QueryContext(ctx,
+		`SELECT id, due_at, message FROM reminders WHERE status = 'pending' AND channel_id = ? ORDER BY due_at`,
+		channel,
+	)
+	if err != nil {
+		return "", fmt.Errorf("remind: list: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var b strings.Builder
+	for rows.Next() {
+		var id, due int64
+		var text string
+		if err := rows.Scan(&id, &due, &text); err != nil {
+			return "", fmt.Errorf("remind: list: %w", err)
+		}
+		fmt.Fprintf(&b, "- #%d %s — %s\n", id, formatDue(time.Unix(due, 0).In(p.loc)), text)
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("remind: list: %w", err)
+	}
+	if b.Len() == 0 {
+		return "No pending reminders.", nil
+	}
+	return "**Pending reminders:**\n" + b.String(), nil
+}
+
+func (p *Plugin) cancelReminder(ctx context.Context, channel, arg string) (string, error) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(arg), "#"), 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("remind: usage: cancel <id>")
+	}
+	res, err := p.store.Exec(ctx,
+		`UPDATE reminders SET status = 'cancelled' WHERE id = ? AND channel_id = ? AND status = 'pending'`,
+		id, channel,
+	)
+	if err != nil {
+		return "", fmt.Errorf("remind: cancel: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return "", fmt.Errorf("remind: no pending reminder #%d in this channel", id)
+	}
+	p.poke()
+
+	p.log.Info("remind: reminder cancelled", "id", id)
+	return fmt.Sprintf("Cancelled reminder #%d.", id), nil
+}
+
+// poke wakes the scheduler to pick up a changed next due time.
+func (p *Plugin) poke() {
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Plugin) run(ctx context.Context) {
+	defer p.wg.Done()
+	for {
+		p.fireDue(ctx)
+
+		wait := idleWait
+		if next, ok := p.nextDue(ctx); ok {
+			wait = min(next.Sub(p.now()), idleWait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-p.wake:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+}
+
+func (p *Plugin) nextDue(ctx context.Context) (time.Time, bool) {
+	var due int64
+	err := p.store.DB().QueryRowContext(ctx,
+		`SELECT due_at FROM reminders WHERE status = 'pending' ORDER BY due_at LIMIT 1`,
+	).Scan(&due)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) && ctx.Err() == nil {
+			p.log.Error("remind: query next reminder", "error", err)
+		}
+		return time.Time{}, false
+	}
+	return time.Unix(due, 0), true
+}
+
+type reminder struct {
+	id                               int64
+	message, channel, postID, rootID string
+	userID, eventID, correlationID   string
+}
+
+// fireDue emits a "due" event for every pending reminder whose time has come.
+// Each reminder is marked fired before its event is emitted, so a crash in
+// between loses the reminder rather than repeating it.
+func (p *Plugin) fireDue(ctx context.Context) {
+	rows, err := p.store.DB().QueryContext(ctx,
+		`SELECT id, message, channel_id, COALESCE(post_id, ''), COALESCE(root_id, ''), COALESCE(user_id, ''),
+		        COALESCE(event_id, ''), COALESCE(correlation_id, '')
+		 FROM reminders WHERE status = 'pending' AND due_at <= ? ORDER BY due_at`,
+		p.now().Unix(),
+	)
+	if err != nil {
+		if ctx.Err() == nil {
+			p.log.Error("remind: query due reminders", "error", err)
+		}
+		return
+	}
+	var due []reminder
+	for rows.Next() {
+		var r reminder
+		if err := rows.Scan(&r.id, &r.message, &r.channel, &r.postID, &r.rootID, &r.userID, &r.eventID, &r.correlationID); err != nil {
+			p.log.Error("remind: scan reminder", "error", err)
+			continue
+		}
+		due = append(due, r)
+	}
+	_ = rows.Close()
+
+	for _, r := range due {
+		res, err := p.store.Exec(ctx,
+			`UPDATE reminders SET status = 'fired', fired_at = ? WHERE id = ? AND status = 'pending'`,
+			p.now().Unix(), r.id,
+		)
+		if err != nil {
+			p.log.Error("remind: mark reminder fired", "id", r.id, "error", err)
+			continue
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue // cancelled in the meantime
+		}
+
+		p.log.Info("remind: reminder due", "id", r.id, "channel", r.channel)
+		p.bus.Emit(plugin.Event{
+			ID:            uuid.NewString(),
+			Source:        "remind",
+			Type:          "due",
+			Timestamp:     p.now(),
+			ParentID:      r.eventID,
+			CorrelationID: r.correlationID,
+			Payload: map[string]any{
+				"reminder_id": r.id,
+				"channel":     r.channel,
+				"channel_id":  r.channel,
+				"post_id":     r.postID,
+				"root_id":     r.rootID,
+				"user_id":     r.userID,
+				"message":     r.message,
+				"summary":     "⏰ Reminder: " + r.message,
+			},
+		})
+	}
+}
+
+func formatDue(t time.Time) string {
+	return t.Format("Mon Jan 2 15:04 MST")
+}
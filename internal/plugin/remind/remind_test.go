@@ -0,0 +1,185 @@
+package remind
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/boozedog/smoothbrain/internal/plugin"
+	"github.com/boozedog/smoothbrain/internal/store"
+)
+
+type captureBus struct {
+	mu     sync.Mutex
+	events []plugin.Event
+}
+
+func (b *captureBus) Emit(e plugin.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, e)
+}
+
+func (b *captureBus) all() []plugin.Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]plugin.Event(nil), b.events...)
+}
+
+func newTestRemind(t *testing.T, st *store.Store) *Plugin {
+	t.Helper()
+	p := New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.SetStore(st)
+	if err := p.Init(json.RawMessage(`{"timezone": "UTC"}`)); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func openStore(t *testing.T) *store.Store {
+	t.Helper()
+	st, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	return st
+}
+
+func command(t *testing.T, p *Plugin, channel, msg string) (string, error) {
+	t.Helper()
+	ev := plugin.Event{ID: "cmd-" + msg, Payload: map[string]any{
+		"channel": channel,
+		"post_id": "post1",
+		"message": msg,
+	}}
+	out, err := p.Transform(context.Background(), ev, "command", nil)
+	summary, _ := out.Payload["summary"].(string)
+	return summary, err
+}
+
+func TestTransform_UnknownAction(t *testing.T) {
+	p := newTestRemind(t, openStore(t))
+	_, err := p.Transform(context.Background(), plugin.Event{Payload: map[string]any{}}, "nope", nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown action") {
+		t.Errorf("error = %v, want unknown action", err)
+	}
+}
+
+func TestInit_SchemaDefaultsAreAccepted(t *testing.T) {
+	p := New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	p.SetStore(openStore(t))
+	cfg := map[string]string{}
+	for _, f := range p.ConfigSchema() {
+		cfg[f.Name] = f.Default
+	}
+	b, _ := json.Marshal(cfg)
+	if err := p.Init(b); err != nil {
+		t.Fatalf("Init(%s) error = %v", b, err)
+	}
+	if p.loc != time.Local {
+		t.Errorf("location = %v, want local", p.loc)
+	}
+}
+
+func TestCommand_CreateListCancel(t *testing.T) {
+	p := newTestRemind(t, openStore(t))
+	p.now = func() time.Time { return time.Date(2025, 6, 4, 14, 30, 0, 0, time.UTC) }
+
+	got, err := command(t, p, "c1", "in 2h check the backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Reminder #1 set for Wed Jun 4 16:30 UTC (in 2h0m0s)."; got != want {
+		t.Errorf("create summary = %q, want %q", got, want)
+	}
+	if _, err := command(t, p, "c2", "tomorrow 9am other channel"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err = command(t, p, "c1", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "#1 Wed Jun 4 16:30 UTC — check the backup") || strings.Contains(got, "other channel") {
+		t.Errorf("list = %q, want only this channel's reminder", got)
+	}
+
+	if _, err := command(t, p, "c1", "cancel 2"); err == nil {
+		t.Error("cancelling another channel's reminder succeeded")
+	}
+	if got, err = command(t, p, "c1", "cancel #1"); err != nil || got != "Cancelled reminder #1." {
+		t.Errorf("cancel = (%q, %v)", got, err)
+	}
+	if got, _ = command(t, p, "c1", "list"); got != "No pending reminders." {
+		t.Errorf("list after cancel = %q", got)
+	}
+	if _, err := command(t, p, "c1", "sometime maybe"); err == nil {
+		t.Error("unparseable reminder succeeded")
+	}
+}
+
+func TestCreate_UsesCauseFromContext(t *testing.T) {
+	st := openStore(t)
+	p := newTestRemind(t, st)
+	ctx := plugin.WithCause(context.Background(), plugin.Event{ID: "chat-1", CorrelationID: "root-1"})
+	ev := plugin.Event{ID: "rewritten", Payload: map[string]any{"channel": "c1", "message": "in 1h stretch"}}
+	if _, err := p.Transform(ctx, ev, "command", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var eventID, corr string
+	if err := st.DB().QueryRow(`SELECT event_id, correlation_id FROM reminders`).Scan(&eventID, &corr); err != nil {
+		t.Fatal(err)
+	}
+	if eventID != "chat-1" || corr != "root-1" {
+		t.Errorf("reminder lineage = (%q, %q), want (chat-1, root-1)", eventID, corr)
+	}
+}
+
+func TestFireDue_SurvivesRestart(t *testing.T) {
+	st := openStore(t)
+	p := newTestRemind(t, st)
+	if _, err := command(t, p, "c1", "in 1h check the backup"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new instance over the same database after the reminder fell due.
+	p = newTestRemind(t, st)
+	p.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	bus := &captureBus{}
+	if err := p.Start(context.Background(), bus); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = p.Stop() }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(bus.all()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	events := bus.all()
+	if len(events) != 1 {
+		t.Fatalf("emitted %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.Source != "remind" || e.Type != "due" {
+		t.Errorf("event = %s/%s, want remind/due", e.Source, e.Type)
+	}
+	if e.Payload["channel"] != "c1" || e.Payload["post_id"] != "post1" || e.Payload["message"] != "check the backup" {
+		t.Errorf("payload = %v", e.Payload)
+	}
+	if e.ParentID != "cmd-in 1h check the backup" {
+		t.Errorf("parent = %q, want the command event", e.ParentID)
+	}
+
+	// Fired reminders do not fire again.
+	p.fireDue(context.Background())
+	if n := len(bus.all()); n != 1 {
+		t.Errorf("emitted %d events after refire, want 1", n)
+	}
+}
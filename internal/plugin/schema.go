@@ -0,0 +1,348 @@
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// FieldType is the JSON shape a config field accepts.
+type FieldType string
+
+const (
+	FieldString   FieldType = "string"
+	FieldBool     FieldType = "bool"
+	FieldInt      FieldType = "int"
+	FieldDuration FieldType = "duration" // a string for time.ParseDuration
+	FieldStrings  FieldType = "[]string"
+	FieldMap      FieldType = "map" // an object of string values
+	FieldJSON     FieldType = "json"
+)
+
+// ConfigField describes one key of a plugin's config, or one param of an
+// action.
+type ConfigField struct {
+	Name        string    `json:"name"`
+	Type        FieldType `json:"type"`
+	Required    bool      `json:"required,omitempty"`
+	Secret      bool      `json:"secret,omitempty"` // masked in the UI
+	Default     string    `json:"default,omitempty"`
+	Description string    `json:"description,omitempty"`
+}
+
+// Configurable is implemented by plugins that describe their config. The
+// registry rejects keys that are not in the schema, values of the wrong
+// type and missing required fields before Init.
+type Configurable interface {
+	ConfigSchema() []ConfigField
+}
+
+// reservedKeys are read by the registry rather than the plugin.
+var reservedKeys = []string{"type", "enabled"}
+
+// ValidateConfig checks cfg against schema and returns every problem found.
+func ValidateConfig(schema []ConfigField, cfg json.RawMessage) error {
+	values, err := configValues(cfg)
+	if err != nil {
+		return err
+	}
+	fields := make(map[string]ConfigField, len(schema))
+	for _, f := range schema {
+		fields[f.Name] = f
+	}
+
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		if slices.Contains(reservedKeys, key) {
+			continue
+		}
+		f, ok := fields[key]
+		if !ok {
+			msg := fmt.Sprintf("unknown key %q", key)
+			if s := closestField(key, schema); s != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", s)
+			}
+			errs = append(errs, errors.New(msg))
+			continue
+		}
+		if err := checkType(f.Type, values[key]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	for _, f := range schema {
+		if f.Required && isEmpty(values[f.Name]) {
+			errs = append(errs, fmt.Errorf("%s is required", f.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func configValues(cfg json.RawMessage) (map[string]json.RawMessage, error) {
+	values := map[string]json.RawMessage{}
+	if len(bytes.TrimSpace(cfg)) == 0 {
+		return values, nil
+	}
+	if err := json.Unmarshal(cfg, &values); err != nil {
+		return nil, errors.New("config must be a JSON object")
+	}
+	return values, nil
+}
+
+func checkType(typ FieldType, raw json.RawMessage) error {
+	if isNull(raw) {
+		return nil
+	}
+	var ok bool
+	switch typ {
+	case FieldString:
+		var s string
+		ok = json.Unmarshal(raw, &s) == nil
+	case FieldBool:
+		var b bool
+		ok = json.Unmarshal(raw, &b) == nil
+	case FieldInt:
+		var n int64
+		ok = json.Unmarshal(raw, &n) == nil
+	case FieldDuration:
+		var s string
+		if json.Unmarshal(raw, &s) != nil {
+			break
+		}
+		if s == "" {
+			return nil
+		}
+		if _, err := time.ParseDuration(s); err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		ok = true
+	case FieldStrings:
+		var ss []string
+		ok = json.Unmarshal(raw, &ss) == nil
+	case FieldMap:
+		var m map[string]string
+		ok = json.Unmarshal(raw, &m) == nil
+	case FieldJSON:
+		ok = json.Valid(raw)
+	default:
+		return fmt.Errorf("unsupported field type %q", typ)
+	}
+	if !ok {
+		return fmt.Errorf("must be %s, got %s", typ, raw)
+	}
+	return nil
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || string(raw) == "null"
+}
+
+func isEmpty(raw json.RawMessage) bool {
+	return isNull(raw) || string(raw) == `""`
+}
+
+// closestField returns the schema field within two edits of key, if any.
+func closestField(key string, schema []ConfigField) string {
+	best, bestDist := "", 3
+	for _, f := range schema {
+		if d := editDistance(key, f.Name); d < bestDist {
+			best, bestDist = f.Name, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// CoerceParams checks route step params against an action's param schema and
+// returns them converted to Go types: FieldInt to int, FieldDuration to
+// time.Duration, FieldStrings to []string and FieldMap to map[string]string.
+// Numbers and bools may also be given as strings. Missing params take their
+// default, if any; unknown and missing required params are errors.
+func CoerceParams(schema []ConfigField, params map[string]any) (map[string]any, error) {
+	fields := make(map[string]ConfigField, len(schema))
+	for _, f := range schema {
+		fields[f.Name] = f
+	}
+
+	out := make(map[string]any, len(schema))
+	var errs []error
+	for _, key := range slices.Sorted(maps.Keys(params)) {
+		f, ok := fields[key]
+		if !ok {
+			msg := fmt.Sprintf("unknown param %q", key)
+			if s := closestField(key, schema); s != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", s)
+			}
+			errs = append(errs, errors.New(msg))
+			continue
+		}
+		if params[key] == nil {
+			continue
+		}
+		v, err := coerce(f.Type, params[key])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		out[key] = v
+	}
+	for _, f := range schema {
+		if _, ok := out[f.Name]; ok {
+			continue
+		}
+		switch {
+		case f.Default != "":
+			v, err := coerce(f.Type, f.Default)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: default: %w", f.Name, err))
+				continue
+			}
+			out[f.Name] = v
+		case f.Required:
+			errs = append(errs, fmt.Errorf("%s is required", f.Name))
+		}
+	}
+	return out, errors.Join(errs...)
+}
+
+// coerce converts a param decoded from JSON to typ's Go type.
+func coerce(typ FieldType, v any) (any, error) {
+	switch typ {
+	case FieldString:
+		switch v := v.(type) {
+		case string:
+			return v, nil
+		case float64, int, bool:
+			return fmt.Sprint(v), nil
+		}
+	case FieldBool:
+		switch v := v.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(v); err == nil {
+				return b, nil
+			}
+		}
+	case FieldInt:
+		switch v := v.(type) {
+		case int:
+			return v, nil
+		case float64:
+			if v == float64(int(v)) {
+				return int(v), nil
+			}
+		case string:
+			if n, err := strconv.Atoi(v); err == nil {
+				return n, nil
+			}
+		}
+	case FieldDuration:
+		switch v := v.(type) {
+		case time.Duration:
+			return v, nil
+		case string:
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid duration %q", v)
+			}
+			return d, nil
+		}
+	case FieldStrings:
+		switch v := v.(type) {
+		case []string:
+			return v, nil
+		case []any:
+			ss := make([]string, 0, len(v))
+			for _, e := range v {
+				s, ok := e.(string)
+				if !ok {
+					return nil, fmt.Errorf("must be %s, got element %v", typ, e)
+				}
+				ss = append(ss, s)
+			}
+			return ss, nil
+		}
+	case FieldMap:
+		switch v := v.(type) {
+		case map[string]string:
+			return v, nil
+		case map[string]any:
+			m := make(map[string]string, len(v))
+			for k, e := range v {
+				s, ok := e.(string)
+				if !ok {
+					return nil, fmt.Errorf("must be %s, got %s: %v", typ, k, e)
+				}
+				m[k] = s
+			}
+			return m, nil
+		}
+	case FieldJSON:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unsupported field type %q", typ)
+	}
+	return nil, fmt.Errorf("must be %s, got %v", typ, v)
+}
+
+// Setting is a config field's effective value for display.
+type Setting struct {
+	Name    string
+	Value   string // masked for secrets
+	Default bool   // Value is the schema default, not configured
+	Secret  bool
+}
+
+// secretMask replaces configured secret values.
+const secretMask = "••••••"
+
+// Settings returns each schema field's configured value, or its default,
+// with secrets masked.
+func Settings(schema []ConfigField, cfg json.RawMessage) []Setting {
+	values, _ := configValues(cfg)
+	out := make([]Setting, 0, len(schema))
+	for _, f := range schema {
+		s := Setting{Name: f.Name, Secret: f.Secret}
+		raw := values[f.Name]
+		switch {
+		case isEmpty(raw):
+			s.Value, s.Default = f.Default, true
+		case f.Secret:
+			s.Value = secretMask
+		default:
+			var str string
+			var buf bytes.Buffer
+			if json.Unmarshal(raw, &str) == nil {
+				s.Value = str
+			} else if json.Compact(&buf, raw) == nil {
+				s.Value = buf.String()
+			}
+		}
+		out = append(out, s)
+	}
+	return out
+}
@@ -0,0 +1,183 @@
+package plugin
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSchema = []ConfigField{
+	{Name: "vault_path", Type: FieldString, Required: true},
+	{Name: "listen", Type: FieldBool, Default: "false"},
+	{Name: "retries", Type: FieldInt},
+	{Name: "interval", Type: FieldDuration, Default: "1m"},
+	{Name: "args", Type: FieldStrings},
+	{Name: "env", Type: FieldMap, Secret: true},
+	{Name: "token", Type: FieldString, Secret: true},
+	{Name: "extra", Type: FieldJSON},
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  string
+		want []string // substrings of the error; none means valid
+	}{
+		{"valid", `{"vault_path": "/v", "listen": true, "retries": 3, "interval": "5s", "args": ["a"], "env": {"K": "v"}, "extra": {"x": [1]}}`, nil},
+		{"reserved keys", `{"vault_path": "/v", "type": "obsidian", "enabled": true}`, nil},
+		{"null values", `{"vault_path": "/v", "retries": null}`, nil},
+		{"typo", `{"vault_pth": "/v"}`, []string{`unknown key "vault_pth" (did you mean "vault_path"?)`, "vault_path is required"}},
+		{"unknown without suggestion", `{"vault_path": "/v", "colour": "red"}`, []string{`unknown key "colour"`}},
+		{"wrong types", `{"vault_path": 1, "listen": "yes", "retries": 1.5, "args": "a", "env": {"K": 1}}`, []string{
+			"vault_path: must be string", "listen: must be bool", "retries: must be int", "args: must be []string", "env: must be map",
+		}},
+		{"bad duration", `{"vault_path": "/v", "interval": "soon"}`, []string{`interval: invalid duration "soon"`}},
+		{"empty required", `{"vault_path": ""}`, []string{"vault_path is required"}},
+		{"not an object", `[]`, []string{"config must be a JSON object"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(testSchema, json.RawMessage(tt.cfg))
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("ValidateConfig() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("ValidateConfig() succeeded, want error")
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error %q does not contain %q", err, w)
+				}
+			}
+		})
+	}
+}
+
+func TestCoerceParams(t *testing.T) {
+	schema := []ConfigField{
+		{Name: "query", Type: FieldString, Required: true},
+		{Name: "limit", Type: FieldInt, Default: "10"},
+		{Name: "exact", Type: FieldBool},
+		{Name: "within", Type: FieldDuration},
+		{Name: "tags", Type: FieldStrings},
+		{Name: "headers", Type: FieldMap},
+	}
+	tests := []struct {
+		name   string
+		params map[string]any
+		want   map[string]any
+		errs   []string
+	}{
+		{
+			name:   "json values",
+			params: map[string]any{"query": "go", "limit": 5.0, "exact": true, "within": "48h", "tags": []any{"a", "b"}, "headers": map[string]any{"K": "v"}},
+			want:   map[string]any{"query": "go", "limit": 5, "exact": true, "within": 48 * time.Hour, "tags": []string{"a", "b"}, "headers": map[string]string{"K": "v"}},
+		},
+		{
+			name:   "strings and defaults",
+			params: map[string]any{"query": 42.0, "exact": "false"},
+			want:   map[string]any{"query": "42", "limit": 10, "exact": false},
+		},
+		{
+			name:   "problems",
+			params: map[string]any{"limt": 3.0, "exact": "maybe", "tags": []any{1.0}},
+			errs:   []string{`unknown param "limt" (did you mean "limit"?)`, "exact: must be bool", "tags: must be []string", "query is required"},
+		},
+		{
+			name:   "fractional int",
+			params: map[string]any{"query": "go", "limit": 2.5},
+			errs:   []string{"limit: must be int, got 2.5"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CoerceParams(schema, tt.params)
+			if len(tt.errs) == 0 {
+				if err != nil {
+					t.Fatalf("CoerceParams() error = %v", err)
+				}
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("CoerceParams() = %#v, want %#v", got, tt.want)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("CoerceParams() succeeded, want error")
+			}
+			for _, w := range tt.errs {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("error %q does not contain %q", err, w)
+				}
+			}
+		})
+	}
+}
+
+func TestSettings_MasksSecrets(t *testing.T) {
+	got := Settings(testSchema, json.RawMessage(`{"vault_path": "/v", "retries": 2, "token": "hunter2", "env": {"K": "v"}, "args": ["a", "b"]}`))
+	want := map[string]Setting{
+		"vault_path": {Name: "vault_path", Value: "/v"},
+		"listen":     {Name: "listen", Value: "false", Default: true},
+		"retries":    {Name: "retries", Value: "2"},
+		"args":       {Name: "args", Value: `["a","b"]`},
+		"env":        {Name: "env", Value: secretMask, Secret: true},
+		"token":      {Name: "token", Value: secretMask, Secret: true},
+		"extra":      {Name: "extra", Default: true},
+	}
+	for _, s := range got {
+		if w, ok := want[s.Name]; ok && s != w {
+			t.Errorf("setting %s = %+v, want %+v", s.Name, s, w)
+		}
+		if strings.Contains(s.Value, "hunter2") {
+			t.Errorf("secret leaked in %+v", s)
+		}
+	}
+	if len(got) != len(testSchema) {
+		t.Errorf("got %d settings, want %d", len(got), len(testSchema))
+	}
+}
+
+type stubConfigurablePlugin struct {
+	stubPlugin
+}
+
+func (s *stubConfigurablePlugin) ConfigSchema() []ConfigField { return testSchema }
+
+func TestRegistry_InitAll_ValidatesSchema(t *testing.T) {
+	r := newTestRegistry(t)
+	var inits []string
+	p := &stubConfigurablePlugin{stubPlugin: stubPlugin{name: "vault", initSeq: &inits}}
+	r.Register(p)
+	r.Register(&stubPlugin{name: "lenient"})
+
+	err := r.InitAll(map[string]json.RawMessage{
+		"vault":   json.RawMessage(`{"vault_pth": "/v", "token": 5}`),
+		"lenient": json.RawMessage(`{"anything": true}`),
+	})
+	if err == nil {
+		t.Fatal("InitAll() with invalid config succeeded")
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "plugin vault: ") {
+		t.Errorf("InitAll() error = %q, want one line per problem", err)
+	}
+	if len(inits) != 0 {
+		t.Error("plugin was initialized despite invalid config")
+	}
+
+	if err := r.InitAll(map[string]json.RawMessage{"vault": json.RawMessage(`{"vault_path": "/v", "token": "s3cret"}`)}); err != nil {
+		t.Fatalf("InitAll() error = %v", err)
+	}
+	settings, ok := r.Settings("vault")
+	if !ok || settings[0].Value != "/v" || settings[6].Value != secretMask {
+		t.Errorf("Settings() = %+v, %v", settings, ok)
+	}
+	if _, ok := r.Settings("lenient"); ok {
+		t.Error("Settings() for plugin without schema reported ok")
+	}
+}
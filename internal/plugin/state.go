@@ -0,0 +1,181 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/boozedog/smoothbrain/internal/store"
+)
+
+// StateStore is a namespaced key-value store for plugin state. Values are
+// stored as JSON. A zero TTL means the entry never expires.
+type StateStore interface {
+	// Get decodes the value for key into v. It reports false if the key is
+	// missing or expired.
+	Get(ctx context.Context, key string, v any) (bool, error)
+	Set(ctx context.Context, key string, v any, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	// List returns the live keys with the given prefix, sorted.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// CompareAndSwap sets key to newV only if its current value equals old.
+	// A nil old means the key must not exist. It reports whether the swap
+	// happened.
+	CompareAndSwap(ctx context.Context, key string, old, newV any, ttl time.Duration) (bool, error)
+}
+
+// StateAware is implemented by plugins that persist state across restarts.
+type StateAware interface {
+	SetState(state StateStore)
+}
+
+// sqlState implements StateStore over the plugin_state table.
+type sqlState struct {
+	store     *store.Store
+	namespace string
+}
+
+// NewStateStore returns a StateStore scoped to namespace, usually the plugin name.
+func NewStateStore(s *store.Store, namespace string) StateStore {
+	return &sqlState{store: s, namespace: namespace}
+}
+
+func expiry(ttl time.Duration) any {
+	if ttl <= 0 {
+		return nil
+	}
+	return time.Now().UTC().Add(ttl)
+}
+
+func (s *sqlState) Get(ctx context.Context, key string, v any) (bool, error) {
+	var raw string
+	err := s.store.DB().QueryRowContext(ctx,
+		`SELECT value FROM plugin_state WHERE plugin = ? AND key = ? AND (expires_at IS NULL OR expires_at > ?)`,
+		s.namespace, key, time.Now().UTC(),
+	).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("state get %s/%s: %w", s.namespace, key, err)
+	}
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		return false, fmt.Errorf("state decode %s/%s: %w", s.namespace, key, err)
+	}
+	return true, nil
+}
+
+func (s *sqlState) Set(ctx context.Context, key string, v any, ttl time.Duration) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("state encode %s/%s: %w", s.namespace, key, err)
+	}
+	err = s.store.Write(ctx, func(tx *sql.Tx) error {
+		return upsertState(ctx, tx, s.namespace, key, data, ttl)
+	})
+	if err != nil {
+		return fmt.Errorf("state set %s/%s: %w", s.namespace, key, err)
+	}
+	return nil
+}
+
+// upsertState writes a value and opportunistically drops expired entries in
+// the namespace so TTL-only keys (e.g. nonces) do not accumulate.
+func upsertState(ctx context.Context, tx *sql.Tx, namespace, key string, data []byte, ttl time.Duration) error {
+	now := time.Now().UTC()
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM plugin_state WHERE plugin = ? AND expires_at IS NOT NULL AND expires_at <= ?`,
+		namespace, now,
+	); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO plugin_state (plugin, key, value, updated_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(plugin, key) DO UPDATE SET
+			value = excluded.value,
+			updated_at = excluded.updated_at,
+			expires_at = excluded.expires_at`,
+		namespace, key, string(data), now, expiry(ttl),
+	)
+	return err
+}
+
+func (s *sqlState) Delete(ctx context.Context, key string) error {
+	if _, err := s.store.Exec(ctx, `DELETE FROM plugin_state WHERE plugin = ? AND key = ?`, s.namespace, key); err != nil {
+		return fmt.Errorf("state delete %s/%s: %w", s.namespace, key, err)
+	}
+	return nil
+}
+
+func (s *sqlState) List(ctx context.Context, prefix string) ([]string, error) {
+	rows, err := s.store.DB().QueryContext(ctx,
+		`SELECT key FROM plugin_state WHERE plugin = ? AND (expires_at IS NULL OR expires_at > ?) ORDER BY key`,
+		s.namespace, time.Now().UTC(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("state list %s: %w", s.namespace, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var keys []string
+	for rows.Next() {
+		var k string
+		if err := rows.Scan(&k); err != nil {
+			return nil, fmt.Errorf("state list %s: %w", s.namespace, err)
+		}
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	return keys, rows.Err()
+}
+
+func (s *sqlState) CompareAndSwap(ctx context.Context, key string, old, newV any, ttl time.Duration) (bool, error) {
+	data, err := json.Marshal(newV)
+	if err != nil {
+		return false, fmt.Errorf("state encode %s/%s: %w", s.namespace, key, err)
+	}
+
+	var want []byte
+	if old != nil {
+		if want, err = json.Marshal(old); err != nil {
+			return false, fmt.Errorf("state encode %s/%s: %w", s.namespace, key, err)
+		}
+	}
+
+	// The read and the write share the writer's transaction, so no other
+	// write can interleave.
+	swapped := false
+	err = s.store.Write(ctx, func(tx *sql.Tx) error {
+		var current string
+		err := tx.QueryRowContext(ctx,
+			`SELECT value FROM plugin_state WHERE plugin = ? AND key = ? AND (expires_at IS NULL OR expires_at > ?)`,
+			s.namespace, key, time.Now().UTC(),
+		).Scan(&current)
+		exists := err == nil
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if old == nil && exists {
+			return nil
+		}
+		if old != nil && (!exists || !bytes.Equal([]byte(current), want)) {
+			return nil
+		}
+		if err := upsertState(ctx, tx, s.namespace, key, data, ttl); err != nil {
+			return err
+		}
+		swapped = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("state cas %s/%s: %w", s.namespace, key, err)
+	}
+	return swapped, nil
+}
@@ -0,0 +1,153 @@
+package plugin
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/boozedog/smoothbrain/internal/store"
+)
+
+func newTestState(t *testing.T, namespace string) StateStore {
+	t.Helper()
+	st, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	return NewStateStore(st, namespace)
+}
+
+func TestState_SetGetDelete(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t, "alpha")
+
+	type cursor struct {
+		ID    string `json:"id"`
+		Count int    `json:"count"`
+	}
+	if err := s.Set(ctx, "cursor", cursor{ID: "abc", Count: 3}, 0); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	var got cursor
+	ok, err := s.Get(ctx, "cursor", &got)
+	if err != nil || !ok {
+		t.Fatalf("Get() = %v, %v; want found", ok, err)
+	}
+	if got.ID != "abc" || got.Count != 3 {
+		t.Errorf("Get() = %+v, want {abc 3}", got)
+	}
+
+	if err := s.Delete(ctx, "cursor"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := s.Get(ctx, "cursor", &got); ok {
+		t.Error("expected key to be gone after Delete")
+	}
+}
+
+func TestState_NamespacesAreIsolated(t *testing.T) {
+	ctx := context.Background()
+	st, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	a := NewStateStore(st, "a")
+	b := NewStateStore(st, "b")
+
+	if err := a.Set(ctx, "k", "from-a", 0); err != nil {
+		t.Fatal(err)
+	}
+	var v string
+	if ok, _ := b.Get(ctx, "k", &v); ok {
+		t.Error("namespace b should not see a's key")
+	}
+}
+
+func TestState_TTLExpires(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t, "ttl")
+
+	if err := s.Set(ctx, "short", 1, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	var v int
+	if ok, _ := s.Get(ctx, "short", &v); !ok {
+		t.Fatal("expected key before expiry")
+	}
+	time.Sleep(80 * time.Millisecond)
+	if ok, _ := s.Get(ctx, "short", &v); ok {
+		t.Error("expected key to be expired")
+	}
+}
+
+func TestState_List(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t, "list")
+	for _, k := range []string{"nonce:b", "nonce:a", "cursor"} {
+		if err := s.Set(ctx, k, true, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	keys, err := s.List(ctx, "nonce:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(keys, []string{"nonce:a", "nonce:b"}) {
+		t.Errorf("List() = %v, want [nonce:a nonce:b]", keys)
+	}
+}
+
+func TestState_CompareAndSwap(t *testing.T) {
+	ctx := context.Background()
+	s := newTestState(t, "cas")
+
+	ok, err := s.CompareAndSwap(ctx, "k", nil, "v1", 0)
+	if err != nil || !ok {
+		t.Fatalf("insert-if-absent = %v, %v; want true", ok, err)
+	}
+	if ok, _ := s.CompareAndSwap(ctx, "k", nil, "v2", 0); ok {
+		t.Error("insert-if-absent should fail when key exists")
+	}
+	if ok, _ := s.CompareAndSwap(ctx, "k", "wrong", "v2", 0); ok {
+		t.Error("swap should fail when old value does not match")
+	}
+	if ok, _ := s.CompareAndSwap(ctx, "k", "v1", "v2", 0); !ok {
+		t.Error("swap should succeed when old value matches")
+	}
+	var v string
+	if _, err := s.Get(ctx, "k", &v); err != nil || v != "v2" {
+		t.Errorf("Get() = %q, %v; want v2", v, err)
+	}
+}
+
+func TestRegistry_InitAll_InjectsState(t *testing.T) {
+	st, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	r := NewRegistry(slog.New(slog.NewTextHandler(io.Discard, nil)), st)
+	p := &stubStatePlugin{stubPlugin: stubPlugin{name: "stateful"}}
+	r.Register(p)
+	if err := r.InitAll(nil); err != nil {
+		t.Fatal(err)
+	}
+	if p.state == nil {
+		t.Fatal("expected state store to be injected")
+	}
+	if err := p.state.Set(context.Background(), "k", 1, 0); err != nil {
+		t.Fatalf("injected store Set() error = %v", err)
+	}
+}
+
+type stubStatePlugin struct {
+	stubPlugin
+	state StateStore
+}
+
+func (s *stubStatePlugin) SetState(state StateStore) { s.state = state }
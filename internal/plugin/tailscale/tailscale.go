@@ -9,6 +9,10 @@ import (
 	"tailscale.com/tsnet"
 )
 
+func init() {
+	plugin.RegisterType("tailscale", func(log *slog.Logger) plugin.Plugin { return New(log) })
+}
+
 type Plugin struct {
 	log    *slog.Logger
 	server *tsnet.Server
@@ -23,6 +27,10 @@ func (p *Plugin) Init(_ json.RawMessage) error                     { return nil
 func (p *Plugin) Start(_ context.Context, _ plugin.EventBus) error { return nil }
 func (p *Plugin) Stop() error                                      { return nil }
 
+// ConfigSchema is empty: tsnet is configured under "tailscale" at the top
+// level, not here.
+func (p *Plugin) ConfigSchema() []plugin.ConfigField { return nil }
+
 func (p *Plugin) SetServer(s *tsnet.Server) { p.server = s }
 
 func (p *Plugin) HealthCheck(ctx context.Context) plugin.HealthStatus {
@@ -23,28 +23,49 @@ import (
 
 const maxBodySize = 1 << 20 // 1 MB
 
+func init() {
+	plugin.RegisterType("td", func(log *slog.Logger) plugin.Plugin { return New(log) })
+}
+
 type Config struct {
 	WebhookSecret     string `json:"webhook_secret"`
 	WebhookSecretFile string `json:"webhook_secret_file"`
 }
 
-type Plugin struct {
-	cfg Config
-	log *slog.Logger
-	bus plugin.EventBus
+func (p *Plugin) ConfigSchema() []plugin.ConfigField {
+	return []plugin.ConfigField{
+		{Name: "webhook_secret", Type: plugin.FieldString, Secret: true, Description: "HMAC secret for webhook signatures"},
+		{Name: "webhook_secret_file", Type: plugin.FieldString, Description: "File containing the webhook secret"},
+	}
+}
 
+type Plugin struct {
+	name string
+	cfg  Config
+	log  *slog.Logger
+	bus  plugin.EventBus
+
+	// state persists nonces across restarts; the in-memory map is used when
+	// no state store has been injected.
+	state   plugin.StateStore
 	nonceMu sync.Mutex
 	nonces  map[string]time.Time // signature -> time seen
 }
 
 func New(log *slog.Logger) *Plugin {
 	return &Plugin{
+		name:   "td",
 		log:    log,
 		nonces: make(map[string]time.Time),
 	}
 }
 
-func (p *Plugin) Name() string { return "td" }
+func (p *Plugin) Name() string { return p.name }
+
+// SetName sets the instance name, used as the event source and webhook path.
+func (p *Plugin) SetName(name string) { p.name = name }
+
+func (p *Plugin) SetState(state plugin.StateStore) { p.state = state }
 
 func (p *Plugin) Init(cfg json.RawMessage) error {
 	if err := json.Unmarshal(cfg, &p.cfg); err != nil {
@@ -68,9 +89,10 @@ func (p *Plugin) Start(ctx context.Context, bus plugin.EventBus) error {
 
 func (p *Plugin) Stop() error { return nil }
 
-// RegisterWebhook sets up the POST /hooks/td endpoint.
+// RegisterWebhook sets up the POST /hooks/<name> endpoint (/hooks/td by
+// default).
 func (p *Plugin) RegisterWebhook(reg plugin.WebhookRegistrar) {
-	reg.RegisterWebhook("td", p.handleWebhook)
+	reg.RegisterWebhook(p.name, p.handleWebhook)
 }
 
 func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
@@ -92,7 +114,7 @@ func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "unauthorized: timestamp too old", http.StatusUnauthorized)
 			return
 		}
-		if p.isReplayedNonce(sig) {
+		if p.isReplayedNonce(r.Context(), sig) {
 			http.Error(w, "unauthorized: replayed request", http.StatusUnauthorized)
 			return
 		}
@@ -118,7 +140,7 @@ func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
 
 	event := plugin.Event{
 		ID:        uuid.NewString(),
-		Source:    "td",
+		Source:    p.name,
 		Type:      eventType,
 		Payload:   payloadMap,
 		Timestamp: time.Now(),
@@ -175,8 +197,19 @@ func isTimestampFresh(ts string, maxAge time.Duration) bool {
 const nonceWindow = 5*time.Minute + 30*time.Second
 
 // isReplayedNonce returns true if the signature was already seen within the
-// replay window. It evicts expired entries on each call.
-func (p *Plugin) isReplayedNonce(sig string) bool {
+// replay window. Persisted nonces expire via their TTL; the in-memory fallback
+// evicts expired entries on each call.
+func (p *Plugin) isReplayedNonce(ctx context.Context, sig string) bool {
+	if p.state != nil {
+		// Insert-if-absent; a failed swap means the nonce is still live.
+		stored, err := p.state.CompareAndSwap(ctx, "nonce:"+sig, nil, time.Now().Unix(), nonceWindow)
+		if err != nil {
+			p.log.Error("td: record nonce", "error", err)
+			return true
+		}
+		return !stored
+	}
+
 	now := time.Now()
 
 	p.nonceMu.Lock()
@@ -1,6 +1,7 @@
 package td
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
@@ -15,6 +16,7 @@ import (
 	"time"
 
 	"github.com/boozedog/smoothbrain/internal/plugin"
+	"github.com/boozedog/smoothbrain/internal/store"
 )
 
 func TestVerifySignatureValid(t *testing.T) {
@@ -188,7 +190,7 @@ func TestNonceEviction(t *testing.T) {
 	p.nonces["old-sig"] = old
 
 	// A new check should evict the old entry.
-	if p.isReplayedNonce("new-sig") {
+	if p.isReplayedNonce(context.Background(), "new-sig") {
 		t.Fatal("new signature should not be a replay")
 	}
 
@@ -244,3 +246,29 @@ func TestWebhookResponseJSON(t *testing.T) {
 		t.Errorf("want status=accepted, got %q", resp["status"])
 	}
 }
+
+func TestNoncePersistedAcrossRestart(t *testing.T) {
+	st, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+
+	secret := "persist-secret"
+	body := []byte(`{"actions":[{"action_type":"update","entity_type":"ticket","id":"t1","entity_id":"td-001"}]}`)
+	ts := time.Now().UTC().Format(time.RFC3339)
+	sig := signRequest(secret, ts, body)
+
+	first := newTestPlugin(secret)
+	first.SetState(plugin.NewStateStore(st, "td"))
+	if w := doWebhook(first, ts, body, sig); w.Code != http.StatusOK {
+		t.Fatalf("first request: want 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// A fresh plugin instance sharing the store simulates a restart.
+	restarted := newTestPlugin(secret)
+	restarted.SetState(plugin.NewStateStore(st, "td"))
+	if w := doWebhook(restarted, ts, body, sig); w.Code != http.StatusUnauthorized {
+		t.Fatalf("replay after restart: want 401, got %d: %s", w.Code, w.Body.String())
+	}
+}
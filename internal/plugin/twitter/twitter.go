@@ -17,6 +17,10 @@ import (
 	"github.com/google/uuid"
 )
 
+func init() {
+	plugin.RegisterType("twitter", func(log *slog.Logger) plugin.Plugin { return New(log) })
+}
+
 type Config struct {
 	BearerToken     string `json:"bearer_token"`
 	BearerTokenFile string `json:"bearer_token_file"`
@@ -25,24 +29,44 @@ type Config struct {
 	PollInterval    string `json:"poll_interval"`
 }
 
+func (p *Plugin) ConfigSchema() []plugin.ConfigField {
+	return []plugin.ConfigField{
+		{Name: "bearer_token", Type: plugin.FieldString, Secret: true, Description: "X API bearer token"},
+		{Name: "bearer_token_file", Type: plugin.FieldString, Description: "File containing the bearer token"},
+		{Name: "list_id", Type: plugin.FieldString, Description: "List to poll; idle when empty"},
+		{Name: "query_filter", Type: plugin.FieldString, Description: "Search operators appended to the list query"},
+		{Name: "poll_interval", Type: plugin.FieldDuration, Default: "60s"},
+	}
+}
+
 type Plugin struct {
+	name          string
 	cfg           Config
 	bearerToken   string
 	pollInterval  time.Duration
 	client        *http.Client
 	log           *slog.Logger
+	state         plugin.StateStore
 	lastFetchOK   atomic.Bool
 	lastFetchTime atomic.Int64
 }
 
 func New(log *slog.Logger) *Plugin {
 	return &Plugin{
+		name:   "twitter",
 		client: &http.Client{Timeout: 30 * time.Second},
 		log:    log,
 	}
 }
 
-func (p *Plugin) Name() string { return "twitter" }
+func (p *Plugin) Name() string { return p.name }
+
+// SetName sets the instance name, carried as the source of tweet events.
+func (p *Plugin) SetName(name string) { p.name = name }
+
+func (p *Plugin) SetState(state plugin.StateStore) { p.state = state }
+
+func (p *Plugin) SetTransport(rt http.RoundTripper) { p.client.Transport = rt }
 
 func (p *Plugin) Init(cfg json.RawMessage) error {
 	p.cfg = Config{PollInterval: "60s"}
@@ -88,22 +112,50 @@ func (p *Plugin) poll(ctx context.Context, bus plugin.EventBus) {
 	ticker := time.NewTicker(p.pollInterval)
 	defer ticker.Stop()
 
-	var sinceID string
+	sinceID := p.loadSinceID(ctx)
 
 	// Do an initial poll immediately.
-	p.log.Debug("twitter: starting poller", "list_id", p.cfg.ListID, "interval", p.pollInterval)
-	sinceID = p.fetch(ctx, bus, sinceID)
+	p.log.Debug("twitter: starting poller", "list_id", p.cfg.ListID, "interval", p.pollInterval, "since_id", sinceID)
+	sinceID = p.advance(ctx, sinceID, p.fetch(ctx, bus, sinceID))
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			sinceID = p.fetch(ctx, bus, sinceID)
+			sinceID = p.advance(ctx, sinceID, p.fetch(ctx, bus, sinceID))
 		}
 	}
 }
 
+// sinceIDKey scopes the persisted cursor to the list being polled.
+func (p *Plugin) sinceIDKey() string {
+	return "since_id:" + p.cfg.ListID
+}
+
+// loadSinceID restores the last seen tweet ID so restarts do not re-emit tweets.
+func (p *Plugin) loadSinceID(ctx context.Context) string {
+	if p.state == nil {
+		return ""
+	}
+	var id string
+	if _, err := p.state.Get(ctx, p.sinceIDKey(), &id); err != nil {
+		p.log.Warn("twitter: load since_id", "error", err)
+	}
+	return id
+}
+
+// advance persists next when it differs from prev and returns it.
+func (p *Plugin) advance(ctx context.Context, prev, next string) string {
+	if next == prev || p.state == nil {
+		return next
+	}
+	if err := p.state.Set(ctx, p.sinceIDKey(), next, 0); err != nil {
+		p.log.Warn("twitter: save since_id", "error", err)
+	}
+	return next
+}
+
 // fetch calls the X API and emits events for each tweet. Returns the updated sinceID.
 func (p *Plugin) fetch(ctx context.Context, bus plugin.EventBus, sinceID string) string {
 	query := fmt.Sprintf("list:%s", p.cfg.ListID)
@@ -178,7 +230,7 @@ func (p *Plugin) fetch(ctx context.Context, bus plugin.EventBus, sinceID string)
 			author := users[tw.AuthorID]
 			event := plugin.Event{
 				ID:        uuid.NewString(),
-				Source:    "twitter",
+				Source:    p.name,
 				Type:      "tweet",
 				Timestamp: time.Now(),
 				Payload: map[string]any{
@@ -0,0 +1,75 @@
+package twitter
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/boozedog/smoothbrain/internal/plugin"
+	"github.com/boozedog/smoothbrain/internal/store"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+type busFunc func(plugin.Event)
+
+func (f busFunc) Emit(e plugin.Event) { f(e) }
+
+// newTestPlugin returns a plugin whose API calls are answered by a fake that
+// records the since_id it was sent and returns one tweet, newest ID 200.
+func newTestPlugin(t *testing.T, st *store.Store, sent *[]string) *Plugin {
+	t.Helper()
+	p := New(slog.New(slog.DiscardHandler))
+	p.SetState(plugin.NewStateStore(st, "twitter"))
+	if err := p.Init(json.RawMessage(`{"bearer_token":"t","list_id":"42"}`)); err != nil {
+		t.Fatal(err)
+	}
+	p.SetTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		*sent = append(*sent, r.URL.Query().Get("since_id"))
+		body := `{"data":[{"id":"200","text":"hi","author_id":"1"}],"includes":{"users":[{"id":"1","username":"u"}]},"meta":{"newest_id":"200","result_count":1}}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{}}, nil
+	}))
+	return p
+}
+
+func TestSinceIDPersistedAcrossRestart(t *testing.T) {
+	st, err := store.Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = st.Close() })
+	ctx := context.Background()
+	var sent []string
+	var emitted int
+	bus := busFunc(func(plugin.Event) { emitted++ })
+
+	first := newTestPlugin(t, st, &sent)
+	since := first.loadSinceID(ctx)
+	if since != "" {
+		t.Fatalf("initial since_id = %q, want empty", since)
+	}
+	if got := first.advance(ctx, since, first.fetch(ctx, bus, since)); got != "200" {
+		t.Fatalf("since_id after fetch = %q, want 200", got)
+	}
+
+	// A fresh instance sharing the store simulates a restart.
+	restarted := newTestPlugin(t, st, &sent)
+	since = restarted.loadSinceID(ctx)
+	if since != "200" {
+		t.Fatalf("since_id after restart = %q, want 200", since)
+	}
+	restarted.fetch(ctx, bus, since)
+
+	if len(sent) != 2 || sent[0] != "" || sent[1] != "200" {
+		t.Errorf("since_id sent = %q, want [\"\" \"200\"]", sent)
+	}
+	if emitted != 2 {
+		t.Errorf("emitted %d events, want 2", emitted)
+	}
+}
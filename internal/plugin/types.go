@@ -0,0 +1,49 @@
+package plugin
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+var (
+	typesMu sync.RWMutex
+	types   = make(map[string]Factory)
+)
+
+// RegisterType makes a plugin type available to configuration. It is meant
+// to be called from plugin package init functions and panics on an empty or
+// duplicate type.
+func RegisterType(typ string, f Factory) {
+	typesMu.Lock()
+	defer typesMu.Unlock()
+	if typ == "" {
+		panic("plugin: RegisterType with empty type")
+	}
+	if _, ok := types[typ]; ok {
+		panic(fmt.Sprintf("plugin: duplicate type %q", typ))
+	}
+	types[typ] = f
+}
+
+// Types returns the registered plugin types in alphabetical order.
+func Types() []string {
+	typesMu.RLock()
+	defer typesMu.RUnlock()
+	return slices.Sorted(maps.Keys(types))
+}
+
+func registeredTypes() map[string]Factory {
+	typesMu.RLock()
+	defer typesMu.RUnlock()
+	return maps.Clone(types)
+}
+
+// unregisterType removes a type added with RegisterType, so tests can leave
+// the registry as they found it.
+func unregisterType(typ string) {
+	typesMu.Lock()
+	defer typesMu.Unlock()
+	delete(types, typ)
+}
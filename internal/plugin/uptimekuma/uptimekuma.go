@@ -18,22 +18,37 @@ import (
 
 const maxBodySize = 1 << 20 // 1 MB
 
+func init() {
+	plugin.RegisterType("uptime-kuma", func(log *slog.Logger) plugin.Plugin { return New(log) })
+}
+
 type Config struct {
 	WebhookToken     string `json:"webhook_token"`
 	WebhookTokenFile string `json:"webhook_token_file"`
 }
 
+func (p *Plugin) ConfigSchema() []plugin.ConfigField {
+	return []plugin.ConfigField{
+		{Name: "webhook_token", Type: plugin.FieldString, Secret: true, Description: "Token required on webhook requests"},
+		{Name: "webhook_token_file", Type: plugin.FieldString, Description: "File containing the webhook token"},
+	}
+}
+
 type Plugin struct {
-	cfg Config
-	log *slog.Logger
-	bus plugin.EventBus
+	name string
+	cfg  Config
+	log  *slog.Logger
+	bus  plugin.EventBus
 }
 
 func New(log *slog.Logger) *Plugin {
-	return &Plugin{log: log}
+	return &Plugin{name: "uptime-kuma", log: log}
 }
 
-func (p *Plugin) Name() string { return "uptime-kuma" }
+func (p *Plugin) Name() string { return p.name }
+
+// SetName renames the instance; its webhook moves to /hooks/<name>.
+func (p *Plugin) SetName(name string) { p.name = name }
 
 func (p *Plugin) Init(cfg json.RawMessage) error {
 	if err := json.Unmarshal(cfg, &p.cfg); err != nil {
@@ -57,9 +72,10 @@ func (p *Plugin) Start(ctx context.Context, bus plugin.EventBus) error {
 
 func (p *Plugin) Stop() error { return nil }
 
-// RegisterWebhook sets up the POST /hooks/uptime-kuma endpoint.
+// RegisterWebhook sets up the POST /hooks/<name> endpoint (/hooks/uptime-kuma
+// by default).
 func (p *Plugin) RegisterWebhook(reg plugin.WebhookRegistrar) {
-	reg.RegisterWebhook("uptime-kuma", p.handleWebhook)
+	reg.RegisterWebhook(p.name, p.handleWebhook)
 }
 
 func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
@@ -85,7 +101,7 @@ func (p *Plugin) handleWebhook(w http.ResponseWriter, r *http.Request) {
 
 	event := plugin.Event{
 		ID:        uuid.NewString(),
-		Source:    "uptime-kuma",
+		Source:    p.name,
 		Type:      "alert",
 		Payload:   payload,
 		Timestamp: time.Now(),
@@ -16,11 +16,22 @@ import (
 
 const defaultEndpoint = "https://webmd.booze.dog/"
 
+func init() {
+	plugin.RegisterType("webmd", func(log *slog.Logger) plugin.Plugin { return New(log) })
+}
+
 type Config struct {
 	Endpoint string `json:"endpoint"`
 }
 
+func (p *Plugin) ConfigSchema() []plugin.ConfigField {
+	return []plugin.ConfigField{
+		{Name: "endpoint", Type: plugin.FieldString, Default: defaultEndpoint, Description: "URL-to-markdown service"},
+	}
+}
+
 type Plugin struct {
+	name   string
 	cfg    Config
 	client *http.Client
 	log    *slog.Logger
@@ -28,12 +39,18 @@ type Plugin struct {
 
 func New(log *slog.Logger) *Plugin {
 	return &Plugin{
+		name:   "webmd",
 		client: &http.Client{Timeout: 60 * time.Second},
 		log:    log,
 	}
 }
 
-func (p *Plugin) Name() string { return "webmd" }
+func (p *Plugin) Name() string { return p.name }
+
+// SetName sets the instance name used in route pipelines.
+func (p *Plugin) SetName(name string) { p.name = name }
+
+func (p *Plugin) SetTransport(rt http.RoundTripper) { p.client.Transport = rt }
 
 func (p *Plugin) Init(cfg json.RawMessage) error {
 	p.cfg.Endpoint = defaultEndpoint
@@ -48,6 +65,18 @@ func (p *Plugin) Init(cfg json.RawMessage) error {
 func (p *Plugin) Start(ctx context.Context, bus plugin.EventBus) error { return nil }
 func (p *Plugin) Stop() error                                          { return nil }
 
+func (p *Plugin) Actions() []plugin.Action {
+	return []plugin.Action{
+		{
+			Name:        "fetch",
+			Description: "Fetches the URL in the message as markdown",
+			Params:      []plugin.ConfigField{},
+			Reads:       []string{"message"},
+			Writes:      []string{"summary", "file_content", "file_name"},
+		},
+	}
+}
+
 func (p *Plugin) Transform(ctx context.Context, event plugin.Event, action string, params map[string]any) (plugin.Event, error) {
 	switch action {
 	case "fetch":
@@ -15,13 +15,26 @@ import (
 	"github.com/boozedog/smoothbrain/internal/plugin"
 )
 
+func init() {
+	plugin.RegisterType("xai", func(log *slog.Logger) plugin.Plugin { return New(log) })
+}
+
 type Config struct {
 	Model      string `json:"model"`
 	APIKey     string `json:"api_key"`
 	APIKeyFile string `json:"api_key_file"`
 }
 
+func (p *Plugin) ConfigSchema() []plugin.ConfigField {
+	return []plugin.ConfigField{
+		{Name: "model", Type: plugin.FieldString, Default: "grok-3"},
+		{Name: "api_key", Type: plugin.FieldString, Secret: true},
+		{Name: "api_key_file", Type: plugin.FieldString, Description: "File containing the API key"},
+	}
+}
+
 type Plugin struct {
+	name   string
 	cfg    Config
 	apiKey string
 	client *http.Client
@@ -30,12 +43,18 @@ type Plugin struct {
 
 func New(log *slog.Logger) *Plugin {
 	return &Plugin{
+		name:   "xai",
 		client: &http.Client{Timeout: 120 * time.Second},
 		log:    log,
 	}
 }
 
-func (p *Plugin) Name() string { return "xai" }
+func (p *Plugin) Name() string { return p.name }
+
+// SetName sets the instance name that routes use to reach this model.
+func (p *Plugin) SetName(name string) { p.name = name }
+
+func (p *Plugin) SetTransport(rt http.RoundTripper) { p.client.Transport = rt }
 
 func (p *Plugin) Init(cfg json.RawMessage) error {
 	p.cfg = Config{Model: "grok-3"}
@@ -60,6 +79,19 @@ func (p *Plugin) Start(ctx context.Context, bus plugin.EventBus) error {
 
 func (p *Plugin) Stop() error { return nil }
 
+func (p *Plugin) Actions() []plugin.Action {
+	return []plugin.Action{
+		{
+			Name:        "summarize",
+			Description: "Sends the payload to the model and adds its response as summary",
+			Params: []plugin.ConfigField{
+				{Name: "prompt", Type: plugin.FieldString, Description: "System prompt; defaults to a chat alert summary"},
+			},
+			Writes: []string{"summary"},
+		},
+	}
+}
+
 func (p *Plugin) Transform(ctx context.Context, event plugin.Event, action string, params map[string]any) (plugin.Event, error) {
 	switch action {
 	case "summarize":
@@ -0,0 +1,243 @@
+// Package schedule parses supervisor schedules: five-field cron expressions,
+// @-descriptors, and plain Go durations.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule computes fire times.
+type Schedule interface {
+	// Next returns the first fire time strictly after t, or the zero time if
+	// there is none within the next five years.
+	Next(t time.Time) time.Time
+}
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses spec, evaluating cron fields in loc (nil means time.Local).
+// Accepted forms:
+//
+//	"*/15 9-17 * * mon-fri"   five-field cron: minute hour day-of-month month day-of-week
+//	"@weekly", "@monthly"     descriptors (@yearly, @monthly, @weekly, @daily, @hourly)
+//	"@every 90m", "1h"        fixed interval
+//	"daily@07:30"             legacy form, equivalent to "30 7 * * *"
+func Parse(spec string, loc *time.Location) (Schedule, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	spec = strings.TrimSpace(spec)
+
+	switch {
+	case spec == "":
+		return nil, fmt.Errorf("schedule: empty spec")
+	case strings.HasPrefix(spec, "@every "):
+		return parseInterval(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+	case strings.HasPrefix(spec, "@"):
+		expr, ok := descriptors[strings.ToLower(spec)]
+		if !ok {
+			return nil, fmt.Errorf("schedule: unknown descriptor %q", spec)
+		}
+		return parseCron(expr, loc)
+	case strings.HasPrefix(spec, "daily@"):
+		hh, mm, ok := strings.Cut(strings.TrimPrefix(spec, "daily@"), ":")
+		if !ok {
+			return nil, fmt.Errorf("schedule: expected daily@HH:MM, got %q", spec)
+		}
+		return parseCron(mm+" "+hh+" * * *", loc)
+	case len(strings.Fields(spec)) == 1:
+		return parseInterval(spec)
+	default:
+		return parseCron(spec, loc)
+	}
+}
+
+type interval time.Duration
+
+func parseInterval(s string) (Schedule, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, fmt.Errorf("schedule: %q is neither a cron expression nor a duration", s)
+	}
+	if d < time.Second {
+		return nil, fmt.Errorf("schedule: interval %s must be at least 1s", d)
+	}
+	return interval(d), nil
+}
+
+func (i interval) Next(t time.Time) time.Time { return t.Add(time.Duration(i)) }
+
+// bits is a set of allowed values for one cron field.
+type bits uint64
+
+func (b bits) has(v int) bool { return b&(1<<uint(v)) != 0 }
+
+type cron struct {
+	minute, hour, dom, month, dow bits
+	// Per Vixie cron, when both day fields are restricted a day matches if
+	// either does.
+	domStar, dowStar bool
+	loc              *time.Location
+}
+
+type field struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day-of-month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Day-of-week accepts 7 as an alias for Sunday.
+	dowField = field{name: "day-of-week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+func parseCron(expr string, loc *time.Location) (Schedule, error) {
+	f := strings.Fields(expr)
+	if len(f) != 5 {
+		return nil, fmt.Errorf("schedule: cron expression %q must have 5 fields, got %d", expr, len(f))
+	}
+	c := &cron{loc: loc}
+	var err error
+	if c.minute, err = minuteField.parse(f[0]); err != nil {
+		return nil, err
+	}
+	if c.hour, err = hourField.parse(f[1]); err != nil {
+		return nil, err
+	}
+	if c.dom, err = domField.parse(f[2]); err != nil {
+		return nil, err
+	}
+	if c.month, err = monthField.parse(f[3]); err != nil {
+		return nil, err
+	}
+	if c.dow, err = dowField.parse(f[4]); err != nil {
+		return nil, err
+	}
+	if c.dow.has(7) {
+		c.dow |= 1
+	}
+	c.domStar = f[2] == "*" || f[2] == "?"
+	c.dowStar = f[4] == "*" || f[4] == "?"
+	return c, nil
+}
+
+// parse handles comma-separated lists of "*", "N", "A-B", each with an
+// optional "/step".
+func (f field) parse(s string) (bits, error) {
+	var b bits
+	for part := range strings.SplitSeq(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepStr)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("schedule: %s: invalid step %q", f.name, stepStr)
+			}
+			step = n
+		}
+
+		var lo, hi int
+		switch {
+		case rng == "*" || rng == "?":
+			lo, hi = f.min, f.max
+		case strings.Contains(rng, "-"):
+			a, z, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(a); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(z); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("schedule: %s: range %q is reversed", f.name, rng)
+			}
+		default:
+			v, err := f.value(rng)
+			if err != nil {
+				return 0, err
+			}
+			lo, hi = v, v
+			if hasStep {
+				hi = f.max
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			b |= 1 << uint(v)
+		}
+	}
+	return b, nil
+}
+
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("schedule: %s: invalid value %q", f.name, s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("schedule: %s: %d out of range %d-%d", f.name, v, f.min, f.max)
+	}
+	return v, nil
+}
+
+func (c *cron) dayMatches(t time.Time) bool {
+	dom := c.dom.has(t.Day())
+	dow := c.dow.has(int(t.Weekday()))
+	if c.domStar || c.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func (c *cron) Next(t time.Time) time.Time {
+	// Start at the next whole minute. Stepping uses absolute time so the
+	// repeated hour of a DST fall-back is not mistaken for an earlier time.
+	t = t.In(c.loc)
+	t = t.Add(-time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond())).Add(time.Minute)
+	limit := t.Year() + 5
+
+	for t.Year() <= limit {
+		switch {
+		case !c.month.has(int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.loc)
+		case !c.hour.has(t.Hour()):
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case !c.minute.has(t.Minute()):
+			n := t.Add(time.Minute)
+			if n.Minute() == 0 && n.Hour() == t.Hour() {
+				// The clock fell back; skip the repeated hour so a task
+				// does not fire twice.
+				n = n.Add(time.Hour)
+			}
+			t = n
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,127 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone %s unavailable: %v", name, err)
+	}
+	return loc
+}
+
+func TestNext(t *testing.T) {
+	utc := time.UTC
+	base := time.Date(2025, time.March, 14, 10, 17, 30, 0, utc) // Friday
+
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"* * * * *", base, time.Date(2025, 3, 14, 10, 18, 0, 0, utc)},
+		{"*/15 * * * *", base, time.Date(2025, 3, 14, 10, 30, 0, 0, utc)},
+		{"0 9-17/4 * * *", base, time.Date(2025, 3, 14, 13, 0, 0, 0, utc)},
+		{"5,45 10 * * *", base, time.Date(2025, 3, 14, 10, 45, 0, 0, utc)},
+		{"0 8 * * mon-fri", base, time.Date(2025, 3, 17, 8, 0, 0, 0, utc)},
+		{"0 0 * * 7", base, time.Date(2025, 3, 16, 0, 0, 0, 0, utc)},
+		{"0 0 1 jan *", base, time.Date(2026, 1, 1, 0, 0, 0, 0, utc)},
+		{"0 12 31 * *", base, time.Date(2025, 3, 31, 12, 0, 0, 0, utc)},
+		{"0 0 29 feb *", base, time.Date(2028, 2, 29, 0, 0, 0, 0, utc)},
+		// Both day fields restricted: either matches (the 15th, or a Monday).
+		{"0 0 15 * mon", base, time.Date(2025, 3, 15, 0, 0, 0, 0, utc)},
+		{"@weekly", base, time.Date(2025, 3, 16, 0, 0, 0, 0, utc)},
+		{"@monthly", base, time.Date(2025, 4, 1, 0, 0, 0, 0, utc)},
+		{"@hourly", base, time.Date(2025, 3, 14, 11, 0, 0, 0, utc)},
+		{"daily@09:30", base, time.Date(2025, 3, 15, 9, 30, 0, 0, utc)},
+		{"daily@23:59", base, time.Date(2025, 3, 14, 23, 59, 0, 0, utc)},
+		{"1h", base, base.Add(time.Hour)},
+		{"@every 90m", base, base.Add(90 * time.Minute)},
+		// A fire time is strictly after the reference time.
+		{"17 10 * * *", time.Date(2025, 3, 14, 10, 17, 0, 0, utc), time.Date(2025, 3, 15, 10, 17, 0, 0, utc)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec, utc)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next(%s) = %s, want %s", tt.spec, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestNext_Timezone(t *testing.T) {
+	tokyo := mustLoad(t, "Asia/Tokyo")
+	s, err := Parse("0 9 * * *", tokyo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2025, 3, 14, 0, 30, 0, 0, time.UTC) // 09:30 in Tokyo
+	want := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)  // 09:00 next day in Tokyo
+	if got := s.Next(from); !got.Equal(want) {
+		t.Errorf("Next() = %s, want %s", got.UTC(), want)
+	}
+}
+
+func TestNext_DST(t *testing.T) {
+	ny := mustLoad(t, "America/New_York")
+
+	// Spring forward: 02:30 does not exist on 2025-03-09, so it is skipped.
+	s, _ := Parse("30 2 * * *", ny)
+	got := s.Next(time.Date(2025, 3, 9, 0, 0, 0, 0, ny))
+	if want := time.Date(2025, 3, 10, 2, 30, 0, 0, ny); !got.Equal(want) {
+		t.Errorf("spring forward Next() = %s, want %s", got, want)
+	}
+
+	// Fall back: 01:30 happens twice on 2025-11-02 but fires once.
+	s, _ = Parse("30 1 * * *", ny)
+	first := s.Next(time.Date(2025, 11, 2, 0, 0, 0, 0, ny))
+	if first.Hour() != 1 || first.Minute() != 30 || first.Day() != 2 {
+		t.Fatalf("fall back first = %s, want 01:30 on Nov 2", first)
+	}
+	second := s.Next(first)
+	if second.Day() != 3 {
+		t.Errorf("fall back second = %s, want Nov 3", second)
+	}
+
+	// Hourly jobs still run every real hour across the fall back.
+	s, _ = Parse("0 * * * *", ny)
+	at := s.Next(time.Date(2025, 11, 2, 0, 30, 0, 0, ny))
+	for range 4 {
+		next := s.Next(at)
+		if d := next.Sub(at); d <= 0 || d > 2*time.Hour {
+			t.Fatalf("hourly step from %s to %s", at, next)
+		}
+		at = next
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"* * * foo *",
+		"@fortnightly",
+		"daily@0930",
+		"daily@25:00",
+		"soon",
+		"500ms",
+	} {
+		if _, err := Parse(spec, time.UTC); err == nil {
+			t.Errorf("Parse(%q) expected error", spec)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	backupPrefix     = "smoothbrain-"
+	backupSuffix     = ".db"
+	backupTimeLayout = "20060102T150405Z"
+)
+
+// BackupOptions configures scheduled backups.
+type BackupOptions struct {
+	Dir        string
+	Interval   time.Duration
+	KeepDaily  int
+	KeepWeekly int
+}
+
+// Snapshot writes a consistent copy of the live database to dest using
+// VACUUM INTO. dest must not already exist. It runs on the writer connection,
+// so writes queue behind it.
+func (s *Store) Snapshot(dest string) error {
+	if _, err := s.wdb.Exec(`VACUUM INTO ?`, dest); err != nil {
+		return fmt.Errorf("snapshot to %s: %w", dest, err)
+	}
+	return nil
+}
+
+// Backup writes a timestamped snapshot into opts.Dir and prunes old backups
+// according to the retention settings. It returns the new backup's path.
+func (s *Store) Backup(opts BackupOptions) (string, error) {
+	if err := os.MkdirAll(opts.Dir, 0o700); err != nil {
+		return "", fmt.Errorf("creating backup dir: %w", err)
+	}
+	name := backupPrefix + time.Now().UTC().Format(backupTimeLayout) + backupSuffix
+	dest := filepath.Join(opts.Dir, name)
+	if err := s.Snapshot(dest); err != nil {
+		return "", err
+	}
+	if _, err := PruneBackups(opts.Dir, opts.KeepDaily, opts.KeepWeekly); err != nil {
+		return dest, err
+	}
+	return dest, nil
+}
+
+// StartBackups runs Backup every opts.Interval until ctx is cancelled. If
+// the newest backup in opts.Dir is already older than opts.Interval, one is
+// taken straight away, so frequent restarts do not keep postponing it.
+func (s *Store) StartBackups(ctx context.Context, opts BackupOptions, log *slog.Logger) {
+	ticker := time.NewTicker(opts.Interval)
+	go func() {
+		defer ticker.Stop()
+		backup := func() {
+			path, err := s.Backup(opts)
+			if err != nil {
+				log.Error("database backup failed", "error", err)
+				return
+			}
+			log.Info("database backup written", "path", path)
+		}
+		if backupDue(opts.Dir, opts.Interval, time.Now()) {
+			backup()
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				backup()
+			}
+		}
+	}()
+}
+
+// backupDue reports whether dir has no backup newer than interval.
+func backupDue(dir string, interval time.Duration, now time.Time) bool {
+	backups, err := listBackups(dir)
+	if err != nil {
+		return true // most likely the directory does not exist yet
+	}
+	for _, b := range backups {
+		if now.Sub(b.at) < interval {
+			return false
+		}
+	}
+	return true
+}
+
+type backupFile struct {
+	path string
+	at   time.Time
+}
+
+func listBackups(dir string) ([]backupFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading backup dir: %w", err)
+	}
+	var backups []backupFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, backupPrefix) || !strings.HasSuffix(name, backupSuffix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix)
+		at, err := time.Parse(backupTimeLayout, stamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), at: at})
+	}
+	// Newest first.
+	slices.SortFunc(backups, func(a, b backupFile) int { return b.at.Compare(a.at) })
+	return backups, nil
+}
+
+// PruneBackups keeps the newest backup of each of the last keepDaily days and
+// of each of the last keepWeekly ISO weeks, removing the rest. The newest
+// backup is always kept. It returns the removed paths.
+func PruneBackups(dir string, keepDaily, keepWeekly int) ([]string, error) {
+	backups, err := listBackups(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	days := make(map[string]bool)
+	weeks := make(map[string]bool)
+	var removed []string
+	for i, b := range backups {
+		keep := i == 0
+		day := b.at.Format(time.DateOnly)
+		if !days[day] && len(days) < keepDaily {
+			days[day] = true
+			keep = true
+		}
+		year, week := b.at.ISOWeek()
+		wk := fmt.Sprintf("%d-%02d", year, week)
+		if !weeks[wk] && len(weeks) < keepWeekly {
+			weeks[wk] = true
+			keep = true
+		}
+		if keep {
+			continue
+		}
+		if err := os.Remove(b.path); err != nil {
+			return removed, fmt.Errorf("removing old backup: %w", err)
+		}
+		removed = append(removed, b.path)
+	}
+	return removed, nil
+}
+
+// ValidateBackup checks that path is an intact smoothbrain database whose
+// schema is not newer than the migrations known to this binary.
+func ValidateBackup(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("backup: open: %w", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var integrity string
+	if err := db.QueryRow(`PRAGMA integrity_check`).Scan(&integrity); err != nil {
+		return fmt.Errorf("backup: not a readable SQLite database: %w", err)
+	}
+	if integrity != "ok" {
+		return fmt.Errorf("backup: integrity check failed: %s", integrity)
+	}
+
+	rows, err := db.Query(`SELECT component, MAX(version) FROM schema_migrations GROUP BY component`)
+	if err != nil {
+		return fmt.Errorf("backup: no schema_migrations table: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	versions := make(map[string]int)
+	for rows.Next() {
+		var component string
+		var version int
+		if err := rows.Scan(&component, &version); err != nil {
+			return fmt.Errorf("backup: scan schema_migrations: %w", err)
+		}
+		versions[component] = version
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("backup: read schema_migrations: %w", err)
+	}
+
+	if versions[CoreComponent] == 0 {
+		return fmt.Errorf("backup: missing core schema version")
+	}
+	for component, version := range versions {
+		latest := LatestVersion(component)
+		if latest > 0 && version > latest {
+			return fmt.Errorf("backup: %s schema version %d is newer than this binary supports (%d)", component, version, latest)
+		}
+	}
+	return nil
+}
+
+// Restore validates src and swaps it in as the database at dst. The previous
+// database, with its WAL files, is kept alongside under a ".pre-restore"
+// suffix whose path is returned. The server must not be running.
+func Restore(src, dst string) (string, error) {
+	if err := ValidateBackup(src); err != nil {
+		return "", err
+	}
+
+	tmp := dst + ".restore-tmp"
+	if err := copyFile(src, tmp); err != nil {
+		return "", fmt.Errorf("restore: copy: %w", err)
+	}
+
+	var prev string
+	if _, err := os.Stat(dst); err == nil {
+		prev = dst + ".pre-restore-" + time.Now().UTC().Format(backupTimeLayout)
+		if err := os.Rename(dst, prev); err != nil {
+			_ = os.Remove(tmp)
+			return "", fmt.Errorf("restore: move current database: %w", err)
+		}
+	}
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if _, err := os.Stat(dst + suffix); err != nil {
+			continue
+		}
+		if prev == "" {
+			_ = os.Remove(dst + suffix)
+			continue
+		}
+		if err := os.Rename(dst+suffix, prev+suffix); err != nil {
+			return prev, fmt.Errorf("restore: move %s: %w", suffix, err)
+		}
+	}
+
+	if err := os.Rename(tmp, dst); err != nil {
+		return prev, fmt.Errorf("restore: install: %w", err)
+	}
+	return prev, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
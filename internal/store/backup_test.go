@@ -0,0 +1,179 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSnapshot_ContainsData(t *testing.T) {
+	s, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	if _, err := s.Exec(context.Background(),
+		`INSERT INTO events (id, source, type, payload, timestamp) VALUES ('evt-1', 'test', 'x', '{}', '2025-01-01T00:00:00Z')`,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	dest := filepath.Join(t.TempDir(), "snap.db")
+	if err := s.Snapshot(dest); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if err := ValidateBackup(dest); err != nil {
+		t.Fatalf("ValidateBackup() error = %v", err)
+	}
+
+	snap, err := Open(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = snap.Close() })
+	var n int
+	if err := snap.DB().QueryRow(`SELECT COUNT(*) FROM events`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("snapshot has %d events, want 1", n)
+	}
+}
+
+func TestPruneBackups_KeepsDailyAndWeekly(t *testing.T) {
+	dir := t.TempDir()
+	base := time.Date(2025, 3, 31, 3, 0, 0, 0, time.UTC) // a Monday
+	var names []string
+	for i := range 21 {
+		at := base.AddDate(0, 0, -i)
+		name := backupPrefix + at.Format(backupTimeLayout) + backupSuffix
+		names = append(names, name)
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An extra same-day backup that is older than the day's newest.
+	extra := backupPrefix + base.Add(-time.Hour).Format(backupTimeLayout) + backupSuffix
+	if err := os.WriteFile(filepath.Join(dir, extra), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := PruneBackups(dir, 2, 3); err != nil {
+		t.Fatalf("PruneBackups() error = %v", err)
+	}
+
+	left, err := listBackups(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, b := range left {
+		got = append(got, filepath.Base(b.path))
+	}
+	// Two newest days (Mon 31st, Sun 30th) cover ISO weeks 14 and 13; the
+	// third week is covered by Sunday the 23rd.
+	want := []string{names[0], names[1], names[8]}
+	if !slices.Equal(got, want) {
+		t.Errorf("kept %v, want %v", got, want)
+	}
+}
+
+func TestBackupDue(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+	if !backupDue(filepath.Join(dir, "missing"), 24*time.Hour, now) {
+		t.Error("backup not due for a missing directory")
+	}
+	if !backupDue(dir, 24*time.Hour, now) {
+		t.Error("backup not due for an empty directory")
+	}
+
+	name := backupPrefix + now.Add(-30*time.Hour).Format(backupTimeLayout) + backupSuffix
+	if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if !backupDue(dir, 24*time.Hour, now) {
+		t.Error("backup not due when the newest is older than the interval")
+	}
+	if backupDue(dir, 48*time.Hour, now) {
+		t.Error("backup due although the newest is within the interval")
+	}
+}
+
+func TestValidateBackup_RejectsNonDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junk.db")
+	if err := os.WriteFile(path, []byte("definitely not sqlite"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateBackup(path); err == nil {
+		t.Fatal("expected error for non-database file")
+	}
+}
+
+func TestValidateBackup_RejectsNewerSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "future.db")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Exec(context.Background(),
+		`INSERT INTO schema_migrations (component, version, description, applied_at) VALUES (?, ?, 'future', CURRENT_TIMESTAMP)`,
+		CoreComponent, LatestVersion(CoreComponent)+1,
+	); err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Close()
+
+	if err := ValidateBackup(path); err == nil {
+		t.Fatal("expected error for schema newer than binary")
+	}
+}
+
+func TestRestore_SwapsDatabase(t *testing.T) {
+	dir := t.TempDir()
+	live := filepath.Join(dir, "live.db")
+	s, err := Open(live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Close()
+
+	backup := filepath.Join(dir, "backup.db")
+	src, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.Exec(context.Background(),
+		`INSERT INTO events (id, source, type, payload, timestamp) VALUES ('restored', 'test', 'x', '{}', '2025-01-01T00:00:00Z')`,
+	); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Snapshot(backup); err != nil {
+		t.Fatal(err)
+	}
+	_ = src.Close()
+
+	prev, err := Restore(backup, live)
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if _, err := os.Stat(prev); err != nil {
+		t.Errorf("previous database not kept: %v", err)
+	}
+
+	restored, err := Open(live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = restored.Close() })
+	var id string
+	if err := restored.DB().QueryRow(`SELECT id FROM events`).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	if id != "restored" {
+		t.Errorf("event id = %q, want %q", id, "restored")
+	}
+}
@@ -0,0 +1,228 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// CoreComponent is the migration component owned by the store itself.
+const CoreComponent = "core"
+
+// Migration is a single numbered schema change owned by a component.
+type Migration struct {
+	Version     int
+	Description string
+	SQL         string
+	// Func, if set, runs after SQL in the same transaction, for changes
+	// that depend on what the schema already looks like.
+	Func func(tx *sql.Tx) error
+}
+
+// MigrationState describes a registered migration and whether it has run.
+type MigrationState struct {
+	Component   string
+	Version     int
+	Description string
+	AppliedAt   time.Time // zero when pending
+}
+
+// Applied reports whether the migration has been applied.
+func (m MigrationState) Applied() bool {
+	return !m.AppliedAt.IsZero()
+}
+
+var (
+	migrationsMu sync.RWMutex
+	migrations   = make(map[string][]Migration)
+)
+
+const migrationsSchema = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    component TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    description TEXT NOT NULL,
+    applied_at DATETIME NOT NULL,
+    PRIMARY KEY (component, version)
+);
+`
+
+// Register adds migrations for a component. It is meant to be called from
+// package init functions and panics on invalid or duplicate versions.
+func Register(component string, ms ...Migration) {
+	migrationsMu.Lock()
+	defer migrationsMu.Unlock()
+	if component == "" {
+		panic("store: Register with empty component")
+	}
+	existing := migrations[component]
+	for _, m := range ms {
+		if m.Version <= 0 {
+			panic(fmt.Sprintf("store: %s migration version %d must be positive", component, m.Version))
+		}
+		if slices.ContainsFunc(existing, func(e Migration) bool { return e.Version == m.Version }) {
+			panic(fmt.Sprintf("store: duplicate %s migration version %d", component, m.Version))
+		}
+		existing = append(existing, m)
+	}
+	slices.SortFunc(existing, func(a, b Migration) int { return a.Version - b.Version })
+	migrations[component] = existing
+}
+
+// Components returns the names of all components with registered migrations,
+// core first and the rest alphabetically.
+func Components() []string {
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+	names := make([]string, 0, len(migrations))
+	for name := range migrations {
+		if name != CoreComponent {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	if _, ok := migrations[CoreComponent]; ok {
+		names = append([]string{CoreComponent}, names...)
+	}
+	return names
+}
+
+func registered(component string) []Migration {
+	migrationsMu.RLock()
+	defer migrationsMu.RUnlock()
+	return slices.Clone(migrations[component])
+}
+
+// Migrate applies all pending migrations registered for component, each in
+// its own transaction, in version order.
+func Migrate(db *sql.DB, component string) error {
+	if _, err := db.Exec(migrationsSchema); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	applied, err := appliedVersions(db, component)
+	if err != nil {
+		return err
+	}
+	for _, m := range registered(component) {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := apply(db, component, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateAll applies pending migrations for every registered component.
+func MigrateAll(db *sql.DB) error {
+	for _, component := range Components() {
+		if err := Migrate(db, component); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func apply(db *sql.DB, component string, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("migration %s/%d: begin: %w", component, m.Version, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if m.SQL != "" {
+		if _, err := tx.Exec(m.SQL); err != nil {
+			return fmt.Errorf("migration %s/%d (%s): %w", component, m.Version, m.Description, err)
+		}
+	}
+	if m.Func != nil {
+		if err := m.Func(tx); err != nil {
+			return fmt.Errorf("migration %s/%d (%s): %w", component, m.Version, m.Description, err)
+		}
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO schema_migrations (component, version, description, applied_at) VALUES (?, ?, ?, ?)`,
+		component, m.Version, m.Description, time.Now().UTC(),
+	); err != nil {
+		return fmt.Errorf("migration %s/%d: record: %w", component, m.Version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("migration %s/%d: commit: %w", component, m.Version, err)
+	}
+	return nil
+}
+
+func appliedVersions(db *sql.DB, component string) (map[int]time.Time, error) {
+	rows, err := db.Query(`SELECT version, applied_at FROM schema_migrations WHERE component = ?`, component)
+	if err != nil {
+		return nil, fmt.Errorf("querying schema_migrations: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("scanning schema_migrations: %w", err)
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// Status reports every registered migration and whether it has been applied.
+// The database is not modified.
+func Status(db *sql.DB) ([]MigrationState, error) {
+	var exists int
+	if err := db.QueryRow(
+		`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'`,
+	).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("checking schema_migrations: %w", err)
+	}
+
+	var states []MigrationState
+	for _, component := range Components() {
+		applied := map[int]time.Time{}
+		if exists > 0 {
+			var err error
+			if applied, err = appliedVersions(db, component); err != nil {
+				return nil, err
+			}
+		}
+		for _, m := range registered(component) {
+			states = append(states, MigrationState{
+				Component:   component,
+				Version:     m.Version,
+				Description: m.Description,
+				AppliedAt:   applied[m.Version],
+			})
+		}
+	}
+	return states, nil
+}
+
+// SchemaVersion returns the highest applied migration version for component,
+// or 0 if none has been applied.
+func SchemaVersion(db *sql.DB, component string) (int, error) {
+	var version int
+	err := db.QueryRow(
+		`SELECT COALESCE(MAX(version), 0) FROM schema_migrations WHERE component = ?`, component,
+	).Scan(&version)
+	if err != nil {
+		return 0, fmt.Errorf("querying schema version: %w", err)
+	}
+	return version, nil
+}
+
+// LatestVersion returns the highest registered migration version for component.
+func LatestVersion(component string) int {
+	ms := registered(component)
+	if len(ms) == 0 {
+		return 0
+	}
+	return ms[len(ms)-1].Version
+}
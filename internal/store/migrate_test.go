@@ -0,0 +1,133 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func openRaw(t *testing.T) *sql.DB {
+	t.Helper()
+	s, err := OpenUnmigrated(t.TempDir() + "/migrate.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s.wdb
+}
+
+func TestMigrate_AppliesInOrderOnce(t *testing.T) {
+	Register("test-order",
+		Migration{Version: 2, Description: "add column", SQL: `ALTER TABLE widgets ADD COLUMN color TEXT`},
+		Migration{Version: 1, Description: "create widgets", SQL: `CREATE TABLE widgets (id INTEGER PRIMARY KEY)`},
+	)
+	db := openRaw(t)
+
+	if err := Migrate(db, "test-order"); err != nil {
+		t.Fatalf("Migrate() error = %v", err)
+	}
+	// Second run must be a no-op; re-running the ALTER would fail.
+	if err := Migrate(db, "test-order"); err != nil {
+		t.Fatalf("second Migrate() error = %v", err)
+	}
+
+	if _, err := db.Exec(`INSERT INTO widgets (id, color) VALUES (1, 'red')`); err != nil {
+		t.Fatalf("insert into migrated table: %v", err)
+	}
+	v, err := SchemaVersion(db, "test-order")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 2 {
+		t.Errorf("SchemaVersion() = %d, want 2", v)
+	}
+	if LatestVersion("test-order") != 2 {
+		t.Errorf("LatestVersion() = %d, want 2", LatestVersion("test-order"))
+	}
+}
+
+func TestMigrate_FailureRollsBack(t *testing.T) {
+	Register("test-rollback",
+		Migration{Version: 1, Description: "ok", SQL: `CREATE TABLE gadgets (id INTEGER PRIMARY KEY)`},
+		Migration{Version: 2, Description: "broken", SQL: `CREATE TABLE gizmos (id INTEGER); INSERT INTO nope VALUES (1)`},
+	)
+	db := openRaw(t)
+
+	if err := Migrate(db, "test-rollback"); err == nil {
+		t.Fatal("expected error from broken migration")
+	}
+
+	v, err := SchemaVersion(db, "test-rollback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 1 {
+		t.Errorf("SchemaVersion() = %d, want 1", v)
+	}
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name = 'gizmos'`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Error("table from failed migration should have been rolled back")
+	}
+}
+
+func TestStatus_PendingAndApplied(t *testing.T) {
+	Register("test-status",
+		Migration{Version: 1, Description: "one", SQL: `CREATE TABLE status_one (id INTEGER)`},
+	)
+	db := openRaw(t)
+
+	states, err := Status(db)
+	if err != nil {
+		t.Fatalf("Status() on fresh db error = %v", err)
+	}
+	if len(states) == 0 || states[0].Component != CoreComponent {
+		t.Fatalf("expected core migrations listed first, got %+v", states)
+	}
+	for _, s := range states {
+		if s.Applied() {
+			t.Errorf("%s/%d should be pending on a fresh db", s.Component, s.Version)
+		}
+	}
+
+	if err := Migrate(db, "test-status"); err != nil {
+		t.Fatal(err)
+	}
+	states, err = Status(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range states {
+		want := s.Component == "test-status"
+		if s.Applied() != want {
+			t.Errorf("%s/%d applied = %v, want %v", s.Component, s.Version, s.Applied(), want)
+		}
+	}
+}
+
+func TestRegister_DuplicateVersionPanics(t *testing.T) {
+	Register("test-dup", Migration{Version: 1, SQL: `SELECT 1`})
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic on duplicate version")
+		}
+	}()
+	Register("test-dup", Migration{Version: 1, SQL: `SELECT 1`})
+}
+
+func TestOpen_RecordsCoreMigration(t *testing.T) {
+	s, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	v, err := SchemaVersion(s.DB(), CoreComponent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != LatestVersion(CoreComponent) {
+		t.Errorf("core schema version = %d, want %d", v, LatestVersion(CoreComponent))
+	}
+}
@@ -3,11 +3,63 @@ package store
 import (
 	"database/sql"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	_ "modernc.org/sqlite"
 )
 
-var schema = `
+func init() {
+	Register(CoreComponent,
+		Migration{Version: 1, Description: "initial schema", SQL: initialSchema},
+		Migration{Version: 2, Description: "plugin_state expiry", SQL: `ALTER TABLE plugin_state ADD COLUMN expires_at DATETIME`},
+		Migration{Version: 3, Description: "event and run indexes", SQL: `
+CREATE INDEX IF NOT EXISTS idx_events_created_at ON events(created_at);
+CREATE INDEX IF NOT EXISTS idx_events_source_type ON events(source, type);
+CREATE INDEX IF NOT EXISTS idx_pipeline_runs_event_id ON pipeline_runs(event_id);
+`},
+		Migration{Version: 4, Description: "event lineage", SQL: `
+ALTER TABLE events ADD COLUMN parent_id TEXT;
+ALTER TABLE events ADD COLUMN correlation_id TEXT;
+ALTER TABLE supervisor_log ADD COLUMN event_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_events_parent_id ON events(parent_id);
+CREATE INDEX IF NOT EXISTS idx_events_correlation_id ON events(correlation_id);
+`},
+		Migration{Version: 5, Description: "supervisor run state", SQL: `
+CREATE TABLE IF NOT EXISTS supervisor_state (
+    task TEXT PRIMARY KEY,
+    last_run DATETIME NOT NULL
+);
+ALTER TABLE supervisor_log ADD COLUMN duration_ms INTEGER;
+ALTER TABLE supervisor_log ADD COLUMN error TEXT;
+CREATE INDEX IF NOT EXISTS idx_supervisor_log_task ON supervisor_log(task, id);
+`},
+		Migration{Version: 6, Description: "paused supervisor tasks", SQL: `
+CREATE TABLE IF NOT EXISTS supervisor_paused (
+    task TEXT PRIMARY KEY,
+    paused_at DATETIME NOT NULL
+);
+`},
+		Migration{Version: 7, Description: "heartbeat state", SQL: `
+CREATE TABLE IF NOT EXISTS heartbeat_state (
+    name TEXT PRIMARY KEY,
+    last_seen DATETIME,
+    missed_at DATETIME
+);
+`},
+		Migration{Version: 8, Description: "pipeline run output", SQL: `ALTER TABLE pipeline_runs ADD COLUMN output TEXT`},
+		Migration{Version: 9, Description: "event idempotency keys", SQL: `
+CREATE TABLE IF NOT EXISTS event_idempotency (
+    key        TEXT PRIMARY KEY,
+    event_id   TEXT NOT NULL,
+    created_at DATETIME NOT NULL
+);
+`},
+	)
+}
+
+const initialSchema = `
 CREATE TABLE IF NOT EXISTS events (
     id TEXT PRIMARY KEY,
     source TEXT NOT NULL,
@@ -48,38 +100,107 @@ CREATE TABLE IF NOT EXISTS pipeline_runs (
 `
 
 type Store struct {
-	db *sql.DB
+	db  *sql.DB // read pool, query_only
+	wdb *sql.DB // single writer connection
+
+	writes     chan writeReq
+	quit       chan struct{}
+	writerDone chan struct{}
+	closeOnce  sync.Once
 }
 
+// memDBSeq gives each in-memory database a unique shared-cache name so the
+// read pool and writer connection see the same data.
+var memDBSeq atomic.Int64
+
+// Open opens the database at path and applies pending core migrations.
 func Open(path string) (*Store, error) {
-	db, err := sql.Open("sqlite", path)
+	s, err := OpenUnmigrated(path)
 	if err != nil {
-		return nil, fmt.Errorf("opening database %s: %w", path, err)
+		return nil, err
 	}
 
-	// Enable WAL mode for better concurrent reads.
-	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
-		_ = db.Close()
-		return nil, fmt.Errorf("setting WAL mode: %w", err)
+	if err := s.Migrate(CoreComponent); err != nil {
+		_ = s.Close()
+		return nil, fmt.Errorf("running migrations: %w", err)
 	}
 
-	if _, err := db.Exec("PRAGMA busy_timeout = 5000"); err != nil {
-		_ = db.Close()
-		return nil, fmt.Errorf("setting busy timeout: %w", err)
+	return s, nil
+}
+
+// OpenUnmigrated opens the database without applying any migrations, for
+// commands that inspect or manage the schema themselves.
+func OpenUnmigrated(path string) (*Store, error) {
+	base := "file:" + path
+	writeParams := []string{"_pragma=busy_timeout(5000)"}
+	readParams := []string{"_pragma=busy_timeout(5000)", "_pragma=query_only(1)"}
+	if path == ":memory:" {
+		base = fmt.Sprintf("file:smoothbrain-mem-%d", memDBSeq.Add(1))
+		shared := []string{"mode=memory", "cache=shared"}
+		writeParams = append(shared, writeParams...)
+		// Shared-cache readers would otherwise block on the writer's table locks.
+		readParams = append(append(shared, readParams...), "_pragma=read_uncommitted(1)")
+	} else {
+		// Enable WAL mode for better concurrent reads.
+		writeParams = append(writeParams, "_pragma=journal_mode(WAL)", "_txlock=immediate")
 	}
+	writeDSN := base + "?" + strings.Join(writeParams, "&")
+	readDSN := base + "?" + strings.Join(readParams, "&")
 
-	if _, err := db.Exec(schema); err != nil {
-		_ = db.Close()
-		return nil, fmt.Errorf("running migrations: %w", err)
+	wdb, err := sql.Open("sqlite", writeDSN)
+	if err != nil {
+		return nil, fmt.Errorf("opening database %s: %w", path, err)
+	}
+	wdb.SetMaxOpenConns(1)
+	// The connection must stay open; an in-memory database vanishes with it.
+	wdb.SetConnMaxIdleTime(0)
+	if err := wdb.Ping(); err != nil {
+		_ = wdb.Close()
+		return nil, fmt.Errorf("opening database %s: %w", path, err)
+	}
+
+	db, err := sql.Open("sqlite", readDSN)
+	if err != nil {
+		_ = wdb.Close()
+		return nil, fmt.Errorf("opening read pool %s: %w", path, err)
 	}
 
-	return &Store{db: db}, nil
+	s := &Store{
+		db:         db,
+		wdb:        wdb,
+		writes:     make(chan writeReq),
+		quit:       make(chan struct{}),
+		writerDone: make(chan struct{}),
+	}
+	go s.runWriter()
+	return s, nil
 }
 
+// DB returns the read-only connection pool. Writes must go through Exec or
+// Write.
 func (s *Store) DB() *sql.DB {
 	return s.db
 }
 
+// Migrate applies pending migrations registered for component on the writer
+// connection.
+func (s *Store) Migrate(component string) error {
+	return Migrate(s.wdb, component)
+}
+
+// MigrateAll applies pending migrations for every registered component.
+func (s *Store) MigrateAll() error {
+	return MigrateAll(s.wdb)
+}
+
+// Close waits for the in-flight write batch, stops the writer and closes both
+// pools.
 func (s *Store) Close() error {
-	return s.db.Close()
+	s.closeOnce.Do(func() { close(s.quit) })
+	<-s.writerDone
+	rerr := s.db.Close()
+	if err := s.wdb.Close(); err != nil {
+		return err
+	}
+	return rerr
 }
@@ -1,6 +1,9 @@
 package store
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"testing"
 )
 
@@ -23,10 +26,11 @@ func TestOpen_SchemaCreated(t *testing.T) {
 	t.Cleanup(func() { _ = s.Close() })
 
 	want := map[string]bool{
-		"events":         false,
-		"plugin_state":   false,
-		"supervisor_log": false,
-		"pipeline_runs":  false,
+		"events":            false,
+		"plugin_state":      false,
+		"supervisor_log":    false,
+		"pipeline_runs":     false,
+		"schema_migrations": false,
 	}
 
 	rows, err := s.DB().Query("SELECT name FROM sqlite_master WHERE type='table'")
@@ -106,7 +110,7 @@ func TestInsertAndQueryEvents(t *testing.T) {
 	}
 	t.Cleanup(func() { _ = s.Close() })
 
-	_, err = s.DB().Exec(
+	_, err = s.Exec(context.Background(),
 		`INSERT INTO events (id, source, type, payload, timestamp) VALUES (?, ?, ?, ?, ?)`,
 		"evt-1", "test", "test.event", `{"key":"value"}`, "2025-01-01T00:00:00Z",
 	)
@@ -136,12 +140,12 @@ func TestInsertAndQueryPipelineRuns(t *testing.T) {
 	t.Cleanup(func() { _ = s.Close() })
 
 	// Insert event first for FK constraint
-	_, _ = s.DB().Exec(
+	_, _ = s.Exec(context.Background(),
 		`INSERT INTO events (id, source, type, payload, timestamp) VALUES (?, ?, ?, ?, ?)`,
 		"evt-1", "test", "test.event", `{}`, "2025-01-01T00:00:00Z",
 	)
 
-	_, err = s.DB().Exec(
+	_, err = s.Exec(context.Background(),
 		`INSERT INTO pipeline_runs (event_id, route, status, started_at, finished_at, duration_ms, steps)
 		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
 		"evt-1", "test-route", "completed", "2025-01-01T00:00:00Z", "2025-01-01T00:00:01Z", 1000, `[]`,
@@ -170,7 +174,7 @@ func TestPluginState(t *testing.T) {
 	}
 	t.Cleanup(func() { _ = s.Close() })
 
-	_, err = s.DB().Exec(
+	_, err = s.Exec(context.Background(),
 		`INSERT INTO plugin_state (plugin, key, value) VALUES (?, ?, ?)`,
 		"myplugin", "cursor", "abc123",
 	)
@@ -205,7 +209,7 @@ func TestSupervisorLog(t *testing.T) {
 	}
 	t.Cleanup(func() { _ = s.Close() })
 
-	_, err = s.DB().Exec(
+	_, err = s.Exec(context.Background(),
 		`INSERT INTO supervisor_log (task, result) VALUES (?, ?)`,
 		"healthcheck", "ok",
 	)
@@ -222,3 +226,66 @@ func TestSupervisorLog(t *testing.T) {
 		t.Errorf("count = %d, want 1", count)
 	}
 }
+
+func BenchmarkExec_Serial(b *testing.B) {
+	s, err := Open(b.TempDir() + "/bench.db")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = s.Close() })
+
+	i := 0
+	for b.Loop() {
+		if err := insertEvent(s, fmt.Sprintf("evt-%d", i)); err != nil {
+			b.Fatal(err)
+		}
+		i++
+	}
+}
+
+func BenchmarkExec_Parallel(b *testing.B) {
+	s, err := Open(b.TempDir() + "/bench.db")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = s.Close() })
+
+	var mu sync.Mutex
+	next := 0
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			mu.Lock()
+			id := fmt.Sprintf("evt-%d", next)
+			next++
+			mu.Unlock()
+			if err := insertEvent(s, id); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkRead_DuringWrites(b *testing.B) {
+	s, err := Open(b.TempDir() + "/bench.db")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { _ = s.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for i := 0; ctx.Err() == nil; i++ {
+			_ = insertEvent(s, fmt.Sprintf("bg-%d", i))
+		}
+	}()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			var n int
+			if err := s.DB().QueryRow(`SELECT COUNT(*) FROM events`).Scan(&n); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
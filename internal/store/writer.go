@@ -0,0 +1,118 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// maxWriteBatch bounds how many queued writes share one transaction.
+const maxWriteBatch = 256
+
+// ErrClosed is returned for writes submitted after Close.
+var ErrClosed = errors.New("store: closed")
+
+type writeReq struct {
+	ctx  context.Context
+	fn   func(tx *sql.Tx) error
+	done chan error
+}
+
+// Write runs fn inside the writer goroutine's transaction and waits for the
+// batch to commit. Writes that arrive while a batch is committing are grouped
+// into the next transaction; each runs under its own savepoint so one
+// failure does not affect the others. fn must not call back into the Store.
+func (s *Store) Write(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	req := writeReq{ctx: ctx, fn: fn, done: make(chan error, 1)}
+	select {
+	case s.writes <- req:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-s.quit:
+		return ErrClosed
+	}
+	return <-req.done
+}
+
+// Exec runs a single statement through the writer.
+func (s *Store) Exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	var res sql.Result
+	err := s.Write(ctx, func(tx *sql.Tx) error {
+		var err error
+		res, err = tx.ExecContext(ctx, query, args...)
+		return err
+	})
+	return res, err
+}
+
+func (s *Store) runWriter() {
+	defer close(s.writerDone)
+	for {
+		var first writeReq
+		select {
+		case first = <-s.writes:
+		case <-s.quit:
+			return
+		}
+
+		batch := []writeReq{first}
+	drain:
+		for len(batch) < maxWriteBatch {
+			select {
+			case req := <-s.writes:
+				batch = append(batch, req)
+			default:
+				break drain
+			}
+		}
+		s.commitBatch(batch)
+	}
+}
+
+func (s *Store) commitBatch(batch []writeReq) {
+	results := make([]error, len(batch))
+	fail := func(err error) {
+		for _, req := range batch {
+			req.done <- err
+		}
+	}
+
+	tx, err := s.wdb.Begin()
+	if err != nil {
+		fail(fmt.Errorf("store: begin write batch: %w", err))
+		return
+	}
+
+	for i, req := range batch {
+		if err := req.ctx.Err(); err != nil {
+			results[i] = err
+			continue
+		}
+		if _, err := tx.Exec(`SAVEPOINT write`); err != nil {
+			results[i] = err
+			continue
+		}
+		if err := req.fn(tx); err != nil {
+			results[i] = err
+			if _, rerr := tx.Exec(`ROLLBACK TO write`); rerr != nil {
+				_ = tx.Rollback()
+				fail(fmt.Errorf("store: rollback savepoint: %w", rerr))
+				return
+			}
+		}
+		if _, err := tx.Exec(`RELEASE write`); err != nil {
+			_ = tx.Rollback()
+			fail(fmt.Errorf("store: release savepoint: %w", err))
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		fail(fmt.Errorf("store: commit write batch: %w", err))
+		return
+	}
+	for i, req := range batch {
+		req.done <- results[i]
+	}
+}
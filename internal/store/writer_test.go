@@ -0,0 +1,118 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func insertEvent(s *Store, id string) error {
+	_, err := s.Exec(context.Background(),
+		`INSERT INTO events (id, source, type, payload, timestamp) VALUES (?, 'test', 'x', '{}', '2025-01-01T00:00:00Z')`,
+		id,
+	)
+	return err
+}
+
+func countEvents(t *testing.T, s *Store) int {
+	t.Helper()
+	var n int
+	if err := s.DB().QueryRow(`SELECT COUNT(*) FROM events`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestDB_IsReadOnly(t *testing.T) {
+	s, err := Open(t.TempDir() + "/ro.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	if _, err := s.DB().Exec(`DELETE FROM events`); err == nil {
+		t.Fatal("expected write through DB() to fail")
+	}
+}
+
+func TestWrite_ConcurrentWritersAllCommit(t *testing.T) {
+	s, err := Open(t.TempDir() + "/concurrent.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	const n = 200
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := range n {
+		wg.Go(func() {
+			errs <- insertEvent(s, fmt.Sprintf("evt-%d", i))
+		})
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("insert error = %v", err)
+		}
+	}
+	if got := countEvents(t, s); got != n {
+		t.Errorf("events = %d, want %d", got, n)
+	}
+}
+
+func TestWrite_FailureIsolatedWithinBatch(t *testing.T) {
+	s, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	boom := errors.New("boom")
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Go(func() {
+			err := s.Write(context.Background(), func(tx *sql.Tx) error {
+				if _, err := tx.Exec(
+					`INSERT INTO events (id, source, type, payload, timestamp) VALUES (?, 'test', 'x', '{}', '2025-01-01T00:00:00Z')`,
+					fmt.Sprintf("evt-%d", i),
+				); err != nil {
+					return err
+				}
+				if i%2 == 1 {
+					return boom
+				}
+				return nil
+			})
+			if i%2 == 1 && !errors.Is(err, boom) {
+				t.Errorf("write %d error = %v, want boom", i, err)
+			}
+			if i%2 == 0 && err != nil {
+				t.Errorf("write %d error = %v", i, err)
+			}
+		})
+	}
+	wg.Wait()
+
+	// Failed writes are rolled back to their savepoint; the rest commit.
+	if got := countEvents(t, s); got != 10 {
+		t.Errorf("events = %d, want 10", got)
+	}
+}
+
+func TestWrite_AfterClose(t *testing.T) {
+	s, err := Open(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := insertEvent(s, "late"); !errors.Is(err, ErrClosed) {
+		t.Errorf("error = %v, want ErrClosed", err)
+	}
+}